@@ -0,0 +1,156 @@
+// Command maintenance runs one-off maintenance tasks against the database and the file storage.
+//
+// Uso:
+//
+//	go run ./cmd/maintenance storage-check [-checksums] [-repair]
+//	go run ./cmd/maintenance reencrypt [-all]
+//	go run ./cmd/maintenance expire-uploads
+//	go run ./cmd/maintenance purge-trash
+//	go run ./cmd/maintenance extract-text [-all]
+//	go run ./cmd/maintenance remind-pending
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/SaidMg10/gestor-one/internal/config"
+	"github.com/SaidMg10/gestor-one/internal/db"
+	"github.com/SaidMg10/gestor-one/internal/repository"
+	"github.com/SaidMg10/gestor-one/internal/service"
+	"github.com/SaidMg10/gestor-one/internal/storage"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := config.Init("."); err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	cfg := config.Cfg
+
+	if err := db.Init(cfg.Database); err != nil {
+		log.Fatalf("X error initializing database: %v", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			log.Printf("Error closing database: %v", err)
+		}
+	}()
+
+	fileStorage, err := storage.New(cfg.Storage)
+	if err != nil {
+		log.Fatalf("X error initializing storage: %v", err)
+	}
+
+	receiptRepo := repository.NewGormReceiptRepo(db.DB)
+	incomeRepo := repository.NewGormIncomeRepo(db.DB)
+	expenseRepo := repository.NewGormExpenseRepo(db.DB)
+	uploadRepo := repository.NewGormUploadRepo(db.DB)
+	maintenanceSvc := service.NewMaintenanceService(receiptRepo, uploadRepo, fileStorage, cfg.Storage.OrphanGrace)
+
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "storage-check":
+		fs := flag.NewFlagSet("storage-check", flag.ExitOnError)
+		checksums := fs.Bool("checksums", false, "verify the sha256 of every stored file")
+		repair := fs.Bool("repair", false, "quarantine old orphans and recompute checksums")
+		_ = fs.Parse(os.Args[2:])
+
+		report, err := maintenanceSvc.CheckStorage(ctx, service.StorageCheckOptions{
+			VerifyChecksums: *checksums,
+			Repair:          *repair,
+		})
+		if err != nil {
+			log.Fatalf("storage check failed: %v", err)
+		}
+		printJSON(report)
+	case "reencrypt":
+		fs := flag.NewFlagSet("reencrypt", flag.ExitOnError)
+		all := fs.Bool("all", false, "re-encrypt every receipt, not only those using an old key")
+		_ = fs.Parse(os.Args[2:])
+
+		if !cfg.Storage.Encryption.Enabled {
+			log.Fatalf("storage encryption is disabled, nothing to do")
+		}
+		report, err := maintenanceSvc.ReEncrypt(ctx, cfg.Storage.Encryption.ActiveKeyID, *all)
+		if err != nil {
+			log.Fatalf("re-encryption failed: %v", err)
+		}
+		printJSON(report)
+	case "expire-uploads":
+		uploadSvc, err := service.NewUploadService(
+			uploadRepo,
+			fileStorage,
+			cfg.Storage.UploadDir,
+			cfg.Storage.MaxFileSize,
+			cfg.Storage.UploadTTL,
+		)
+		if err != nil {
+			log.Fatalf("X error initializing uploads: %v", err)
+		}
+		expired, err := uploadSvc.ExpireStale(ctx)
+		if err != nil {
+			log.Fatalf("upload expiration failed: %v", err)
+		}
+		printJSON(map[string]int{"expired": expired})
+	case "extract-text":
+		fs := flag.NewFlagSet("extract-text", flag.ExitOnError)
+		all := fs.Bool("all", false, "extract the text of every receipt, not only those without it")
+		_ = fs.Parse(os.Args[2:])
+
+		report, err := maintenanceSvc.ExtractText(ctx, *all)
+		if err != nil {
+			log.Fatalf("text extraction failed: %v", err)
+		}
+		printJSON(report)
+	case "purge-trash":
+		trashSvc := service.NewTrashService(incomeRepo, expenseRepo, fileStorage, cfg.Trash.Retention)
+		report, err := trashSvc.Purge(ctx)
+		if err != nil {
+			log.Fatalf("trash purge failed: %v", err)
+		}
+		printJSON(report)
+	case "remind-pending":
+		reminderSvc := service.NewReminderService(
+			repository.NewGormReminderRepo(db.DB),
+			cfg.Reminders.PendingReceiptDays,
+		)
+		sent, err := reminderSvc.SendReminders(ctx)
+		if err != nil {
+			log.Fatalf("pending receipt reminders failed: %v", err)
+		}
+		printJSON(map[string]int{"sent": sent})
+	default:
+		usage()
+		os.Exit(2)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: maintenance <command> [flags]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "commands:")
+	fmt.Fprintln(os.Stderr, "  storage-check   cross-check receipts against storage (-checksums, -repair)")
+	fmt.Fprintln(os.Stderr, "  reencrypt       re-encrypt receipts with the active master key (-all)")
+	fmt.Fprintln(os.Stderr, "  expire-uploads  delete abandoned resumable uploads")
+	fmt.Fprintln(os.Stderr, "  purge-trash     hard-delete incomes/expenses past the trash retention")
+	fmt.Fprintln(os.Stderr, "  extract-text    store the text of receipts saved before search existed (-all)")
+	fmt.Fprintln(os.Stderr, "  remind-pending  remind users of transactions still missing their receipt")
+}
+
+func printJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Fatalf("cannot encode output: %v", err)
+	}
+}
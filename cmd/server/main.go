@@ -13,7 +13,12 @@ import (
 	"github.com/SaidMg10/gestor-one/internal/auth"
 	"github.com/SaidMg10/gestor-one/internal/config"
 	"github.com/SaidMg10/gestor-one/internal/db"
+	"github.com/SaidMg10/gestor-one/internal/extractor"
+	"github.com/SaidMg10/gestor-one/internal/jobs"
+	"github.com/SaidMg10/gestor-one/internal/mailer"
+	"github.com/SaidMg10/gestor-one/internal/oidc"
 	"github.com/SaidMg10/gestor-one/internal/repository"
+	"github.com/SaidMg10/gestor-one/internal/scanner"
 	"github.com/SaidMg10/gestor-one/internal/service"
 	"github.com/SaidMg10/gestor-one/internal/storage"
 	httpTransport "github.com/SaidMg10/gestor-one/internal/transport/http"
@@ -38,26 +43,283 @@ func main() {
 		}
 	}()
 
-	auth := auth.NewJWTAuthenticatorFromConfig(cfg.JWT)
+	auth, err := auth.NewAuthenticatorFromConfig(cfg.JWT)
+	if err != nil {
+		log.Fatalf("X error initializing jwt signing: %v", err)
+	}
 
-	fileStorage := storage.NewFileStorageLocal()
+	backendStorage, err := storage.New(cfg.Storage)
+	if err != nil {
+		log.Fatalf("X error initializing storage: %v", err)
+	}
+	malwareScanner, err := scanner.New(cfg.Scanner)
+	if err != nil {
+		log.Fatalf("X error initializing malware scanner: %v", err)
+	}
+	// Las subidas pasan por el antivirus antes de llegar al backend
+	fileStorage := storage.NewInspectingStorage(
+		backendStorage,
+		malwareScanner,
+		storage.ScanPolicy{OnInfected: cfg.Scanner.OnInfected, OnError: cfg.Scanner.OnError},
+		storage.PDFPolicy{
+			ActiveContent:  cfg.Storage.PDF.ActiveContent,
+			Attachments:    cfg.Storage.PDF.Attachments,
+			AllowEncrypted: cfg.Storage.PDF.AllowEncrypted,
+			MaxPages:       cfg.Storage.PDF.MaxPages,
+			Strict:         cfg.Storage.PDF.Strict,
+		},
+		cfg.Storage.MaxFileSize,
+	)
 
 	// 3. Inicializar repositorios y servicios
 	userRepo := repository.NewGormUserRepo(db.DB)
 	incomeRepo := repository.NewGormIncomeRepo(db.DB)
 	expenseRepo := repository.NewGormExpenseRepo(db.DB)
-	userSvc := service.NewUserService(userRepo)
+	receiptRepo := repository.NewGormReceiptRepo(db.DB)
+	uploadRepo := repository.NewGormUploadRepo(db.DB)
+	searchRepo := repository.NewGormSearchRepo(db.DB)
+	reminderRepo := repository.NewGormReminderRepo(db.DB)
+	tokenRepo := repository.NewGormRefreshTokenRepo(db.DB)
+	sessionRepo := repository.NewGormSessionRepo(db.DB)
+	oauthStateRepo := repository.NewGormOAuthStateRepo(db.DB)
+	identityRepo := repository.NewGormUserIdentityRepo(db.DB)
+	twoFactorRepo := repository.NewGormTwoFactorRepo(db.DB)
+	userTokenRepo := repository.NewGormUserTokenRepo(db.DB)
+	throttleRepo := repository.NewGormLoginThrottleRepo(db.DB)
+	securityEventRepo := repository.NewGormSecurityEventRepo(db.DB)
+	apiKeyRepo := repository.NewGormAPIKeyRepo(db.DB)
+	twoFactorIssuer := cfg.TwoFactor.Issuer
+	if twoFactorIssuer == "" {
+		twoFactorIssuer = cfg.App.Name
+	}
+	twoFactorSvc := service.NewTwoFactorService(twoFactorRepo, twoFactorIssuer)
+	mfaTTL := cfg.TwoFactor.LoginTTL
+	if mfaTTL <= 0 {
+		mfaTTL = 5 * time.Minute
+	}
+	failureWindow := cfg.Login.FailureWindow
+	if failureWindow <= 0 {
+		failureWindow = 15 * time.Minute
+	}
+	loginGuard := service.NewLoginGuardService(throttleRepo, securityEventRepo, userRepo, service.LoginPolicy{
+		FailureWindow:      failureWindow,
+		BackoffAfter:       cfg.Login.BackoffAfter,
+		BackoffBase:        cfg.Login.BackoffBase,
+		BackoffMax:         cfg.Login.BackoffMax,
+		AccountMaxFailures: cfg.Login.AccountMaxFailures,
+		IPMaxFailures:      cfg.Login.IPMaxFailures,
+		LockoutDuration:    cfg.Login.LockoutDuration,
+	})
 	authSvc := service.NewAuthService(
-		userRepo, // repositorio de usuarios
-		auth,     // Authenticator
+		userRepo,     // repositorio de usuarios
+		tokenRepo,    // refresh tokens emitidos
+		sessionRepo,  // sesiones iniciadas por dispositivo
+		auth,         // Authenticator
+		twoFactorSvc, // segundo factor (TOTP)
+		loginGuard,   // límite de intentos fallidos
 		cfg.JWT.AccessTokenTTL,
 		cfg.JWT.RefreshTokenTTL,
+		mfaTTL,
 		cfg.JWT.Issuer,
 	)
-	incomeSvc := service.NewIncomeService(incomeRepo, fileStorage)
-	expenseSvc := service.NewExpenseService(expenseRepo, fileStorage)
+	emailSender, err := mailer.New(cfg.Mailer)
+	if err != nil {
+		log.Fatalf("X error initializing mailer: %v", err)
+	}
+	// Los enlaces de los emails apuntan al frontend
+	linkBaseURL := cfg.Account.LinkBaseURL
+	if linkBaseURL == "" {
+		linkBaseURL = cfg.App.FEOriginURL
+	}
+	resetTTL := cfg.Account.ResetTokenTTL
+	if resetTTL <= 0 {
+		resetTTL = time.Hour
+	}
+	verifyTTL := cfg.Account.VerifyTokenTTL
+	if verifyTTL <= 0 {
+		verifyTTL = 48 * time.Hour
+	}
+	resetLimits := service.ResetLimits{PerEmail: cfg.Account.ResetMaxPerEmail, PerIP: cfg.Account.ResetMaxPerIP}
+	if resetLimits.PerEmail <= 0 {
+		resetLimits.PerEmail = 3
+	}
+	if resetLimits.PerIP <= 0 {
+		resetLimits.PerIP = 20
+	}
+	accountSvc := service.NewAccountService(userRepo, userTokenRepo, authSvc, emailSender, linkBaseURL, resetTTL, verifyTTL, resetLimits)
+	userSvc := service.NewUserService(userRepo, accountSvc, authSvc)
+	apiKeyDefaultTTL := cfg.APIKeys.DefaultTTL
+	if apiKeyDefaultTTL <= 0 {
+		apiKeyDefaultTTL = 90 * 24 * time.Hour
+	}
+	apiKeyMaxTTL := cfg.APIKeys.MaxTTL
+	if apiKeyMaxTTL <= 0 {
+		apiKeyMaxTTL = 365 * 24 * time.Hour
+	}
+	apiKeySvc := service.NewAPIKeyService(apiKeyRepo, userRepo, min(apiKeyDefaultTTL, apiKeyMaxTTL), apiKeyMaxTTL)
+	// Proveedores OIDC (Google y los corporativos); sin client id quedan deshabilitados
+	var ssoProviders []*service.SSOProvider
+	if cfg.Google.ClientID != "" {
+		googleIssuer := cfg.Google.IssuerURL
+		if googleIssuer == "" {
+			googleIssuer = "https://accounts.google.com"
+		}
+		ssoProviders = append(ssoProviders, &service.SSOProvider{
+			Name:     service.ProviderGoogle,
+			OAuth2:   cfg.GetGoogleOAuthConfig(),
+			Provider: oidc.NewProvider(googleIssuer, nil),
+		})
+	}
+	for name, p := range cfg.OIDC.Providers {
+		if p.ClientID == "" {
+			continue
+		}
+		roleMap, err := p.GetRoleMap()
+		if err != nil {
+			log.Fatalf("X error in oidc provider %s: %v", name, err)
+		}
+		ssoProviders = append(ssoProviders, &service.SSOProvider{
+			Name:        name,
+			OAuth2:      p.GetOAuthConfig(),
+			Provider:    oidc.NewProvider(p.IssuerURL, nil),
+			RoleClaim:   p.RoleClaim,
+			RoleMap:     roleMap,
+			DefaultRole: p.DefaultRole,
+			JIT:         p.JIT,
+			Domains:     p.Domains,
+		})
+	}
+	oidcSvc, err := service.NewOIDCService(oauthStateRepo, identityRepo, userRepo, authSvc, ssoProviders)
+	if err != nil {
+		log.Fatalf("X error initializing oidc providers: %v", err)
+	}
+	// Las descargas de los roles configurados llevan marca de agua con el usuario
+	watermarker, err := service.NewReceiptWatermarker(cfg.Storage.Watermark.Roles)
+	if err != nil {
+		log.Fatalf("X error initializing watermark: %v", err)
+	}
+	incomeSvc := service.NewIncomeService(incomeRepo, uploadRepo, fileStorage, watermarker)
+	expenseSvc := service.NewExpenseService(expenseRepo, uploadRepo, fileStorage, watermarker)
+	uploadSvc, err := service.NewUploadService(
+		uploadRepo,
+		fileStorage,
+		cfg.Storage.UploadDir,
+		cfg.Storage.MaxFileSize,
+		cfg.Storage.UploadTTL,
+	)
+	if err != nil {
+		log.Fatalf("X error initializing uploads: %v", err)
+	}
+
+	// Las URLs firmadas tienen clave propia: el secreto del JWT no existe con RS256/EdDSA
+	urlSigner, err := storage.NewURLSigner(cfg.Storage.URLSignKey, cfg.Storage.URLTTL)
+	if err != nil {
+		log.Fatalf("X error initializing receipt url signer (set storage.url_sign_key): %v", err)
+	}
+	receiptSvc := service.NewReceiptService(receiptRepo, incomeRepo, expenseRepo, userRepo, fileStorage, urlSigner, watermarker)
+
+	exportSvc := service.NewExportService(incomeRepo, expenseRepo, fileStorage, watermarker)
+	searchSvc := service.NewSearchService(searchRepo)
+	receiptExtractor, err := extractor.New(cfg.Extractor)
+	if err != nil {
+		log.Fatalf("X error initializing receipt extractor: %v", err)
+	}
+	analysisSvc := service.NewAnalysisService(receiptExtractor, uploadRepo, fileStorage)
+	reminderSvc := service.NewReminderService(reminderRepo, cfg.Reminders.PendingReceiptDays)
+	trashSvc := service.NewTrashService(incomeRepo, expenseRepo, fileStorage, cfg.Trash.Retention)
+	maintenanceSvc := service.NewMaintenanceService(receiptRepo, uploadRepo, fileStorage, cfg.Storage.OrphanGrace)
 
-	r := httpTransport.NewRouter(userSvc, authSvc, incomeSvc, expenseSvc)
+	// Tareas periódicas; se detienen al apagar el servidor
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	uploadSweep := cfg.Storage.UploadSweep
+	if uploadSweep == 0 {
+		uploadSweep = 15 * time.Minute
+	}
+	jobs.Every(jobsCtx, "expire-uploads", uploadSweep, func(ctx context.Context) error {
+		expired, err := uploadSvc.ExpireStale(ctx)
+		if expired > 0 {
+			log.Printf("expired %d abandoned uploads", expired)
+		}
+		return err
+	})
+	jobs.Every(jobsCtx, "purge-refresh-tokens", time.Hour, func(ctx context.Context) error {
+		_, err := authSvc.PurgeExpiredTokens(ctx)
+		return err
+	})
+	jobs.Every(jobsCtx, "purge-sessions", time.Hour, func(ctx context.Context) error {
+		_, err := authSvc.PurgeExpiredSessions(ctx)
+		return err
+	})
+	jobs.Every(jobsCtx, "purge-user-tokens", time.Hour, func(ctx context.Context) error {
+		_, err := accountSvc.PurgeExpiredTokens(ctx)
+		return err
+	})
+	jobs.Every(jobsCtx, "purge-login-throttles", time.Hour, func(ctx context.Context) error {
+		_, err := loginGuard.PurgeStale(ctx)
+		return err
+	})
+	if cfg.Login.EventRetention > 0 {
+		jobs.Every(jobsCtx, "purge-security-events", 24*time.Hour, func(ctx context.Context) error {
+			_, err := loginGuard.PurgeEvents(ctx, cfg.Login.EventRetention)
+			return err
+		})
+	}
+	jobs.Every(jobsCtx, "purge-oauth-states", time.Hour, func(ctx context.Context) error {
+		_, err := oidcSvc.PurgeExpiredStates(ctx)
+		return err
+	})
+	if cfg.Trash.Retention > 0 {
+		trashSweep := cfg.Trash.Sweep
+		if trashSweep == 0 {
+			trashSweep = time.Hour
+		}
+		jobs.Every(jobsCtx, "purge-trash", trashSweep, func(ctx context.Context) error {
+			report, err := trashSvc.Purge(ctx)
+			if report != nil && report.Incomes+report.Expenses > 0 {
+				log.Printf("purged %d incomes and %d expenses from trash", report.Incomes, report.Expenses)
+			}
+			return err
+		})
+	}
+
+	if reminderSvc.Enabled() {
+		reminderSweep := cfg.Reminders.Sweep
+		if reminderSweep == 0 {
+			reminderSweep = time.Hour
+		}
+		jobs.Every(jobsCtx, "remind-pending-receipts", reminderSweep, func(ctx context.Context) error {
+			sent, err := reminderSvc.SendReminders(ctx)
+			if sent > 0 {
+				log.Printf("sent %d pending receipt reminders", sent)
+			}
+			return err
+		})
+	}
+
+	r := httpTransport.NewRouter(
+		userSvc,
+		authSvc,
+		incomeSvc,
+		expenseSvc,
+		receiptSvc,
+		maintenanceSvc,
+		uploadSvc,
+		exportSvc,
+		searchSvc,
+		analysisSvc,
+		reminderSvc,
+		oidcSvc,
+		twoFactorSvc,
+		accountSvc,
+		apiKeySvc,
+		cfg.Storage.MaxFileSize,
+	)
+	// Sin proxies de confianza la IP del cliente es la de la conexión: X-Forwarded-For no
+	// se puede falsear para esquivar el límite de intentos por IP
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("X error in trusted proxies: %v", err)
+	}
 
 	// Mostrar que la config se cargó correctamente
 	fmt.Println("=================================")
@@ -65,7 +327,6 @@ func main() {
 	fmt.Printf("Server running on %s:%d\n", cfg.Server.Host, cfg.Server.Port)
 	fmt.Printf("Debug mode: %v\n", cfg.App.Debug)
 	fmt.Printf("Google Client ID: %s\n", cfg.Google.ClientID)
-	fmt.Printf("Google Client Secret: %s\n", cfg.Google.ClientSecret)
 	fmt.Printf("Google Redirect URL: %s\n", cfg.Google.RedirectURL)
 	fmt.Println("=================================")
 
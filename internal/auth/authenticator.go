@@ -56,6 +56,11 @@ func (a *JWTAuthenticator) GenerateToken(claims jwt.Claims) (string, error) {
 	return signedString, nil
 }
 
+// JWKS devuelve un conjunto vacío: el secreto HMAC no se puede publicar.
+func (a *JWTAuthenticator) JWKS() JWKS {
+	return JWKS{Keys: []JWK{}}
+}
+
 // ValidateToken parsea y valida un token JWT string.
 // Verifica que:
 // - El método de firma sea HMAC (HS256) para evitar ataques con 'alg' maliciosos.
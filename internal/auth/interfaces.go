@@ -31,4 +31,8 @@ type Authenticator interface {
 	// Devuelve el token parseado si es válido o un error en caso contrario.
 	// Usado en middleware u otros puntos de verificación.
 	ValidateToken(token string) (*jwt.Token, error)
+
+	// JWKS devuelve las claves públicas con las que otros servicios pueden verificar
+	// los tokens. Con un secreto compartido (HS256) no hay ninguna que publicar.
+	JWKS() JWKS
 }
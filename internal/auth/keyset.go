@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+
+	"github.com/SaidMg10/gestor-one/internal/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Algoritmos de firma soportados.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// minRSABits es el tamaño mínimo aceptado para las claves RSA.
+const minRSABits = 2048
+
+// JWK es una clave pública publicada en el JWKS (RFC 7517, RFC 8037 para Ed25519).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS es el documento de /.well-known/jwks.json.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// verificationKey es una clave del conjunto; private es nil si solo se tiene la pública.
+type verificationKey struct {
+	alg     string
+	public  crypto.PublicKey
+	private crypto.PrivateKey
+}
+
+// KeySetAuthenticator firma los tokens con la clave activa (RS256 o EdDSA) y pone su kid
+// en el header. Verifica con cualquiera de las claves del conjunto, elegida por kid: al
+// rotar, las anteriores siguen en el conjunto hasta que vencen los tokens que firmaron.
+type KeySetAuthenticator struct {
+	Aud       string // Audiencia esperada en el token (claim "aud").
+	Iss       string // Emisor esperado en el token (claim "iss").
+	activeKID string
+	keys      map[string]verificationKey
+}
+
+// NewKeySetAuthenticator crea el autenticador con las claves en PEM por kid. Cada PEM
+// puede ser una clave privada (PKCS#8, o PKCS#1 para RSA) o, para claves retiradas que
+// solo verifican, una pública (PKIX). La clave activa tiene que ser privada y de alg.
+func NewKeySetAuthenticator(alg, activeKID string, pems map[string][]byte, aud, iss string) (*KeySetAuthenticator, error) {
+	if alg != AlgRS256 && alg != AlgEdDSA {
+		return nil, fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	keys := make(map[string]verificationKey, len(pems))
+	for kid, data := range pems {
+		key, err := parseKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("jwt key %q: %w", kid, err)
+		}
+		keys[kid] = key
+	}
+
+	active, ok := keys[activeKID]
+	if !ok {
+		return nil, fmt.Errorf("active jwt key %q is not configured", activeKID)
+	}
+	if active.private == nil {
+		return nil, fmt.Errorf("active jwt key %q has no private key", activeKID)
+	}
+	if active.alg != alg {
+		return nil, fmt.Errorf("active jwt key %q is a %s key, not %s", activeKID, active.alg, alg)
+	}
+
+	return &KeySetAuthenticator{
+		Aud:       aud,
+		Iss:       iss,
+		activeKID: activeKID,
+		keys:      keys,
+	}, nil
+}
+
+// NewAuthenticatorFromConfig crea el Authenticator según JWTConfig.SigningAlgorithm:
+// HS256 (o vacío) usa el secreto compartido; RS256 y EdDSA leen las claves de los
+// archivos PEM de JWTConfig.Keys. Como en NewJWTAuthenticatorFromConfig, la audiencia
+// es el issuer.
+func NewAuthenticatorFromConfig(authCfg config.JWTConfig) (Authenticator, error) {
+	switch authCfg.SigningAlgorithm {
+	case "", AlgHS256:
+		if authCfg.Secret == "" {
+			return nil, errors.New("jwt secret is required for HS256")
+		}
+		return NewJWTAuthenticatorFromConfig(authCfg), nil
+	case AlgRS256, AlgEdDSA:
+		pems := make(map[string][]byte, len(authCfg.Keys))
+		for kid, path := range authCfg.Keys {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("jwt key %q: %w", kid, err)
+			}
+			pems[kid] = data
+		}
+		return NewKeySetAuthenticator(authCfg.SigningAlgorithm, authCfg.ActiveKeyID, pems, authCfg.Issuer, authCfg.Issuer)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", authCfg.SigningAlgorithm)
+	}
+}
+
+// GenerateToken firma los claims con la clave activa.
+func (a *KeySetAuthenticator) GenerateToken(claims jwt.Claims) (string, error) {
+	active := a.keys[a.activeKID]
+	token := jwt.NewWithClaims(signingMethod(active.alg), claims)
+	token.Header["kid"] = a.activeKID
+	return token.SignedString(active.private)
+}
+
+// ValidateToken verifica la firma con la clave del kid del header, que tiene que ser del
+// mismo algoritmo que el token, y los mismos claims que JWTAuthenticator.
+func (a *KeySetAuthenticator) ValidateToken(token string) (*jwt.Token, error) {
+	return jwt.Parse(token, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, ok := a.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		if t.Method.Alg() != key.alg {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return key.public, nil
+	},
+		jwt.WithExpirationRequired(),
+		jwt.WithAudience(a.Aud),
+		jwt.WithIssuer(a.Iss),
+		jwt.WithValidMethods([]string{AlgRS256, AlgEdDSA}),
+	)
+}
+
+// JWKS devuelve las claves públicas del conjunto, la activa primero.
+func (a *KeySetAuthenticator) JWKS() JWKS {
+	kids := make([]string, 0, len(a.keys))
+	for kid := range a.keys {
+		kids = append(kids, kid)
+	}
+	sort.Slice(kids, func(i, j int) bool {
+		if (kids[i] == a.activeKID) != (kids[j] == a.activeKID) {
+			return kids[i] == a.activeKID
+		}
+		return kids[i] < kids[j]
+	})
+
+	set := JWKS{Keys: make([]JWK, 0, len(kids))}
+	for _, kid := range kids {
+		key := a.keys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.alg}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// parseKey lee una clave RSA o Ed25519 en PEM, privada o pública.
+func parseKey(data []byte) (verificationKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return verificationKey{}, errors.New("no PEM block found")
+	}
+
+	var parsed any
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return verificationKey{}, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return verificationKey{}, err
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < minRSABits {
+			return verificationKey{}, fmt.Errorf("rsa key must have at least %d bits", minRSABits)
+		}
+		return verificationKey{alg: AlgRS256, public: &key.PublicKey, private: key}, nil
+	case *rsa.PublicKey:
+		if key.N.BitLen() < minRSABits {
+			return verificationKey{}, fmt.Errorf("rsa key must have at least %d bits", minRSABits)
+		}
+		return verificationKey{alg: AlgRS256, public: key}, nil
+	case ed25519.PrivateKey:
+		return verificationKey{alg: AlgEdDSA, public: key.Public(), private: key}, nil
+	case ed25519.PublicKey:
+		return verificationKey{alg: AlgEdDSA, public: key}, nil
+	default:
+		return verificationKey{}, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", parsed)
+	}
+}
+
+func signingMethod(alg string) jwt.SigningMethod {
+	if alg == AlgEdDSA {
+		return jwt.SigningMethodEdDSA
+	}
+	return jwt.SigningMethodRS256
+}
@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testIssuer = "gestor-one"
+
+func privatePEM(t *testing.T, key crypto.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func publicPEM(t *testing.T, key crypto.PublicKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func newEd25519(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newRSA(t *testing.T, bits int) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func testClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"sub": "1",
+		"aud": testIssuer,
+		"iss": testIssuer,
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestNewKeySetAuthenticator(t *testing.T) {
+	ed := newEd25519(t)
+	rsaKey := newRSA(t, 2048)
+	weakRSA := newRSA(t, 1024)
+
+	tests := []struct {
+		name   string
+		alg    string
+		active string
+		pems   map[string][]byte
+		ok     bool
+	}{
+		{"EdDSA", AlgEdDSA, "k1", map[string][]byte{"k1": privatePEM(t, ed)}, true},
+		{"RS256", AlgRS256, "k1", map[string][]byte{"k1": privatePEM(t, rsaKey)}, true},
+		{"RS256 PKCS#1", AlgRS256, "k1", map[string][]byte{"k1": pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})}, true},
+		{"HS256 no es de este autenticador", AlgHS256, "k1", map[string][]byte{"k1": privatePEM(t, ed)}, false},
+		{"clave activa ausente", AlgEdDSA, "k2", map[string][]byte{"k1": privatePEM(t, ed)}, false},
+		{"clave activa solo pública", AlgEdDSA, "k1", map[string][]byte{"k1": publicPEM(t, ed.Public())}, false},
+		{"clave activa de otro algoritmo", AlgRS256, "k1", map[string][]byte{"k1": privatePEM(t, ed)}, false},
+		{"RSA menor a 2048 bits", AlgRS256, "k1", map[string][]byte{"k1": privatePEM(t, weakRSA)}, false},
+		{"RSA retirada menor a 2048 bits", AlgEdDSA, "k1", map[string][]byte{"k1": privatePEM(t, ed), "old": publicPEM(t, &weakRSA.PublicKey)}, false},
+		{"sin PEM", AlgEdDSA, "k1", map[string][]byte{"k1": []byte("not a key")}, false},
+	}
+	for _, tt := range tests {
+		_, err := NewKeySetAuthenticator(tt.alg, tt.active, tt.pems, testIssuer, testIssuer)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: NewKeySetAuthenticator error = %v; want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestKeySetValidateToken(t *testing.T) {
+	active := newEd25519(t)
+	retiredEd := newEd25519(t)
+	retiredRSA := newRSA(t, 2048)
+	other := newEd25519(t)
+
+	// Rotación de RS256 a EdDSA: las claves anteriores quedan solo con la pública
+	a, err := NewKeySetAuthenticator(AlgEdDSA, "ed-2", map[string][]byte{
+		"ed-2":  privatePEM(t, active),
+		"ed-1":  publicPEM(t, retiredEd.Public()),
+		"rsa-1": publicPEM(t, &retiredRSA.PublicKey),
+	}, testIssuer, testIssuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issued, err := a.GenerateToken(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := testClaims()
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	wrongAud := testClaims()
+	wrongAud["aud"] = "someone-else"
+	noExp := testClaims()
+	delete(noExp, "exp")
+	rsaPublicDER, _ := x509.MarshalPKIXPublicKey(&retiredRSA.PublicKey)
+
+	tests := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"firmado con la clave activa", issued, true},
+		{"kid retirado Ed25519", sign(t, jwt.SigningMethodEdDSA, "ed-1", retiredEd, testClaims()), true},
+		{"kid retirado RSA", sign(t, jwt.SigningMethodRS256, "rsa-1", retiredRSA, testClaims()), true},
+		{"kid desconocido", sign(t, jwt.SigningMethodEdDSA, "ed-9", active, testClaims()), false},
+		{"sin kid", sign(t, jwt.SigningMethodEdDSA, "", active, testClaims()), false},
+		{"firma de otra clave con un kid conocido", sign(t, jwt.SigningMethodEdDSA, "ed-1", other, testClaims()), false},
+		{"alg RS256 con kid de una clave Ed25519", sign(t, jwt.SigningMethodRS256, "ed-2", retiredRSA, testClaims()), false},
+		{"alg EdDSA con kid de una clave RSA", sign(t, jwt.SigningMethodEdDSA, "rsa-1", active, testClaims()), false},
+		// Confusión de algoritmos: la clave pública RSA usada como secreto HMAC
+		{"alg HS256 con la pública como secreto", sign(t, jwt.SigningMethodHS256, "rsa-1", rsaPublicDER, testClaims()), false},
+		{"alg none", sign(t, jwt.SigningMethodNone, "ed-2", jwt.UnsafeAllowNoneSignatureType, testClaims()), false},
+		{"vencido", sign(t, jwt.SigningMethodEdDSA, "ed-2", active, expired), false},
+		{"otra audiencia", sign(t, jwt.SigningMethodEdDSA, "ed-2", active, wrongAud), false},
+		{"sin exp", sign(t, jwt.SigningMethodEdDSA, "ed-2", active, noExp), false},
+	}
+	for _, tt := range tests {
+		_, err := a.ValidateToken(tt.token)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s: ValidateToken error = %v; want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestKeySetGenerateTokenUsesActiveKey(t *testing.T) {
+	key := newRSA(t, 2048)
+	a, err := NewKeySetAuthenticator(AlgRS256, "rsa-2", map[string][]byte{
+		"rsa-2": privatePEM(t, key),
+		"ed-1":  privatePEM(t, newEd25519(t)),
+	}, testIssuer, testIssuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := a.GenerateToken(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := a.ValidateToken(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Header["kid"] != "rsa-2" || token.Method.Alg() != AlgRS256 {
+		t.Errorf("header = %v; want kid rsa-2 and RS256", token.Header)
+	}
+}
+
+func TestKeySetJWKS(t *testing.T) {
+	ed := newEd25519(t)
+	rsaKey := newRSA(t, 2048)
+	a, err := NewKeySetAuthenticator(AlgEdDSA, "b-ed", map[string][]byte{
+		"b-ed":  privatePEM(t, ed),
+		"a-rsa": publicPEM(t, &rsaKey.PublicKey),
+	}, testIssuer, testIssuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b64 := base64.RawURLEncoding.EncodeToString
+	want := []JWK{
+		// La activa va primero aunque su kid ordene después
+		{Kty: "OKP", Kid: "b-ed", Use: "sig", Alg: AlgEdDSA, Crv: "Ed25519", X: b64(ed.Public().(ed25519.PublicKey))},
+		// e = 65537 = 0x010001 -> "AQAB"
+		{Kty: "RSA", Kid: "a-rsa", Use: "sig", Alg: AlgRS256, N: b64(rsaKey.N.Bytes()), E: "AQAB"},
+	}
+	got := a.JWKS().Keys
+	if len(got) != len(want) {
+		t.Fatalf("JWKS has %d keys; want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("JWKS key %d = %+v; want %+v", i, got[i], want[i])
+		}
+	}
+
+	// n es big-endian sin ceros a la izquierda: 2048 bits son 256 bytes
+	n, err := base64.RawURLEncoding.DecodeString(got[1].N)
+	if err != nil || len(n) != 256 || n[0] == 0 {
+		t.Errorf("JWKS n decodes to %d bytes (err %v); want 256 without leading zero", len(n), err)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(got[0].X)
+	if err != nil || len(x) != ed25519.PublicKeySize {
+		t.Errorf("JWKS x decodes to %d bytes (err %v); want %d", len(x), err, ed25519.PublicKeySize)
+	}
+}
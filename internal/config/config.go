@@ -2,6 +2,8 @@
 package config
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -14,11 +16,22 @@ import (
 // ----------------------
 
 type Config struct {
-	App      AppConfig          `mapstructure:"app"`
-	Server   ServerConfig       `mapstructure:"server"`
-	Database DBConfig           `mapstructure:"database"`
-	JWT      JWTConfig          `mapstructure:"jwt"`
-	Google   GoogleOAuth2Config `mapstructure:"google_oauth2"`
+	App       AppConfig          `mapstructure:"app"`
+	Server    ServerConfig       `mapstructure:"server"`
+	Database  DBConfig           `mapstructure:"database"`
+	JWT       JWTConfig          `mapstructure:"jwt"`
+	Google    GoogleOAuth2Config `mapstructure:"google_oauth2"`
+	OIDC      OIDCConfig         `mapstructure:"oidc"`
+	TwoFactor TwoFactorConfig    `mapstructure:"twofactor"`
+	Login     LoginConfig        `mapstructure:"login"`
+	APIKeys   APIKeysConfig      `mapstructure:"apikeys"`
+	Account   AccountConfig      `mapstructure:"account"`
+	Mailer    MailerConfig       `mapstructure:"mail"`
+	Storage   StorageConfig      `mapstructure:"storage"`
+	Scanner   ScannerConfig      `mapstructure:"scanner"`
+	Trash     TrashConfig        `mapstructure:"trash"`
+	Extractor ExtractorConfig    `mapstructure:"extractor"`
+	Reminders RemindersConfig    `mapstructure:"reminders"`
 }
 
 // AppConfig es la Configuración general de la aplicación
@@ -38,6 +51,8 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`  // ej: 5s
 	WriteTimeout time.Duration `mapstructure:"write_timeout"` // ej: 10s
 	IdleTimeout  time.Duration `mapstructure:"idle_timeout"`  // ej: 120s
+
+	TrustedProxies []string `mapstructure:"trusted_proxies"` // IPs/CIDR cuyo X-Forwarded-For se cree, ej: 10.0.0.0/8 (vacío = ninguno)
 }
 
 // DBConfig es la Configuración de la base de datos
@@ -60,7 +75,10 @@ type JWTConfig struct {
 	AccessTokenTTL   time.Duration `mapstructure:"access_token_ttl"`  // ej: 15m
 	RefreshTokenTTL  time.Duration `mapstructure:"refresh_token_ttl"` // ej: 7d
 	Issuer           string        `mapstructure:"issuer"`            // emisor
-	SigningAlgorithm string        `mapstructure:"signing_algorithm"` // ej: HS256
+	SigningAlgorithm string        `mapstructure:"signing_algorithm"` // HS256, RS256, EdDSA
+
+	ActiveKeyID string            `mapstructure:"active_key_id"` // kid con el que se firman los tokens nuevos (RS256/EdDSA)
+	Keys        map[string]string `mapstructure:"keys"`          // kid -> archivo PEM; las que no son la activa solo verifican
 }
 
 // GoogleOAuth2Config es la Configuración de Google OAuth2
@@ -69,6 +87,145 @@ type GoogleOAuth2Config struct {
 	ClientSecret string   `mapstructure:"client_secret"`
 	RedirectURL  string   `mapstructure:"redirect_url"`
 	Scopes       []string `mapstructure:"scopes"`
+	IssuerURL    string   `mapstructure:"issuer_url"` // ej: https://accounts.google.com, o un servidor OIDC local para pruebas
+}
+
+// OIDCConfig es la Configuración de los proveedores OIDC corporativos (Azure AD, Keycloak...)
+type OIDCConfig struct {
+	Providers map[string]OIDCProviderConfig `mapstructure:"providers"` // nombre -> proveedor; callback en /api/v1/auth/oidc/<nombre>/callback
+}
+
+// OIDCProviderConfig es la Configuración de un proveedor OIDC
+type OIDCProviderConfig struct {
+	IssuerURL    string   `mapstructure:"issuer_url"`    // ej: https://login.microsoftonline.com/<tenant>/v2.0
+	ClientID     string   `mapstructure:"client_id"`     // vacío = proveedor deshabilitado
+	ClientSecret string   `mapstructure:"client_secret"` // secreto del cliente
+	RedirectURL  string   `mapstructure:"redirect_url"`  // ej: http://localhost:8080/api/v1/auth/oidc/keycloak/callback
+	Scopes       []string `mapstructure:"scopes"`        // ej: openid,profile,email
+	RoleClaim    string   `mapstructure:"role_claim"`    // ej: groups, realm_access.roles (vacío = no se mapean roles)
+	RoleMap      []string `mapstructure:"role_map"`      // valor:rol, ej: gestor-admins:admin,contables:accountant
+	DefaultRole  string   `mapstructure:"default_role"`  // rol sin coincidencias en role_map, ej: employee
+	JIT          bool     `mapstructure:"jit"`           // crear el usuario en su primer login
+	Domains      []string `mapstructure:"domains"`       // dominios de email que solo pueden entrar por este proveedor
+}
+
+// TwoFactorConfig es la Configuración del segundo factor (TOTP)
+type TwoFactorConfig struct {
+	Issuer   string        `mapstructure:"issuer"`    // nombre que muestra la app de autenticación, ej: Gestor One
+	LoginTTL time.Duration `mapstructure:"login_ttl"` // ej: 5m para ingresar el código después de la contraseña
+}
+
+// LoginConfig es la Configuración de la protección contra fuerza bruta del login
+type LoginConfig struct {
+	FailureWindow      time.Duration `mapstructure:"failure_window"`       // ej: 15m sin fallos y el contador vuelve a cero
+	BackoffAfter       int           `mapstructure:"backoff_after"`        // fallos antes de empezar a demorar, ej: 3 (0 = sin demora)
+	BackoffBase        time.Duration `mapstructure:"backoff_base"`         // primera espera, se duplica con cada fallo, ej: 1s
+	BackoffMax         time.Duration `mapstructure:"backoff_max"`          // ej: 5m
+	AccountMaxFailures int           `mapstructure:"account_max_failures"` // fallos por cuenta antes del bloqueo, ej: 10 (0 = nunca)
+	IPMaxFailures      int           `mapstructure:"ip_max_failures"`      // fallos por IP antes del bloqueo, ej: 50 (0 = nunca)
+	LockoutDuration    time.Duration `mapstructure:"lockout_duration"`     // ej: 30m (un admin puede desbloquear antes)
+	EventRetention     time.Duration `mapstructure:"event_retention"`      // ej: 2160h de registro de seguridad (0 = nunca se borra)
+}
+
+// APIKeysConfig es la Configuración de las API keys de los usuarios
+type APIKeysConfig struct {
+	DefaultTTL time.Duration `mapstructure:"default_ttl"` // vencimiento si no se pide otro, ej: 2160h
+	MaxTTL     time.Duration `mapstructure:"max_ttl"`     // vencimiento máximo, ej: 8760h
+}
+
+// AccountConfig es la Configuración de la recuperación de contraseña y la verificación de email
+type AccountConfig struct {
+	ResetTokenTTL    time.Duration `mapstructure:"reset_token_ttl"`     // ej: 1h para usar el enlace de recuperación
+	VerifyTokenTTL   time.Duration `mapstructure:"verify_token_ttl"`    // ej: 48h para verificar el email
+	LinkBaseURL      string        `mapstructure:"link_base_url"`       // URL del frontend para los enlaces (vacío = app.fe_origin_url)
+	ResetMaxPerEmail int           `mapstructure:"reset_max_per_email"` // emails de recuperación por cuenta en login.failure_window (0 = 3)
+	ResetMaxPerIP    int           `mapstructure:"reset_max_per_ip"`    // pedidos de recuperación por IP en login.failure_window (0 = 20)
+}
+
+// MailerConfig es la Configuración del envío de emails
+type MailerConfig struct {
+	Driver       string        `mapstructure:"driver"`        // none, log, smtp
+	From         string        `mapstructure:"from"`          // ej: Gestor One <no-reply@example.com>
+	SMTPHost     string        `mapstructure:"smtp_host"`     // ej: localhost
+	SMTPPort     int           `mapstructure:"smtp_port"`     // ej: 587 (1025 para Mailpit)
+	SMTPUsername string        `mapstructure:"smtp_username"` // vacío = sin autenticación
+	SMTPPassword string        `mapstructure:"smtp_password"` // contraseña SMTP
+	SMTPTLS      string        `mapstructure:"smtp_tls"`      // none, starttls, tls
+	Timeout      time.Duration `mapstructure:"timeout"`       // ej: 30s
+}
+
+// StorageConfig es la Configuración del almacenamiento de comprobantes
+type StorageConfig struct {
+	Driver      string           `mapstructure:"driver"`        // local, s3
+	LocalDir    string           `mapstructure:"local_dir"`     // ej: ./uploads
+	MaxFileSize int64            `mapstructure:"max_file_size"` // bytes por archivo, ej: 10485760
+	URLSignKey  string           `mapstructure:"url_sign_key"`  // clave HMAC para URLs de descarga (obligatoria)
+	URLTTL      time.Duration    `mapstructure:"url_ttl"`       // ej: 5m
+	OrphanGrace time.Duration    `mapstructure:"orphan_grace"`  // ej: 24h antes de poner huérfanos en cuarentena
+	UploadDir   string           `mapstructure:"upload_dir"`    // parciales de subidas reanudables, ej: ./uploads-tmp
+	UploadTTL   time.Duration    `mapstructure:"upload_ttl"`    // ej: 24h hasta que un upload sin usar vence
+	UploadSweep time.Duration    `mapstructure:"upload_sweep"`  // ej: 15m entre limpiezas de uploads vencidos
+	S3          S3StorageConfig  `mapstructure:"s3"`
+	Encryption  EncryptionConfig `mapstructure:"encryption"`
+	PDF         PDFConfig        `mapstructure:"pdf"`
+	Watermark   WatermarkConfig  `mapstructure:"watermark"`
+}
+
+// S3StorageConfig es la Configuración de un bucket compatible con S3 (AWS, MinIO)
+type S3StorageConfig struct {
+	Endpoint  string `mapstructure:"endpoint"`   // ej: localhost:9000
+	AccessKey string `mapstructure:"access_key"` // access key id
+	SecretKey string `mapstructure:"secret_key"` // secret access key
+	Bucket    string `mapstructure:"bucket"`     // ej: gestor-one
+	Region    string `mapstructure:"region"`     // ej: us-east-1
+	UseSSL    bool   `mapstructure:"use_ssl"`    // false para MinIO local
+}
+
+// EncryptionConfig es la Configuración del cifrado en reposo de comprobantes
+type EncryptionConfig struct {
+	Enabled     bool              `mapstructure:"enabled"`       // true/false
+	ActiveKeyID string            `mapstructure:"active_key_id"` // key con la que se cifran los archivos nuevos
+	MasterKeys  map[string]string `mapstructure:"master_keys"`   // key id -> clave AES-256 en base64
+}
+
+// PDFConfig es la Política de validación y saneamiento de los PDFs subidos
+type PDFConfig struct {
+	ActiveContent  string `mapstructure:"active_content"`  // strip, reject (JavaScript y acciones Launch)
+	Attachments    string `mapstructure:"attachments"`     // strip, reject
+	AllowEncrypted bool   `mapstructure:"allow_encrypted"` // true/false
+	MaxPages       int    `mapstructure:"max_pages"`       // 0 = sin límite
+	Strict         bool   `mapstructure:"strict"`          // validación estricta de la estructura
+}
+
+// WatermarkConfig es la Configuración de la marca de agua en las descargas de comprobantes
+type WatermarkConfig struct {
+	Roles []string `mapstructure:"roles"` // roles cuyas descargas se marcan, ej: employee,accountant
+}
+
+// ScannerConfig es la Configuración del antivirus para los archivos subidos
+type ScannerConfig struct {
+	Driver     string        `mapstructure:"driver"`      // none, clamav
+	Address    string        `mapstructure:"address"`     // ej: unix:///var/run/clamav/clamd.ctl o tcp://localhost:3310
+	Timeout    time.Duration `mapstructure:"timeout"`     // ej: 30s
+	OnInfected string        `mapstructure:"on_infected"` // reject, quarantine
+	OnError    string        `mapstructure:"on_error"`    // reject, quarantine, allow
+}
+
+// TrashConfig es la Configuración de la papelera de ingresos y gastos borrados
+type TrashConfig struct {
+	Retention time.Duration `mapstructure:"retention"` // ej: 720h antes de borrar definitivamente (0 = nunca)
+	Sweep     time.Duration `mapstructure:"sweep"`     // ej: 1h entre purgas
+}
+
+// ExtractorConfig es la Configuración del análisis de comprobantes que sugiere monto, fecha y proveedor
+type ExtractorConfig struct {
+	Driver string `mapstructure:"driver"` // none, heuristic
+}
+
+// RemindersConfig es la Configuración de los recordatorios de comprobantes pendientes
+type RemindersConfig struct {
+	PendingReceiptDays int           `mapstructure:"pending_receipt_days"` // días sin comprobante antes de recordar (0 = nunca)
+	Sweep              time.Duration `mapstructure:"sweep"`                // ej: 1h entre pasadas
 }
 
 // -----------------------
@@ -103,6 +260,30 @@ func (c *Config) GetGoogleOAuthConfig() *oauth2.Config {
 	}
 }
 
+// GetOAuthConfig devuelve la configuración OAuth2 del proveedor; los endpoints salen
+// del discovery del issuer.
+func (p OIDCProviderConfig) GetOAuthConfig() *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     p.ClientID,
+		ClientSecret: p.ClientSecret,
+		RedirectURL:  p.RedirectURL,
+		Scopes:       p.Scopes,
+	}
+}
+
+// GetRoleMap interpreta role_map ("valor:rol") como valor del claim -> rol.
+func (p OIDCProviderConfig) GetRoleMap() (map[string]string, error) {
+	roles := make(map[string]string, len(p.RoleMap))
+	for _, entry := range p.RoleMap {
+		value, role, ok := strings.Cut(entry, ":")
+		if !ok || strings.TrimSpace(value) == "" {
+			return nil, fmt.Errorf("invalid role_map entry %q, expected value:role", entry)
+		}
+		roles[strings.TrimSpace(value)] = strings.TrimSpace(role)
+	}
+	return roles, nil
+}
+
 var Cfg *Config
 
 // Init inicializa la configuración y la deja accesible globalmente
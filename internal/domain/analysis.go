@@ -0,0 +1,40 @@
+package domain
+
+import "time"
+
+// ReceiptAnalysis holds the transaction fields suggested from a receipt before it is created.
+// A nil field means nothing usable was found. Confidence goes from 0 (guess) to 1 (certain).
+type ReceiptAnalysis struct {
+	Extractor string            `json:"extractor"`
+	HasText   bool              `json:"has_text"` // false for scanned PDFs without a text layer
+	Amount    *AmountSuggestion `json:"amount,omitempty"`
+	Date      *DateSuggestion   `json:"date,omitempty"`
+	TaxID     *TaxIDSuggestion  `json:"tax_id,omitempty"`
+	Vendor    *TextSuggestion   `json:"vendor,omitempty"`
+}
+
+// AmountSuggestion is the suggested total of a receipt.
+type AmountSuggestion struct {
+	Value      float64 `json:"value"`
+	Currency   string  `json:"currency,omitempty"` // ISO 4217 code when the receipt states it
+	Confidence float64 `json:"confidence"`
+}
+
+// DateSuggestion is the suggested issue date of a receipt.
+type DateSuggestion struct {
+	Value      time.Time `json:"value"`
+	Confidence float64   `json:"confidence"`
+}
+
+// TaxIDSuggestion is the suggested tax ID of the issuer (NIF/CIF, RFC, CUIT, EIN, VAT...).
+type TaxIDSuggestion struct {
+	Value      string  `json:"value"`
+	Scheme     string  `json:"scheme,omitempty"`
+	Confidence float64 `json:"confidence"`
+}
+
+// TextSuggestion is a suggested free-text field.
+type TextSuggestion struct {
+	Value      string  `json:"value"`
+	Confidence float64 `json:"confidence"`
+}
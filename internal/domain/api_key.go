@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// APIKeyPrefix starts every API key, so the auth middleware can tell them from JWTs.
+const APIKeyPrefix = "gop_"
+
+// APIKeyScopes are the scopes an API key may have: "<resource>:read" allows GET and HEAD
+// under /api/v1/<resource>, "<resource>:write" the other methods. Keys cannot be used
+// anywhere else (account, 2FA, admin...).
+var APIKeyScopes = []string{
+	"incomes:read", "incomes:write",
+	"expenses:read", "expenses:write",
+	"uploads:read", "uploads:write",
+	"receipts:read", "receipts:write",
+	"reminders:read", "reminders:write",
+	"exports:read",
+	"search:read",
+}
+
+// IsValidAPIKeyScope reports whether scope is one of APIKeyScopes.
+func IsValidAPIKeyScope(scope string) bool {
+	return slices.Contains(APIKeyScopes, scope)
+}
+
+// APIKey is a personal access token for scripts and integrations. It acts as its user,
+// with the user's role, but only on the endpoints its scopes allow. Only the SHA-256 of
+// the key is stored; Prefix (its first characters) lets the user tell keys apart.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	Name       string     `gorm:"size:100;not null" json:"name"`
+	Prefix     string     `gorm:"size:16;not null" json:"prefix"`
+	TokenHash  string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Scopes     []string   `gorm:"serializer:json;not null" json:"scopes"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `gorm:"size:45" json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// HasScope reports whether the key was granted scope.
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// Usable reports whether the key is neither revoked nor expired at now.
+func (k *APIKey) Usable(now time.Time) bool {
+	return k.RevokedAt == nil && now.Before(k.ExpiresAt)
+}
@@ -1,6 +1,9 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	ErrNotFound          = errors.New("record not found")
@@ -10,4 +13,32 @@ var (
 	ErrPasswordRequired  = errors.New("password is required")
 	ErrInvalidPassword   = errors.New("invalid password")
 	ErrInvalidEmail      = errors.New("invalid email")
+	ErrFileTooLarge      = errors.New("file exceeds the maximum allowed size")
+	ErrForbidden         = errors.New("forbidden")
+	ErrInfectedFile      = errors.New("file rejected by malware scan")
+	ErrScanFailed        = errors.New("file could not be scanned")
+	ErrQuarantined       = errors.New("receipt is quarantined")
+	ErrInvalidPDF        = errors.New("invalid or unsafe PDF")
+	ErrOffsetMismatch    = errors.New("upload offset does not match")
+	ErrUploadIncomplete  = errors.New("upload is not complete")
+	ErrReceiptAttached   = errors.New("transaction already has a receipt")
+	ErrInvalidToken      = errors.New("invalid or expired token")
+	ErrTokenReused       = errors.New("refresh token was already used")
+	ErrSSORequired       = errors.New("this account must sign in with single sign-on")
+	ErrInvalidCode       = errors.New("invalid verification code")
+	ErrTwoFactorEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrTooManyAttempts   = errors.New("too many failed login attempts")
+	ErrAccountLocked     = errors.New("account is temporarily locked")
+	ErrAccountDisabled   = errors.New("account is disabled")
 )
+
+// ThrottledError is returned while logins are delayed or locked out. It wraps
+// ErrTooManyAttempts or ErrAccountLocked; RetryAfter is when the next attempt is accepted.
+type ThrottledError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string { return e.Err.Error() }
+
+func (e *ThrottledError) Unwrap() error { return e.Err }
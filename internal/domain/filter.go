@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// TransactionFilter narrows income and expense listings. Zero values mean "no filter".
+type TransactionFilter struct {
+	From      *time.Time // inclusive
+	To        *time.Time // inclusive
+	Type      string
+	CreatedBy uint
+}
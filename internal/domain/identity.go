@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// UserIdentity links a user to their account in an external OpenID Connect provider.
+// Subject is the provider's "sub" claim, which never changes even if the email does.
+type UserIdentity struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null"`
+	Provider  string `gorm:"size:50;not null"`
+	Subject   string `gorm:"size:255;not null"`
+	Email     string `gorm:"size:150"`
+	CreatedAt time.Time
+}
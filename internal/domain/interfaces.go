@@ -2,40 +2,170 @@ package domain
 
 import (
 	"context"
-	"mime/multipart"
+	"io"
+	"time"
 )
 
 // UserRepo defines an interface with methods for managing User entities.
 type UserRepo interface {
 	GetByEmail(ctx context.Context, email string) (*User, error)
 	GetByID(ctx context.Context, id uint) (*User, error)
+	GetByGoogleID(ctx context.Context, googleID string) (*User, error)
 	List(ctx context.Context) ([]User, error)
 	Create(ctx context.Context, user *User) error
 	Update(ctx context.Context, user *User) error
+	UpdateProfile(ctx context.Context, id uint, profile ProfileUpdate) error
 	Delete(ctx context.Context, id uint) error
 	ExistsByEmail(ctx context.Context, email string) (bool, error)
+	SetEmailVerifiedAt(ctx context.Context, id uint, at *time.Time) error
+}
+
+// UserIdentityRepo stores the links between users and external OpenID Connect accounts.
+// A user has at most one identity per provider: Link reports false, without changing
+// anything, if the identity or the user's link to that provider already exist.
+// CreateUser creates a user and its first identity together.
+type UserIdentityRepo interface {
+	GetUser(ctx context.Context, provider, subject string) (*User, error)
+	Link(ctx context.Context, identity *UserIdentity) (bool, error)
+	CreateUser(ctx context.Context, user *User, identity *UserIdentity) error
+}
+
+// UserTokenRepo stores the tokens emailed to users. Consume marks a token of the given
+// purpose as used and returns it, only if it was unused and not expired; otherwise it
+// returns ErrNotFound.
+type UserTokenRepo interface {
+	Create(ctx context.Context, token *UserToken) error
+	Consume(ctx context.Context, purpose, hash string) (*UserToken, error)
+	DeleteByUser(ctx context.Context, userID uint, purpose string) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// OAuthStateRepo stores the pending OAuth2/OIDC logins between the redirect to the
+// provider and its callback. Consume returns and deletes a state in one step, so each
+// state can be used once.
+type OAuthStateRepo interface {
+	Create(ctx context.Context, state *OAuthState) error
+	Consume(ctx context.Context, id string) (*OAuthState, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// RefreshTokenRepo defines an interface for persisted refresh tokens.
+// MarkUsed marks a token as rotated only if it was neither used nor revoked and reports
+// whether it did, so two concurrent refreshes with the same token cannot both succeed.
+type RefreshTokenRepo interface {
+	Create(ctx context.Context, token *RefreshToken) error
+	GetByHash(ctx context.Context, hash string) (*RefreshToken, error)
+	MarkUsed(ctx context.Context, id uint) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeUser(ctx context.Context, userID uint) error
+	RevokeOthers(ctx context.Context, userID uint, keepFamilyID string) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// SessionRepo stores the signed-in sessions. Seen records activity on an access token;
+// Extend also moves the expiry forward and records the client when the refresh token is rotated.
+type SessionRepo interface {
+	Create(ctx context.Context, session *Session) error
+	GetByID(ctx context.Context, id uint) (*Session, error)
+	GetByFamily(ctx context.Context, familyID string) (*Session, error)
+	ListActive(ctx context.Context, userID uint, now time.Time) ([]Session, error)
+	Seen(ctx context.Context, id uint, at time.Time, ip string) error
+	Extend(ctx context.Context, id uint, at time.Time, device string, client ClientInfo, expiresAt time.Time) error
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeUser(ctx context.Context, userID uint) error
+	RevokeOthers(ctx context.Context, userID uint, keepFamilyID string) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// TwoFactorRepo stores TOTP enrolments, recovery codes and the per-role policy.
+// Begin replaces a pending enrolment but never a confirmed one; Enable confirms it and
+// stores its first recovery codes. UseStep and UseRecoveryCode only succeed once per
+// step or code, and report whether they did.
+type TwoFactorRepo interface {
+	Get(ctx context.Context, userID uint) (*TwoFactor, error)
+	Begin(ctx context.Context, enrolment *TwoFactor) error
+	Enable(ctx context.Context, userID uint, step int64, codeHashes []string) (bool, error)
+	Delete(ctx context.Context, userID uint) error
+	UseStep(ctx context.Context, userID uint, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID uint, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error)
+	CountRecoveryCodes(ctx context.Context, userID uint) (int64, error)
+	ListPolicies(ctx context.Context) ([]TwoFactorPolicy, error)
+	SetPolicy(ctx context.Context, policy *TwoFactorPolicy) error
+	IsRequired(ctx context.Context, role string) (bool, error)
+}
+
+// LoginThrottleRepo stores the failed-login counters. RecordFailure adds a failure to the
+// key atomically, starting over from one if the last failure was before windowStart, and
+// returns the updated counter. Lock sets LockedUntil and clears the failures; Reset
+// deletes the keys.
+type LoginThrottleRepo interface {
+	Get(ctx context.Context, keys ...string) ([]LoginThrottle, error)
+	RecordFailure(ctx context.Context, key string, at, windowStart time.Time) (*LoginThrottle, error)
+	Lock(ctx context.Context, key string, until time.Time) error
+	Reset(ctx context.Context, keys ...string) error
+	DeleteStale(ctx context.Context, before time.Time) (int64, error)
+}
+
+// SecurityEventRepo stores the security log. List returns the newest events first.
+type SecurityEventRepo interface {
+	Create(ctx context.Context, event *SecurityEvent) error
+	List(ctx context.Context, filter SecurityEventFilter) ([]SecurityEvent, error)
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// APIKeyRepo stores personal access tokens. Revoke only affects keys that are not revoked
+// yet and reports whether it did; Touch records the last use of a key.
+type APIKeyRepo interface {
+	Create(ctx context.Context, key *APIKey) error
+	GetByID(ctx context.Context, id uint) (*APIKey, error)
+	GetByHash(ctx context.Context, hash string) (*APIKey, error)
+	ListByUser(ctx context.Context, userID uint) ([]APIKey, error)
+	CountUsable(ctx context.Context, userID uint, now time.Time) (int64, error)
+	Revoke(ctx context.Context, id uint) (bool, error)
+	Touch(ctx context.Context, id uint, at time.Time, ip string) error
 }
 
 // IncomeRepo defines an interface with methods for managing Income entities.
+// GetByID and the listings skip soft-deleted rows; the *Deleted methods only see the trash.
+// Purge hard-deletes a trashed row only if it was deleted before cutoff and reports whether it did.
+// CreateWithReceipt accepts a nil receipt for rows created with ReceiptPending.
+// AttachReceipt saves the receipt of a pending row and clears the flag; ErrReceiptAttached if it was not pending.
 type IncomeRepo interface {
 	GetByID(ctx context.Context, id uint) (*Income, error)
 	List(ctx context.Context) ([]Income, error)
+	ListFiltered(ctx context.Context, filter TransactionFilter) ([]Income, error)
 	CreateWithReceipt(ctx context.Context, income *Income, receipt *Receipt) error
 	UpdateWithReceipt(ctx context.Context, income *Income, receipt *Receipt) error
+	AttachReceipt(ctx context.Context, id uint, receipt *Receipt) error
 	Delete(ctx context.Context, id uint) error
 	SoftDelete(ctx context.Context, id uint) error
 	Restore(ctx context.Context, id uint) error
+	GetDeletedByID(ctx context.Context, id uint) (*Income, error)
+	ListDeleted(ctx context.Context) ([]Income, error)
+	ListDeletedBefore(ctx context.Context, cutoff time.Time) ([]Income, error)
+	Purge(ctx context.Context, id uint, cutoff time.Time) (bool, error)
 }
 
 // ExpenseRepo defines an interface with methods for managing Expense entities.
+// GetByID and the listings skip soft-deleted rows; the *Deleted methods only see the trash.
+// Purge hard-deletes a trashed row only if it was deleted before cutoff and reports whether it did.
+// CreateWithReceipt accepts a nil receipt for rows created with ReceiptPending.
+// AttachReceipt saves the receipt of a pending row and clears the flag; ErrReceiptAttached if it was not pending.
 type ExpenseRepo interface {
 	GetByID(ctx context.Context, id uint) (*Expense, error)
 	List(ctx context.Context) ([]Expense, error)
+	ListFiltered(ctx context.Context, filter TransactionFilter) ([]Expense, error)
 	CreateWithReceipt(ctx context.Context, expense *Expense, receipt *Receipt) error
 	UpdateWithReceipt(ctx context.Context, expense *Expense, receipt *Receipt) error
+	AttachReceipt(ctx context.Context, id uint, receipt *Receipt) error
 	Delete(ctx context.Context, id uint) error
 	SoftDelete(ctx context.Context, id uint) error
 	Restore(ctx context.Context, id uint) error
+	GetDeletedByID(ctx context.Context, id uint) (*Expense, error)
+	ListDeleted(ctx context.Context) ([]Expense, error)
+	ListDeletedBefore(ctx context.Context, cutoff time.Time) ([]Expense, error)
+	Purge(ctx context.Context, id uint, cutoff time.Time) (bool, error)
 }
 
 // ReceiptRepo defines an interface with methods for managing Receipt entities.
@@ -47,7 +177,65 @@ type ReceiptRepo interface {
 	Delete(ctx context.Context, id uint) error
 }
 
+// UploadRepo defines an interface with methods for managing staged resumable uploads.
+// Consume deletes the upload only if it still exists and reports whether it did,
+// so two requests cannot attach the same staged file.
+type UploadRepo interface {
+	GetByID(ctx context.Context, id string) (*Upload, error)
+	List(ctx context.Context) ([]Upload, error)
+	ListExpired(ctx context.Context, now time.Time) ([]Upload, error)
+	Create(ctx context.Context, upload *Upload) error
+	UpdateOffset(ctx context.Context, id string, from, to int64) error
+	Update(ctx context.Context, upload *Upload) error
+	Consume(ctx context.Context, id string) (bool, error)
+	Delete(ctx context.Context, id string) error
+}
+
+// SearchRepo defines full-text search over transactions and receipt contents.
+type SearchRepo interface {
+	Search(ctx context.Context, query SearchQuery) ([]SearchResult, error)
+}
+
+// ReminderRepo defines an interface for pending receipts and the reminders sent about them.
+// ListPending lists all users when userID is 0. ListDue returns pending rows created before
+// cutoff that were never reminded or were reminded before cutoff. Remind records the reminder
+// only if the row is still pending and was not reminded since item.RemindedAt, and reports
+// whether it did. ListByUser skips reminders whose transaction is no longer pending.
+type ReminderRepo interface {
+	ListPending(ctx context.Context, userID uint) ([]PendingReceipt, error)
+	ListDue(ctx context.Context, cutoff time.Time) ([]PendingReceipt, error)
+	Remind(ctx context.Context, item PendingReceipt, reminder *Reminder) (bool, error)
+	ListByUser(ctx context.Context, userID uint) ([]Reminder, error)
+	MarkRead(ctx context.Context, userID, id uint) error
+}
+
+// ReceiptExtractor suggests transaction fields from a receipt PDF of size bytes.
+// An error means the file could not be read; finding nothing is a valid, empty analysis.
+type ReceiptExtractor interface {
+	Extract(ctx context.Context, r io.ReaderAt, size int64) (*ReceiptAnalysis, error)
+}
+
+// FileStorage defines where receipt files are persisted (local disk, S3, ...).
+// SavePDF streams r once; size is the expected length or -1 when unknown.
+// ListPDFs and QuarantinePDF exist for maintenance tasks (consistency checks, orphan cleanup).
+// DeletePDF and DeleteQuarantinedPDF return ErrNotFound when there was nothing to delete;
+// a quarantined file keeps its original relPath but lives apart from the rest.
 type FileStorage interface {
-	SavePDF(fileHeader *multipart.FileHeader) (string, string, string, error)
-	DeletePDF(filePath string) error
+	SavePDF(ctx context.Context, r io.Reader, size int64) (*StoredFile, error)
+	OpenPDF(ctx context.Context, relPath string) (io.ReadCloser, error)
+	DeletePDF(ctx context.Context, relPath string) error
+	DeleteQuarantinedPDF(ctx context.Context, relPath string) error
+	ListPDFs(ctx context.Context) ([]StoredObject, error)
+	QuarantinePDF(ctx context.Context, relPath string) error
+}
+
+// Mailer sends emails. An error means the message was not accepted for delivery.
+type Mailer interface {
+	Send(ctx context.Context, mail *Mail) error
+}
+
+// MalwareScanner scans uploaded files before they are stored.
+// An error means the file could not be scanned; a detection is reported in the result.
+type MalwareScanner interface {
+	Scan(ctx context.Context, r io.Reader) (*ScanResult, error)
 }
@@ -0,0 +1,8 @@
+package domain
+
+// Mail is an outgoing plain-text email.
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
@@ -7,18 +7,28 @@ import (
 
 // User represents a user entity in the system.
 type User struct {
-	ID        uint       `gorm:"primaryKey" json:"id"`
-	Name      string     `gorm:"size:100;not null" json:"name"`
-	LastName  string     `gorm:"size:100;not null" json:"last_name"`
-	Email     string     `gorm:"size:150;not null;uniqueIndex" json:"email"`
-	Phone     string     `gorm:"size:20" json:"phone"`
-	Password  Password   `gorm:"size:255" json:"-"`
-	Role      string     `gorm:"size:30;not null" json:"role"`
-	GoogleID  string     `gorm:"size:150" json:"google_id,omitempty"`
-	Active    *bool      `gorm:"default:true" json:"active"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	DeletedAt *time.Time `gorm:"index" json:"deleted_at,omitempty"`
+	ID       uint     `gorm:"primaryKey" json:"id"`
+	Name     string   `gorm:"size:100;not null" json:"name"`
+	LastName string   `gorm:"size:100;not null" json:"last_name"`
+	Email    string   `gorm:"size:150;not null;uniqueIndex" json:"email"`
+	Phone    string   `gorm:"size:20" json:"phone"`
+	Password Password `gorm:"size:255" json:"-"`
+	Role     string   `gorm:"size:30;not null" json:"role"`
+	GoogleID string   `gorm:"size:150" json:"google_id,omitempty"`
+	Active   *bool    `gorm:"default:true" json:"active"`
+	// EmailVerifiedAt is set when the user proves they own Email; changing the email clears it.
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	DeletedAt       *time.Time `gorm:"index" json:"deleted_at,omitempty"`
+}
+
+// ProfileUpdate holds the profile fields users change about themselves. A nil field is
+// left unchanged; an empty one clears the value.
+type ProfileUpdate struct {
+	Name     *string
+	LastName *string
+	Phone    *string
 }
 
 // Income represents an income record in the system.
@@ -33,6 +43,10 @@ type Income struct {
 	CreatedAt   time.Time  `json:"created_at"`
 	UpdatedAt   time.Time  `json:"updated_at"`
 	DeletedAt   *time.Time `gorm:"index" json:"deleted_at,omitempty"`
+
+	// ReceiptPending marks an income created before its receipt arrived.
+	ReceiptPending    bool       `gorm:"not null;default:false" json:"receipt_pending"`
+	ReceiptRemindedAt *time.Time `json:"receipt_reminded_at,omitempty"`
 }
 
 // Expense represents an expense record in the system.
@@ -47,18 +61,115 @@ type Expense struct {
 	CreatedAt   time.Time   `json:"created_at"`
 	UpdatedAt   time.Time   `json:"updated_at"`
 	DeletedAt   *time.Time  `gorm:"index" json:"deleted_at,omitempty"`
+
+	// ReceiptPending marks an expense created before its receipt arrived.
+	ReceiptPending    bool       `gorm:"not null;default:false" json:"receipt_pending"`
+	ReceiptRemindedAt *time.Time `json:"receipt_reminded_at,omitempty"`
 }
 
 // Receipt represents a receipt associated with an income.
 type Receipt struct {
-	ID         uint      `gorm:"primaryKey" json:"id"`
-	IncomeID   *uint     `gorm:"index"`
-	ExpenseID  *uint     `gorm:"index"`
-	FileName   string    `gorm:"size:255;not null" json:"file_name"`
-	RelPath    string    `gorm:"size:255;not null" json:"relPath_url"`
-	MimeType   string    `gorm:"size:50;not null" json:"mime_type"`
-	UploadedBy uint      `gorm:"not null" json:"uploaded_by"`
-	Checksum   string    `gorm:"size:255" json:"checksum,omitempty"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	IncomeID    *uint      `gorm:"index"`
+	ExpenseID   *uint      `gorm:"index"`
+	FileName    string     `gorm:"size:255;not null" json:"file_name"`
+	RelPath     string     `gorm:"size:255;not null" json:"relPath_url"`
+	MimeType    string     `gorm:"size:50;not null" json:"mime_type"`
+	UploadedBy  uint       `gorm:"not null" json:"uploaded_by"`
+	Checksum    string     `gorm:"size:255" json:"checksum,omitempty"`
+	KeyID       string     `gorm:"size:64" json:"key_id,omitempty"`
+	ScanStatus  ScanStatus `gorm:"size:20" json:"scan_status"`
+	ScanResult  string     `gorm:"size:255" json:"scan_result,omitempty"`
+	ScannedAt   *time.Time `json:"scanned_at,omitempty"`
+	Quarantined bool       `gorm:"not null;default:false" json:"quarantined"`
+	PageCount   int        `json:"page_count"`
+	Sanitized   bool       `gorm:"not null;default:false" json:"sanitized"`
+	Content     string     `gorm:"type:text;not null;default:''" json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// StoredFile describes a file persisted by a FileStorage.
+type StoredFile struct {
+	FileName string
+	RelPath  string
+	Checksum string
+	Size     int64
+	KeyID    string // master key used to encrypt the file, empty when stored in plain form
+
+	ScanStatus  ScanStatus
+	ScanResult  string
+	ScannedAt   *time.Time
+	Quarantined bool
+
+	PageCount int
+	Sanitized bool   // active content or attachments were stripped before saving
+	Text      string // text layer of the PDF, used for full-text search
+}
+
+// StoredObject is a file found while listing a FileStorage.
+type StoredObject struct {
+	RelPath string
+	Size    int64
+	ModTime time.Time
+}
+
+// Upload is a resumable (tus) upload staged before it is attached to an income or expense.
+// Once all bytes arrive the file is saved through the FileStorage and its data kept here
+// until a transaction claims it or the upload expires.
+type Upload struct {
+	ID          string     `gorm:"primaryKey;size:64" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	FileName    string     `gorm:"size:255" json:"file_name"`
+	Length      int64      `gorm:"column:upload_length;not null" json:"length"`
+	Offset      int64      `gorm:"column:upload_offset;not null;default:0" json:"offset"`
+	ExpiresAt   time.Time  `gorm:"not null;index" json:"expires_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+
+	StoredName  string     `gorm:"size:255" json:"-"`
+	RelPath     string     `gorm:"size:255" json:"-"`
+	Checksum    string     `gorm:"size:255" json:"-"`
+	KeyID       string     `gorm:"size:64" json:"-"`
+	ScanStatus  ScanStatus `gorm:"size:20" json:"-"`
+	ScanResult  string     `gorm:"size:255" json:"-"`
+	ScannedAt   *time.Time `json:"-"`
+	Quarantined bool       `gorm:"not null;default:false" json:"-"`
+	PageCount   int        `gorm:"not null;default:0" json:"-"`
+	Sanitized   bool       `gorm:"not null;default:false" json:"-"`
+	Content     string     `gorm:"type:text;not null;default:''" json:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Stored returns the saved file of a completed upload.
+func (u *Upload) Stored() *StoredFile {
+	return &StoredFile{
+		FileName:    u.StoredName,
+		RelPath:     u.RelPath,
+		Checksum:    u.Checksum,
+		KeyID:       u.KeyID,
+		ScanStatus:  u.ScanStatus,
+		ScanResult:  u.ScanResult,
+		ScannedAt:   u.ScannedAt,
+		Quarantined: u.Quarantined,
+		PageCount:   u.PageCount,
+		Sanitized:   u.Sanitized,
+		Text:        u.Content,
+	}
+}
+
+// SetStored records the file saved for a completed upload.
+func (u *Upload) SetStored(stored *StoredFile) {
+	u.StoredName = stored.FileName
+	u.RelPath = stored.RelPath
+	u.Checksum = stored.Checksum
+	u.KeyID = stored.KeyID
+	u.ScanStatus = stored.ScanStatus
+	u.ScanResult = stored.ScanResult
+	u.ScannedAt = stored.ScannedAt
+	u.Quarantined = stored.Quarantined
+	u.PageCount = stored.PageCount
+	u.Sanitized = stored.Sanitized
+	u.Content = stored.Text
 }
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// PendingReceipt is an income or expense still waiting for its receipt.
+type PendingReceipt struct {
+	Kind        string     `json:"kind"` // "income" or "expense"
+	ID          uint       `json:"id"`
+	Date        time.Time  `json:"date"`
+	Type        string     `json:"type"`
+	Amount      float64    `json:"amount"`
+	Description string     `json:"description"`
+	CreatedBy   uint       `json:"created_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	RemindedAt  *time.Time `json:"reminded_at,omitempty"`
+}
+
+// Reminder is a notice for a user, such as a receipt that is still missing.
+type Reminder struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	UserID        uint       `gorm:"not null;index" json:"user_id"`
+	Kind          string     `gorm:"size:20;not null" json:"kind"`
+	TransactionID uint       `gorm:"not null" json:"transaction_id"`
+	Message       string     `gorm:"size:255;not null" json:"message"`
+	ReadAt        *time.Time `json:"read_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
@@ -19,3 +19,24 @@ func IsValidRole(role string) bool {
 	_, ok := AllowedRoles[role]
 	return ok
 }
+
+// CanViewTransaction reports whether user may see an income/expense created by createdBy.
+// Admins and accountants see every transaction; everyone else only their own.
+func CanViewTransaction(user *User, createdBy uint) bool {
+	if user == nil {
+		return false
+	}
+	return CanViewAllTransactions(user) || user.ID == createdBy
+}
+
+// CanViewAllTransactions reports whether user may see transactions created by anyone.
+func CanViewAllTransactions(user *User) bool {
+	if user == nil {
+		return false
+	}
+	switch user.Role {
+	case RoleSuperAdmin, RoleAdmin, RoleAccountant:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,17 @@
+package domain
+
+// ScanStatus is the verdict of the malware scanner for an uploaded file.
+type ScanStatus string
+
+const (
+	ScanStatusClean    ScanStatus = "clean"
+	ScanStatusInfected ScanStatus = "infected"
+	ScanStatusError    ScanStatus = "error"   // the scanner could not analyse the file
+	ScanStatusSkipped  ScanStatus = "skipped" // no scanner configured
+)
+
+// ScanResult is what a MalwareScanner returns for a file.
+type ScanResult struct {
+	Status    ScanStatus
+	Signature string // name of the detected threat, if any
+}
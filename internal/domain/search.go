@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// SearchQuery is a full-text search over incomes, expenses and the text of their receipts.
+// Zero values mean "no filter".
+type SearchQuery struct {
+	Text      string
+	Kind      string // "income", "expense" or empty for both
+	CreatedBy uint
+	Limit     int
+	Offset    int
+}
+
+// SearchResult is a transaction matching a SearchQuery. Results come best match first.
+// Snippet holds the matching fragment of the receipt text, if the match was there.
+type SearchResult struct {
+	Kind        string    `json:"kind"`
+	ID          uint      `json:"id"`
+	Date        time.Time `json:"date"`
+	Type        string    `json:"type"`
+	Amount      float64   `json:"amount"`
+	Description string    `json:"description"`
+	CreatedBy   uint      `json:"created_by"`
+	ReceiptID   *uint     `json:"receipt_id,omitempty"`
+	FileName    string    `json:"file_name,omitempty"`
+	Rank        float64   `json:"rank"`
+	Snippet     string    `json:"snippet,omitempty"`
+}
@@ -0,0 +1,49 @@
+package domain
+
+import "time"
+
+// Security event types.
+const (
+	SecurityEventLoginFailed     = "login_failed"
+	SecurityEventLoginThrottled  = "login_throttled"
+	SecurityEventAccountLocked   = "account_locked"
+	SecurityEventIPLocked        = "ip_locked"
+	SecurityEventAccountUnlocked = "account_unlocked"
+	SecurityEventIPUnlocked      = "ip_unlocked"
+	SecurityEventInactiveLogin   = "inactive_login"
+	SecurityEventPasswordChanged = "password_changed"
+	// SecurityEventRequestThrottled is a request other than a login (e.g. a password reset
+	// email) dropped for exceeding its rate limit; Detail is the action.
+	SecurityEventRequestThrottled = "request_throttled"
+)
+
+// LoginThrottle counts the recent failed logins of a key, either an account
+// ("account:<email>") or a client IP ("ip:<addr>"). Failures go back to zero after a
+// quiet window; LockedUntil is set when the key reaches the lockout threshold.
+type LoginThrottle struct {
+	Key           string    `gorm:"primaryKey;size:200"`
+	Failures      int       `gorm:"not null;default:0"`
+	LastFailureAt time.Time `gorm:"not null"`
+	LockedUntil   *time.Time
+}
+
+// SecurityEvent is an entry of the security log: failed logins, throttled attempts,
+// lockouts and unlocks. UserID is nil when the email does not match an account.
+type SecurityEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Type      string    `gorm:"size:30;not null" json:"type"`
+	UserID    *uint     `json:"user_id,omitempty"`
+	Email     string    `gorm:"size:150" json:"email,omitempty"`
+	IP        string    `gorm:"size:45" json:"ip,omitempty"`
+	Detail    string    `gorm:"size:255" json:"detail,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SecurityEventFilter narrows the security log, newest first. Zero values mean "no filter".
+type SecurityEventFilter struct {
+	UserID uint
+	Type   string
+	IP     string
+	Limit  int
+	Offset int
+}
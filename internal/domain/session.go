@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// ClientInfo is where a login or a token refresh comes from.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// Session is a signed-in device: one per refresh-token family. Access tokens carry the
+// session id (claim "sid"), so revoking the session rejects them before they expire.
+// ExpiresAt follows the expiry of the latest refresh token of the family.
+type Session struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	FamilyID   string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Device     string     `gorm:"size:100" json:"device"`
+	IP         string     `gorm:"size:45" json:"ip"`
+	UserAgent  string     `gorm:"size:255" json:"user_agent"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `gorm:"not null" json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active reports whether the session is neither revoked nor expired at now.
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
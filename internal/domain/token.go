@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// RefreshToken is an issued refresh token. Only the SHA-256 of the token is stored.
+// Every refresh rotates the token: the old one is marked used and a new one is issued
+// in the same family. Presenting a used token again means it leaked, so the whole
+// family is revoked.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"not null;index"`
+	FamilyID  string     `gorm:"size:64;not null;index"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time // set when rotated
+	RevokedAt *time.Time // set on logout or reuse
+	CreatedAt time.Time
+}
+
+// OAuthState is a login started with an external OpenID Connect provider.
+// ID is the SHA-256 of the state sent to the provider; Nonce and Verifier (PKCE)
+// are checked when the provider redirects back.
+type OAuthState struct {
+	ID        string    `gorm:"primaryKey;size:64"`
+	Provider  string    `gorm:"size:50;not null"`
+	Nonce     string    `gorm:"size:64;not null"`
+	Verifier  string    `gorm:"size:128;not null"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+}
+
+// Purposes of a UserToken.
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+)
+
+// UserToken is a single-use, expiring token emailed to a user to reset their password
+// or verify their email. Only its SHA-256 is stored. Email is the address it was sent
+// to, so a verification token stops working if the user changes their email.
+type UserToken struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"not null;index"`
+	Purpose   string     `gorm:"size:30;not null"`
+	TokenHash string     `gorm:"size:64;not null;uniqueIndex"`
+	Email     string     `gorm:"size:150;not null"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time // set when consumed
+	CreatedAt time.Time
+}
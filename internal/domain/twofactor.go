@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// TwoFactor is a user's TOTP enrolment. It stays pending until the first code is
+// confirmed. LastStep is the last TOTP time step accepted, so a code works only once.
+type TwoFactor struct {
+	UserID      uint   `gorm:"primaryKey"`
+	Secret      string `gorm:"size:64;not null"`
+	ConfirmedAt *time.Time
+	LastStep    int64 `gorm:"not null;default:0"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Enabled reports whether the enrolment was confirmed.
+func (t *TwoFactor) Enabled() bool {
+	return t != nil && t.ConfirmedAt != nil
+}
+
+// RecoveryCode is a single-use code to sign in without the authenticator app.
+// Only the SHA-256 of the code is stored.
+type RecoveryCode struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    uint   `gorm:"not null;index"`
+	CodeHash  string `gorm:"size:64;not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// TwoFactorPolicy says whether users with Role must use two-factor authentication.
+type TwoFactorPolicy struct {
+	Role      string    `gorm:"primaryKey;size:30" json:"role"`
+	Required  bool      `gorm:"not null;default:false" json:"required"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
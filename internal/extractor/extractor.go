@@ -0,0 +1,40 @@
+// Package extractor implementa extractores que sugieren los datos de una transacción
+// (monto, fecha, identificación fiscal, proveedor) a partir del PDF del comprobante.
+package extractor
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/SaidMg10/gestor-one/internal/config"
+	"github.com/SaidMg10/gestor-one/internal/domain"
+)
+
+const (
+	DriverNone      = "none"
+	DriverHeuristic = "heuristic"
+)
+
+// New construye el extractor indicado en la configuración. Por defecto se usan heurísticas.
+func New(cfg config.ExtractorConfig) (domain.ReceiptExtractor, error) {
+	switch cfg.Driver {
+	case "", DriverHeuristic:
+		return NewHeuristicExtractor(), nil
+	case DriverNone:
+		return NewNoopExtractor(), nil
+	default:
+		return nil, fmt.Errorf("unknown extractor driver %q", cfg.Driver)
+	}
+}
+
+// NoopExtractor no sugiere nada.
+type NoopExtractor struct{}
+
+func NewNoopExtractor() domain.ReceiptExtractor {
+	return &NoopExtractor{}
+}
+
+func (n *NoopExtractor) Extract(ctx context.Context, r io.ReaderAt, size int64) (*domain.ReceiptAnalysis, error) {
+	return &domain.ReceiptAnalysis{Extractor: DriverNone}, nil
+}
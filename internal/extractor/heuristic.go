@@ -0,0 +1,571 @@
+package extractor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/pdfdoc"
+	"golang.org/x/text/unicode/norm"
+)
+
+// maxAnalyzedText limita cuánto texto del PDF se analiza; los datos buscados
+// están en la primera página y el resto solo agrega ruido.
+const maxAnalyzedText = 32 << 10
+
+// HeuristicExtractor lee la capa de texto del PDF y busca los datos con expresiones
+// regulares y palabras clave de facturas y tickets en español e inglés.
+// No hace OCR: un PDF escaneado sin texto devuelve un análisis vacío.
+type HeuristicExtractor struct {
+	now func() time.Time
+}
+
+func NewHeuristicExtractor() domain.ReceiptExtractor {
+	return &HeuristicExtractor{now: time.Now}
+}
+
+func (h *HeuristicExtractor) Extract(ctx context.Context, r io.ReaderAt, size int64) (*domain.ReceiptAnalysis, error) {
+	text, err := pdfdoc.ExtractText(r, size, maxAnalyzedText)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidPDF, err)
+	}
+	return analyzeText(text, h.now()), nil
+}
+
+// analyzeText aplica las heurísticas sobre el texto, una línea por renglón del PDF.
+func analyzeText(text string, now time.Time) *domain.ReceiptAnalysis {
+	analysis := &domain.ReceiptAnalysis{Extractor: DriverHeuristic}
+
+	var lines []line
+	for _, raw := range strings.Split(text, "\n") {
+		raw = strings.TrimSpace(raw)
+		if raw != "" {
+			lines = append(lines, line{raw: raw, folded: fold(raw)})
+		}
+	}
+	if len(lines) == 0 {
+		return analysis
+	}
+	analysis.HasText = true
+
+	english := isEnglish(lines)
+	analysis.Amount = findAmount(lines)
+	analysis.Date = findDate(lines, english, now)
+	taxID, taxLine := findTaxID(lines)
+	analysis.TaxID = taxID
+	analysis.Vendor = findVendor(lines, taxLine)
+	return analysis
+}
+
+// line es un renglón del texto original y su versión en minúsculas y sin acentos.
+type line struct {
+	raw    string
+	folded string
+}
+
+// fold pasa a minúsculas y quita los acentos para comparar palabras clave.
+func fold(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(s)) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var (
+	englishWords = regexp.MustCompile(`\b(invoice|receipt|amount|due|bill to|date|subtotal|tax|thank you|qty)\b`)
+	spanishWords = regexp.MustCompile(`\b(factura|recibo|importe|fecha|iva|cliente|gracias|cantidad|pagar|base imponible)\b`)
+)
+
+// isEnglish decide el idioma para interpretar fechas ambiguas como 03/04/2024.
+func isEnglish(lines []line) bool {
+	en, es := 0, 0
+	for _, l := range lines {
+		en += len(englishWords.FindAllString(l.folded, -1))
+		es += len(spanishWords.FindAllString(l.folded, -1))
+	}
+	return en > es
+}
+
+// ---------------------------------------------------------------------------
+// Monto
+
+var (
+	// Importes con separador de miles y decimales en cualquiera de los dos formatos:
+	// 1.234,56 / 1,234.56 / 1234,56 / 45.30
+	amountPattern = regexp.MustCompile(`\d{1,3}(?:[.,]\d{3})+(?:[.,]\d{1,2})?|\d+(?:[.,]\d{1,2})?`)
+
+	strongTotal = regexp.MustCompile(`\b(total a pagar|importe total|total factura|total ticket|total del documento|grand total|total due|amount due|balance due|total amount|total)\b`)
+	weakTotal   = regexp.MustCompile(`\b(importe|monto|amount|a pagar|pay)\b`)
+	notTotal    = regexp.MustCompile(`\b(sub-?total|total (iva|impuestos?|tax|sin iva|neto|items?|unidades|articulos|lineas|pages?)|base imponible|descuento|discount|cambio|change|entregado|efectivo)\b`)
+
+	currencyCodes = regexp.MustCompile(`\b(EUR|USD|MXN|ARS|COP|CLP|PEN|GBP|UYU)\b`)
+)
+
+func findAmount(lines []line) *domain.AmountSuggestion {
+	var best *domain.AmountSuggestion
+	consider := func(value float64, confidence float64, l line) {
+		if value <= 0 {
+			return
+		}
+		if best == nil || confidence > best.Confidence ||
+			(confidence == best.Confidence && value > best.Value) {
+			best = &domain.AmountSuggestion{Value: value, Currency: currencyOf(l.raw), Confidence: round2(confidence)}
+		}
+	}
+
+	largest, largestLine := 0.0, line{}
+	for i, l := range lines {
+		// Subtotales, impuestos, efectivo entregado y cambio nunca son el total
+		if notTotal.MatchString(l.folded) {
+			continue
+		}
+		amounts := amountsIn(l.raw)
+		for _, a := range amounts {
+			if a > largest {
+				largest, largestLine = a, l
+			}
+		}
+
+		confidence := 0.0
+		switch {
+		case strongTotal.MatchString(l.folded):
+			// "Total" a secas aparece también en columnas; "total a pagar" no deja dudas
+			confidence = 0.9
+			if strongTotal.FindString(l.folded) == "total" {
+				confidence = 0.8
+			}
+		case weakTotal.MatchString(l.folded):
+			confidence = 0.55
+		default:
+			continue
+		}
+
+		// El total suele estar a la derecha de la etiqueta o en el renglón siguiente
+		if len(amounts) == 0 && i+1 < len(lines) {
+			amounts = amountsIn(lines[i+1].raw)
+			confidence -= 0.1
+		}
+		if len(amounts) > 0 {
+			consider(amounts[len(amounts)-1], confidence, l)
+		}
+	}
+
+	if best == nil && largest > 0 {
+		// Sin etiqueta: el importe más grande suele ser el total
+		best = &domain.AmountSuggestion{Value: largest, Currency: currencyOf(largestLine.raw), Confidence: 0.3}
+	}
+	if best != nil && best.Currency == "" {
+		for _, l := range lines {
+			if c := currencyOf(l.raw); c != "" {
+				best.Currency = c
+				break
+			}
+		}
+	}
+	return best
+}
+
+// amountsIn devuelve los importes de s, ignorando porcentajes, fechas y números sin decimales
+// que parecen códigos (teléfonos, números de factura).
+func amountsIn(s string) []float64 {
+	var amounts []float64
+	for _, loc := range amountPattern.FindAllStringIndex(s, -1) {
+		start, end := loc[0], loc[1]
+		if end < len(s) && (s[end] == '%' || s[end] == '/' || s[end] == '-' || isDigitOrLetter(s[end])) {
+			continue
+		}
+		if start > 0 && (s[start-1] == '/' || s[start-1] == '-' || isDigitOrLetter(s[start-1])) {
+			continue
+		}
+		match := s[start:end]
+		if !strings.ContainsAny(match, ".,") {
+			continue
+		}
+		if value, ok := parseAmount(match); ok {
+			amounts = append(amounts, value)
+		}
+	}
+	return amounts
+}
+
+func isDigitOrLetter(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// parseAmount interpreta el último separador como decimal si le siguen 1 o 2 dígitos,
+// y como separador de miles si le siguen 3.
+func parseAmount(s string) (float64, bool) {
+	last := strings.LastIndexAny(s, ".,")
+	if last >= 0 && len(s)-last-1 == 3 {
+		s = strings.NewReplacer(".", "", ",", "").Replace(s)
+	} else if last >= 0 {
+		integer := strings.NewReplacer(".", "", ",", "").Replace(s[:last])
+		s = integer + "." + s[last+1:]
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || value > 1e10 {
+		return 0, false
+	}
+	return math.Round(value*100) / 100, true
+}
+
+func currencyOf(s string) string {
+	switch {
+	case strings.Contains(s, "€"):
+		return "EUR"
+	case strings.Contains(s, "£"):
+		return "GBP"
+	case strings.Contains(s, "US$"):
+		return "USD"
+	}
+	if m := currencyCodes.FindString(strings.ToUpper(s)); m != "" {
+		return m
+	}
+	return ""
+}
+
+// ---------------------------------------------------------------------------
+// Fecha
+
+var (
+	numericDate = regexp.MustCompile(`\b(\d{1,2})[/.-](\d{1,2})[/.-](\d{4}|\d{2})\b`)
+	isoDate     = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	spanishDate = regexp.MustCompile(`\b(\d{1,2})\s+de\s+([a-z]+)\s+(?:de|del)?\s*(\d{4})\b`)
+	englishDate = regexp.MustCompile(`\b([a-z]{3,9})\.?\s+(\d{1,2})(?:st|nd|rd|th)?,?\s+(\d{4})\b`)
+	dayMonth    = regexp.MustCompile(`\b(\d{1,2})[\s-]+([a-z]{3,9})\.?[\s-]+(\d{4})\b`)
+
+	dateLabel = regexp.MustCompile(`\b(fecha|date|emision|emitida|expedicion|issued|dated)\b`)
+	dueLabel  = regexp.MustCompile(`\b(vencimiento|vence|due|entrega|delivery|periodo|period)\b`)
+)
+
+var months = map[string]time.Month{
+	"enero": 1, "ene": 1, "january": 1, "jan": 1,
+	"febrero": 2, "feb": 2, "february": 2,
+	"marzo": 3, "mar": 3, "march": 3,
+	"abril": 4, "abr": 4, "april": 4, "apr": 4,
+	"mayo": 5, "may": 5,
+	"junio": 6, "jun": 6, "june": 6,
+	"julio": 7, "jul": 7, "july": 7,
+	"agosto": 8, "ago": 8, "august": 8, "aug": 8,
+	"septiembre": 9, "setiembre": 9, "sep": 9, "sept": 9, "set": 9, "september": 9,
+	"octubre": 10, "oct": 10, "october": 10,
+	"noviembre": 11, "nov": 11, "november": 11,
+	"diciembre": 12, "dic": 12, "december": 12, "dec": 12,
+}
+
+func findDate(lines []line, english bool, now time.Time) *domain.DateSuggestion {
+	var best *domain.DateSuggestion
+	for _, l := range lines {
+		for _, found := range datesIn(l.folded, english, now) {
+			confidence := 0.45
+			switch {
+			case dueLabel.MatchString(l.folded):
+				confidence = 0.3
+			case dateLabel.MatchString(l.folded):
+				confidence = 0.9
+			}
+			if found.ambiguous {
+				confidence -= 0.2
+			}
+			// A igual confianza gana la primera: la fecha de emisión suele estar arriba
+			if best == nil || confidence > best.Confidence {
+				best = &domain.DateSuggestion{Value: found.date, Confidence: round2(confidence)}
+			}
+		}
+	}
+	return best
+}
+
+type foundDate struct {
+	date      time.Time
+	ambiguous bool // día y mes intercambiables (03/04/2024)
+}
+
+func datesIn(s string, english bool, now time.Time) []foundDate {
+	var dates []foundDate
+	add := func(year, month, day int, ambiguous bool) {
+		if year < 100 {
+			year += 2000
+		}
+		if month < 1 || month > 12 {
+			return
+		}
+		d := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+		// time.Date normaliza el 31/02 a marzo: lo descartamos
+		if d.Day() != day || d.Year() < 2000 || d.After(now.AddDate(1, 0, 0)) {
+			return
+		}
+		dates = append(dates, foundDate{date: d, ambiguous: ambiguous})
+	}
+
+	for _, m := range isoDate.FindAllStringSubmatch(s, -1) {
+		add(atoi(m[1]), atoi(m[2]), atoi(m[3]), false)
+	}
+	for _, m := range numericDate.FindAllStringSubmatch(s, -1) {
+		a, b, year := atoi(m[1]), atoi(m[2]), atoi(m[3])
+		switch {
+		case a > 12:
+			add(year, b, a, false)
+		case b > 12:
+			add(year, a, b, false)
+		case a == b:
+			add(year, a, b, false)
+		case english:
+			add(year, a, b, true)
+		default:
+			add(year, b, a, true)
+		}
+	}
+	for _, m := range spanishDate.FindAllStringSubmatch(s, -1) {
+		if month, ok := months[m[2]]; ok {
+			add(atoi(m[3]), int(month), atoi(m[1]), false)
+		}
+	}
+	for _, m := range englishDate.FindAllStringSubmatch(s, -1) {
+		if month, ok := months[m[1]]; ok {
+			add(atoi(m[3]), int(month), atoi(m[2]), false)
+		}
+	}
+	for _, m := range dayMonth.FindAllStringSubmatch(s, -1) {
+		if month, ok := months[m[2]]; ok {
+			add(atoi(m[3]), int(month), atoi(m[1]), false)
+		}
+	}
+	return dates
+}
+
+// ---------------------------------------------------------------------------
+// Identificación fiscal
+
+var (
+	taxIDLabel = regexp.MustCompile(`\b(nif|cif|nie|dni|rfc|cuit|cuil|vat|tax id|tin|ein|n\.?i\.?f|c\.?i\.?f)\b`)
+
+	spanishCIF = regexp.MustCompile(`\b(?:ES)?([ABCDEFGHJNPQRSUVW])-?(\d{7})-?([0-9A-J])\b`)
+	spanishNIF = regexp.MustCompile(`\b(?:ES)?(\d{8})-?([A-Z])\b`)
+	spanishNIE = regexp.MustCompile(`\b(?:ES)?([XYZ])-?(\d{7})-?([A-Z])\b`)
+	mexicanRFC = regexp.MustCompile(`\b([A-ZÑ&]{3,4})-?(\d{6})-?([A-Z0-9]{3})\b`)
+	argCUIT    = regexp.MustCompile(`\b(\d{2})-?(\d{8})-?(\d)\b`)
+	usEIN      = regexp.MustCompile(`\b(\d{2}-\d{7})\b`)
+	euVAT      = regexp.MustCompile(`\b([A-Z]{2}[0-9A-Z]{8,12})\b`)
+)
+
+// taxIDCandidate es una identificación encontrada en un renglón.
+type taxIDCandidate struct {
+	value  string
+	scheme string
+	valid  bool // pasó el dígito de control
+	strict bool // el formato solo es confiable junto a una etiqueta
+}
+
+func findTaxID(lines []line) (*domain.TaxIDSuggestion, int) {
+	var best *domain.TaxIDSuggestion
+	bestLine := -1
+	for i, l := range lines {
+		labeled := taxIDLabel.MatchString(l.folded)
+		for _, c := range taxIDsIn(strings.ToUpper(l.raw)) {
+			var confidence float64
+			switch {
+			case c.valid && labeled:
+				confidence = 0.95
+			case c.valid:
+				confidence = 0.8
+			case labeled:
+				confidence = 0.7
+			case !c.strict:
+				confidence = 0.5
+			default:
+				continue
+			}
+			// A igual confianza gana la primera: el emisor suele estar antes que el cliente
+			if best == nil || confidence > best.Confidence {
+				best = &domain.TaxIDSuggestion{Value: c.value, Scheme: c.scheme, Confidence: confidence}
+				bestLine = i
+			}
+		}
+	}
+	return best, bestLine
+}
+
+func taxIDsIn(s string) []taxIDCandidate {
+	var found []taxIDCandidate
+	for _, m := range spanishNIE.FindAllStringSubmatch(s, -1) {
+		value := m[1] + m[2] + m[3]
+		found = append(found, taxIDCandidate{value: value, scheme: "NIE", valid: validNIE(value)})
+	}
+	for _, m := range spanishNIF.FindAllStringSubmatch(s, -1) {
+		value := m[1] + m[2]
+		found = append(found, taxIDCandidate{value: value, scheme: "NIF", valid: validNIF(value)})
+	}
+	for _, m := range spanishCIF.FindAllStringSubmatch(s, -1) {
+		value := m[1] + m[2] + m[3]
+		found = append(found, taxIDCandidate{value: value, scheme: "CIF", valid: validCIF(value)})
+	}
+	for _, m := range argCUIT.FindAllStringSubmatch(s, -1) {
+		value := m[1] + "-" + m[2] + "-" + m[3]
+		found = append(found, taxIDCandidate{value: value, scheme: "CUIT", valid: validCUIT(m[1] + m[2] + m[3])})
+	}
+	for _, m := range mexicanRFC.FindAllStringSubmatch(s, -1) {
+		found = append(found, taxIDCandidate{value: m[1] + m[2] + m[3], scheme: "RFC"})
+	}
+	for _, m := range usEIN.FindAllStringSubmatch(s, -1) {
+		found = append(found, taxIDCandidate{value: m[1], scheme: "EIN", strict: true})
+	}
+	if len(found) == 0 {
+		for _, m := range euVAT.FindAllStringSubmatch(s, -1) {
+			if strings.ContainsAny(m[1][2:], "0123456789") {
+				found = append(found, taxIDCandidate{value: m[1], scheme: "VAT", strict: true})
+			}
+		}
+	}
+	return found
+}
+
+const nifLetters = "TRWAGMYFPDXBNJZSQVHLCKE"
+
+func validNIF(v string) bool {
+	n, err := strconv.Atoi(v[:8])
+	return err == nil && nifLetters[n%23] == v[8]
+}
+
+func validNIE(v string) bool {
+	prefix := strings.IndexByte("XYZ", v[0])
+	return prefix >= 0 && validNIF(strconv.Itoa(prefix)+v[1:])
+}
+
+func validCIF(v string) bool {
+	sum := 0
+	for i, c := range v[1:8] {
+		d := int(c - '0')
+		if i%2 == 0 {
+			d *= 2
+			d = d/10 + d%10
+		}
+		sum += d
+	}
+	control := (10 - sum%10) % 10
+	last := v[8]
+	return last == byte('0'+control) || last == "JABCDEFGHI"[control]
+}
+
+func validCUIT(v string) bool {
+	weights := []int{5, 4, 3, 2, 7, 6, 5, 4, 3, 2}
+	sum := 0
+	for i, w := range weights {
+		sum += int(v[i]-'0') * w
+	}
+	check := 11 - sum%11
+	switch check {
+	case 11:
+		check = 0
+	case 10:
+		check = 9
+	}
+	return int(v[10]-'0') == check
+}
+
+// ---------------------------------------------------------------------------
+// Proveedor
+
+var (
+	companySuffix = regexp.MustCompile(`(?i)(\bS\.?\s?A\.?(\s?de\s?C\.?\s?V\.?)?|\bS\.?\s?L\.?(U\.?)?|\bS\.?\s?R\.?\s?L\.?|\bS\.?\s?A\.?\s?S\.?|\bS\.?\s?C\.?|\bInc\.?|\bLLC|\bL\.?L\.?C\.?|\bLtd\.?|\bLimited|\bGmbH|\bCorp\.?|\bCo\.)(\s|,|$)`)
+	vendorLabel   = regexp.MustCompile(`^(razon social|emisor|proveedor|vendedor|vendor|seller|supplier|from|company|empresa)\s*:\s*(.+)$`)
+	notVendor     = regexp.MustCompile(`\b(factura|invoice|ticket|recibo|receipt|fecha|date|nif|cif|rfc|cuit|vat|tel|telefono|phone|fax|email|e-mail|www|http|cliente|customer|bill to|ship to|pagina|page|total|iva|tax|direccion|address|calle|avenida|c/)\b`)
+)
+
+func findVendor(lines []line, taxLine int) *domain.TextSuggestion {
+	limit := min(len(lines), 15)
+
+	for _, l := range lines[:limit] {
+		if m := vendorLabel.FindStringSubmatch(l.folded); m != nil {
+			return &domain.TextSuggestion{Value: cleanVendor(afterColon(l.raw)), Confidence: 0.85}
+		}
+	}
+
+	for i, l := range lines[:limit] {
+		if !companySuffix.MatchString(l.raw) {
+			continue
+		}
+		name := cleanVendor(beforeTaxID(l.raw))
+		if name == "" {
+			continue
+		}
+		confidence := 0.75
+		if taxLine >= 0 && (i == taxLine || i+1 == taxLine || i-1 == taxLine) {
+			confidence = 0.85
+		}
+		return &domain.TextSuggestion{Value: name, Confidence: confidence}
+	}
+
+	// Sin razón social reconocible: el primer renglón con pinta de nombre
+	for _, l := range lines[:min(limit, 6)] {
+		if notVendor.MatchString(l.folded) || digitRatio(l.raw) > 0.3 {
+			continue
+		}
+		if name := cleanVendor(l.raw); len([]rune(name)) >= 3 && len([]rune(name)) <= 80 {
+			return &domain.TextSuggestion{Value: name, Confidence: 0.4}
+		}
+	}
+	return nil
+}
+
+func afterColon(s string) string {
+	if i := strings.Index(s, ":"); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// beforeTaxID corta el renglón donde empieza la identificación fiscal ("ACME S.L. - CIF B...").
+func beforeTaxID(s string) string {
+	if loc := taxIDLabel.FindStringIndex(fold(s)); loc != nil && loc[0] > 0 {
+		// fold no cambia la cantidad de bytes salvo en letras acentuadas; se corta por runas
+		runes := []rune(s)
+		cut := len([]rune(fold(s)[:loc[0]]))
+		if cut <= len(runes) {
+			return string(runes[:cut])
+		}
+	}
+	return s
+}
+
+func cleanVendor(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return strings.Trim(s, " -–|·,;:")
+}
+
+func digitRatio(s string) float64 {
+	digits, total := 0, 0
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		total++
+		if unicode.IsDigit(r) {
+			digits++
+		}
+	}
+	if total == 0 {
+		return 1
+	}
+	return float64(digits) / float64(total)
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -0,0 +1,183 @@
+package extractor
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"1.234,56", 1234.56, true},
+		{"1,234.56", 1234.56, true},
+		{"1234,56", 1234.56, true},
+		{"45.30", 45.3, true},
+		{"12,5", 12.5, true},
+		{"1.234", 1234, true},
+		{"1,234", 1234, true},
+		{"1.234.567,89", 1234567.89, true},
+		{"1,234,567.8", 1234567.8, true},
+		{"99999999999,00", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseAmount(tt.in)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("parseAmount(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestAmountsIn(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []float64
+	}{
+		{"Total a pagar 1.210,00 €", []float64{1210}},
+		{"Total due $1,210.00", []float64{1210}},
+		{"2 x 3,50 = 7,00", []float64{3.5, 7}},
+		{"IVA 21,00% 42,00", []float64{42}},
+		{"Fecha 03/04/2024", nil},
+		{"Factura 2024-015", nil},
+		{"Pedido 12345", nil},
+	}
+	for _, tt := range tests {
+		if got := amountsIn(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("amountsIn(%q) = %v; want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDatesIn(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		in      string
+		english bool
+		want    []foundDate
+	}{
+		// Día y mes intercambiables: decide el idioma del documento
+		{"03/04/2024", false, []foundDate{{date(2024, time.April, 3), true}}},
+		{"03/04/2024", true, []foundDate{{date(2024, time.March, 4), true}}},
+		// Un componente mayor que 12 solo puede ser el día
+		{"13/04/2024", true, []foundDate{{date(2024, time.April, 13), false}}},
+		{"04/13/2024", false, []foundDate{{date(2024, time.April, 13), false}}},
+		{"05.05.24", false, []foundDate{{date(2024, time.May, 5), false}}},
+		{"2024-05-06", true, []foundDate{{date(2024, time.May, 6), false}}},
+		{"5 de marzo de 2024", false, []foundDate{{date(2024, time.March, 5), false}}},
+		{"march 5th, 2024", true, []foundDate{{date(2024, time.March, 5), false}}},
+		{"05-mar-2024", true, []foundDate{{date(2024, time.March, 5), false}}},
+		// Fechas imposibles, anteriores a 2000 o demasiado en el futuro
+		{"31/02/2024", false, nil},
+		{"10/10/1999", false, nil},
+		{"01/01/2030", false, nil},
+	}
+	for _, tt := range tests {
+		if got := datesIn(tt.in, tt.english, testNow); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("datesIn(%q, english=%v) = %v; want %v", tt.in, tt.english, got, tt.want)
+		}
+	}
+}
+
+func TestTaxIDCheckDigits(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid func(string) bool
+		in    string
+		want  bool
+	}{
+		{"NIF", validNIF, "12345678Z", true},
+		{"NIF", validNIF, "12345678A", false},
+		{"NIE", validNIE, "X1234567L", true},
+		{"NIE", validNIE, "Y1234567X", true},
+		{"NIE", validNIE, "X1234567A", false},
+		{"CIF", validCIF, "B12345674", true},
+		{"CIF", validCIF, "P1234567D", true},
+		{"CIF", validCIF, "B12345675", false},
+		{"CUIT", validCUIT, "20123456786", true},
+		{"CUIT", validCUIT, "20123456780", false},
+	}
+	for _, tt := range tests {
+		if got := tt.valid(tt.in); got != tt.want {
+			t.Errorf("valid%s(%q) = %v; want %v", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestTaxIDsIn(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []taxIDCandidate
+	}{
+		{"CIF: B-12345674", []taxIDCandidate{{value: "B12345674", scheme: "CIF", valid: true}}},
+		{"NIF ES12345678Z", []taxIDCandidate{{value: "12345678Z", scheme: "NIF", valid: true}}},
+		{"CUIT 20-12345678-6", []taxIDCandidate{{value: "20-12345678-6", scheme: "CUIT", valid: true}}},
+		{"EIN 12-3456789", []taxIDCandidate{{value: "12-3456789", scheme: "EIN", strict: true}}},
+	}
+	for _, tt := range tests {
+		if got := taxIDsIn(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("taxIDsIn(%q) = %+v; want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzeText(t *testing.T) {
+	t.Run("factura en español", func(t *testing.T) {
+		a := analyzeText(`ACME Servicios S.L.
+CIF: B12345674
+Factura nº 2024-015
+Fecha: 03/04/2024
+Base imponible 1.000,00
+IVA 21% 210,00
+Total a pagar 1.210,00 €`, testNow)
+
+		if !a.HasText {
+			t.Fatal("HasText = false")
+		}
+		if a.Amount == nil || a.Amount.Value != 1210 || a.Amount.Currency != "EUR" || a.Amount.Confidence != 0.9 {
+			t.Errorf("Amount = %+v", a.Amount)
+		}
+		// 03/04 es ambiguo: se lee día/mes por estar en español y pierde confianza
+		if a.Date == nil || !a.Date.Value.Equal(time.Date(2024, time.April, 3, 0, 0, 0, 0, time.UTC)) || a.Date.Confidence != 0.7 {
+			t.Errorf("Date = %+v", a.Date)
+		}
+		if a.TaxID == nil || a.TaxID.Value != "B12345674" || a.TaxID.Scheme != "CIF" || a.TaxID.Confidence != 0.95 {
+			t.Errorf("TaxID = %+v", a.TaxID)
+		}
+		if a.Vendor == nil || a.Vendor.Value != "ACME Servicios S.L." || a.Vendor.Confidence != 0.85 {
+			t.Errorf("Vendor = %+v", a.Vendor)
+		}
+	})
+
+	t.Run("invoice in english", func(t *testing.T) {
+		a := analyzeText(`Globex Inc.
+Invoice #1001
+Date: 03/04/2024
+Subtotal 100.00
+Tax 8.00
+Total due 108.00 USD`, testNow)
+
+		if a.Amount == nil || a.Amount.Value != 108 || a.Amount.Currency != "USD" || a.Amount.Confidence != 0.9 {
+			t.Errorf("Amount = %+v", a.Amount)
+		}
+		if a.Date == nil || !a.Date.Value.Equal(time.Date(2024, time.March, 4, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("Date = %+v", a.Date)
+		}
+		if a.TaxID != nil {
+			t.Errorf("TaxID = %+v; want nil", a.TaxID)
+		}
+		if a.Vendor == nil || a.Vendor.Value != "Globex Inc." || a.Vendor.Confidence != 0.75 {
+			t.Errorf("Vendor = %+v", a.Vendor)
+		}
+	})
+
+	t.Run("sin texto", func(t *testing.T) {
+		if a := analyzeText(" \n\n ", testNow); a.HasText || a.Amount != nil {
+			t.Errorf("analyzeText(blank) = %+v", a)
+		}
+	})
+}
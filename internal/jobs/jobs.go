@@ -0,0 +1,33 @@
+// Package jobs ejecuta tareas periódicas en segundo plano dentro del servidor.
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Every corre fn cada interval hasta que ctx se cancele. No bloquea.
+// Los errores solo se loguean: la próxima ejecución vuelve a intentar.
+func Every(ctx context.Context, name string, interval time.Duration, fn func(ctx context.Context) error) {
+	if interval <= 0 {
+		log.Printf("job %s disabled (interval %s)", name, interval)
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := fn(ctx); err != nil {
+					log.Printf("job %s failed: %v", name, err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,56 @@
+// Package mailer implementa el envío de emails (recuperación de contraseña, verificación
+// de email...).
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/SaidMg10/gestor-one/internal/config"
+	"github.com/SaidMg10/gestor-one/internal/domain"
+)
+
+const (
+	DriverNone = "none"
+	DriverLog  = "log"
+	DriverSMTP = "smtp"
+)
+
+// New construye el mailer indicado en la configuración. Por defecto los emails solo se
+// escriben en el log.
+func New(cfg config.MailerConfig) (domain.Mailer, error) {
+	switch cfg.Driver {
+	case "", DriverLog:
+		return NewLogMailer(), nil
+	case DriverNone:
+		return NewNoopMailer(), nil
+	case DriverSMTP:
+		return NewSMTPMailer(cfg)
+	default:
+		return nil, fmt.Errorf("unknown mailer driver %q", cfg.Driver)
+	}
+}
+
+// NoopMailer descarta los emails.
+type NoopMailer struct{}
+
+func NewNoopMailer() domain.Mailer {
+	return &NoopMailer{}
+}
+
+func (n *NoopMailer) Send(ctx context.Context, mail *domain.Mail) error {
+	return nil
+}
+
+// LogMailer escribe los emails en el log, enlaces incluidos. Solo para desarrollo.
+type LogMailer struct{}
+
+func NewLogMailer() domain.Mailer {
+	return &LogMailer{}
+}
+
+func (l *LogMailer) Send(ctx context.Context, mail *domain.Mail) error {
+	log.Printf("mail to %s: %s\n%s", mail.To, mail.Subject, mail.Body)
+	return nil
+}
@@ -2,14 +2,20 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
+	"github.com/SaidMg10/gestor-one/internal/domain"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-const userKey = "user"
+const (
+	userKey    = "user"
+	apiKeyKey  = "api_key"
+	sessionKey = "session_id"
+)
 
 func (m *Middleware) AuthTokenMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -31,6 +37,11 @@ func (m *Middleware) AuthTokenMiddleware() gin.HandlerFunc {
 		}
 		// Obtenemos el token que se aloja en el indice [1]
 		tokenString := parts[1]
+		// Las API keys se reconocen por el prefijo y se validan aparte
+		if strings.HasPrefix(tokenString, domain.APIKeyPrefix) {
+			m.authenticateAPIKey(c, tokenString)
+			return
+		}
 		// Usamos la funcion Validate para validar el token
 		authToken, err := m.authenticator.ValidateToken(tokenString)
 		if err != nil {
@@ -45,6 +56,13 @@ func (m *Middleware) AuthTokenMiddleware() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		// Los access tokens no llevan typ: ni los refresh emitidos como JWT antes de la
+		// rotación ni el token intermedio del login con 2FA sirven como access token
+		if typ, _ := claims["typ"].(string); typ != "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token type"})
+			c.Abort()
+			return
+		}
 		// Con el token validado debemos obtener el userID del claim sub
 		userIDFloat, ok := claims["sub"].(float64)
 		if !ok {
@@ -53,6 +71,14 @@ func (m *Middleware) AuthTokenMiddleware() gin.HandlerFunc {
 			return
 		}
 		userID := uint(userIDFloat)
+		// Cada access token es de una sesión (claim sid); los emitidos antes de que
+		// existieran las sesiones no la traen y hay que renovarlos con el refresh token
+		sessionIDFloat, ok := claims["sid"].(float64)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid session in token"})
+			c.Abort()
+			return
+		}
 		// Con el userID, ahora consultamos el usuario en la base de datos
 		ctx := c.Request.Context()
 		user, err := m.userRepo.GetByID(ctx, userID)
@@ -61,10 +87,78 @@ func (m *Middleware) AuthTokenMiddleware() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		// Un usuario desactivado no puede seguir usando los tokens que ya tenía
+		if user.Active != nil && !*user.Active {
+			c.JSON(http.StatusForbidden, gin.H{"error": domain.ErrAccountDisabled.Error()})
+			c.Abort()
+			return
+		}
+		// La sesión revocada deja de servir aunque el token todavía no venció
+		session, err := m.authService.ActiveSession(ctx, uint(sessionIDFloat), userID, c.ClientIP())
+		if err != nil {
+			if errors.Is(err, domain.ErrInvalidToken) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "session has been revoked or expired"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			}
+			c.Abort()
+			return
+		}
 		// Guardamos el usuario en el contexto para que el handler
 		c.Set(userKey, user)
+		c.Set(sessionKey, session.ID)
 		// y otros middlewares puedan acceder a él.
 		// Llamar a c.Next() para que continúe la cadena de middlewares/handlers.
 		c.Next()
 	}
 }
+
+// authenticateAPIKey autentica con una API key. Solo sirve en las rutas de algún recurso
+// de domain.APIKeyScopes y si la key tiene el scope que pide el método.
+func (m *Middleware) authenticateAPIKey(c *gin.Context, secret string) {
+	key, user, err := m.apiKeys.Authenticate(c.Request.Context(), secret, c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid, expired or revoked api key"})
+		case errors.Is(err, domain.ErrAccountDisabled):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		c.Abort()
+		return
+	}
+
+	scope := apiKeyScope(c)
+	if !domain.IsValidAPIKeyScope(scope) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "api keys cannot be used on this endpoint"})
+		c.Abort()
+		return
+	}
+	if !key.HasScope(scope) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "api key is missing the " + scope + " scope"})
+		c.Abort()
+		return
+	}
+
+	c.Set(userKey, user)
+	c.Set(apiKeyKey, key)
+	c.Next()
+}
+
+// apiKeyScope es el scope que pide la ruta: "<recurso>:read" para GET y HEAD bajo
+// /api/v1/<recurso> y "<recurso>:write" para lo demás.
+func apiKeyScope(c *gin.Context) string {
+	path, ok := strings.CutPrefix(c.FullPath(), "/api/v1/")
+	if !ok {
+		return ""
+	}
+	resource, _, _ := strings.Cut(path, "/")
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead:
+		return resource + ":read"
+	default:
+		return resource + ":write"
+	}
+}
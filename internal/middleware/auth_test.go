@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestAPIKeyScope(t *testing.T) {
+	tests := []struct {
+		method string
+		route  string
+		path   string
+		want   string
+	}{
+		{http.MethodGet, "/api/v1/incomes", "/api/v1/incomes", "incomes:read"},
+		{http.MethodHead, "/api/v1/incomes/:id", "/api/v1/incomes/7", "incomes:read"},
+		{http.MethodGet, "/api/v1/expenses/:id/download", "/api/v1/expenses/7/download", "expenses:read"},
+		{http.MethodPost, "/api/v1/incomes", "/api/v1/incomes", "incomes:write"},
+		{http.MethodPatch, "/api/v1/expenses/:id", "/api/v1/expenses/7", "expenses:write"},
+		{http.MethodDelete, "/api/v1/uploads/:id", "/api/v1/uploads/abc", "uploads:write"},
+		{http.MethodPost, "/api/v1/receipts/analyze", "/api/v1/receipts/analyze", "receipts:write"},
+		{http.MethodGet, "/api/v1/exports/receipts.zip", "/api/v1/exports/receipts.zip", "exports:read"},
+		{http.MethodGet, "/api/v1/search", "/api/v1/search", "search:read"},
+		// Rutas fuera de los scopes: dan un scope que no existe
+		{http.MethodGet, "/api/v1/me", "/api/v1/me", "me:read"},
+		{http.MethodPost, "/api/v1/2fa/disable", "/api/v1/2fa/disable", "2fa:write"},
+		{http.MethodPost, "/api/v1/api-keys", "/api/v1/api-keys", "api-keys:write"},
+		{http.MethodGet, "/api/v1/admin/users/:id/sessions", "/api/v1/admin/users/1/sessions", "admin:read"},
+		{http.MethodGet, "/api/v1/files/receipts/:id", "/api/v1/files/receipts/7", "files:read"},
+		{http.MethodGet, "/health", "/health", ""},
+	}
+	for _, tt := range tests {
+		var got string
+		r := gin.New()
+		r.Handle(tt.method, tt.route, func(c *gin.Context) { got = apiKeyScope(c) })
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s %s: status %d, route not matched", tt.method, tt.path, w.Code)
+		}
+		if got != tt.want {
+			t.Errorf("%s %s: apiKeyScope = %q; want %q", tt.method, tt.route, got, tt.want)
+		}
+	}
+}
+
+// fakeAPIKeyRepo devuelve siempre la misma key, sin importar el secreto.
+type fakeAPIKeyRepo struct {
+	domain.APIKeyRepo
+	key *domain.APIKey
+}
+
+func (r *fakeAPIKeyRepo) GetByHash(ctx context.Context, hash string) (*domain.APIKey, error) {
+	return r.key, nil
+}
+
+func (r *fakeAPIKeyRepo) Touch(ctx context.Context, id uint, at time.Time, ip string) error {
+	return nil
+}
+
+type fakeUserRepo struct {
+	domain.UserRepo
+	user *domain.User
+}
+
+func (r *fakeUserRepo) GetByID(ctx context.Context, id uint) (*domain.User, error) {
+	return r.user, nil
+}
+
+func TestAPIKeyRouteRestrictions(t *testing.T) {
+	active := true
+	user := &domain.User{ID: 1, Role: domain.RoleAdmin, Active: &active}
+	key := &domain.APIKey{ID: 1, UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}
+	apiKeys := service.NewAPIKeyService(&fakeAPIKeyRepo{key: key}, &fakeUserRepo{user: user}, time.Hour, time.Hour)
+	m := NewMiddleware(nil, nil, nil, apiKeys)
+
+	r := gin.New()
+	v1 := r.Group("/api/v1", m.AuthTokenMiddleware())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	v1.GET("/incomes", ok)
+	v1.POST("/incomes", ok)
+	v1.GET("/expenses/:id", ok)
+	v1.GET("/me", ok)
+	v1.PATCH("/me", ok)
+	v1.POST("/me/password", ok)
+	v1.GET("/2fa", ok)
+	v1.POST("/2fa/disable", ok)
+	v1.GET("/api-keys", ok)
+	v1.POST("/api-keys", ok)
+	v1.GET("/sessions", ok)
+	v1.GET("/users", ok)
+	v1.GET("/admin/users/:id/sessions", ok)
+	v1.DELETE("/admin/sessions/:id", ok)
+	v1.POST("/auth/logout-all", ok)
+
+	type apiKeyCase struct {
+		name   string
+		scopes []string
+		method string
+		path   string
+		want   int
+	}
+	tests := []apiKeyCase{
+		{"lectura con scope", []string{"incomes:read"}, http.MethodGet, "/api/v1/incomes", http.StatusOK},
+		{"escritura con scope", []string{"incomes:write"}, http.MethodPost, "/api/v1/incomes", http.StatusOK},
+		{"escritura con scope de lectura", []string{"incomes:read"}, http.MethodPost, "/api/v1/incomes", http.StatusForbidden},
+		{"otro recurso", []string{"incomes:read"}, http.MethodGet, "/api/v1/expenses/1", http.StatusForbidden},
+	}
+	// Con todos los scopes, la key igual no entra a la cuenta, 2FA, keys, sesiones ni admin
+	for _, path := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/me"},
+		{http.MethodPatch, "/api/v1/me"},
+		{http.MethodPost, "/api/v1/me/password"},
+		{http.MethodGet, "/api/v1/2fa"},
+		{http.MethodPost, "/api/v1/2fa/disable"},
+		{http.MethodGet, "/api/v1/api-keys"},
+		{http.MethodPost, "/api/v1/api-keys"},
+		{http.MethodGet, "/api/v1/sessions"},
+		{http.MethodGet, "/api/v1/users"},
+		{http.MethodGet, "/api/v1/admin/users/1/sessions"},
+		{http.MethodDelete, "/api/v1/admin/sessions/1"},
+		{http.MethodPost, "/api/v1/auth/logout-all"},
+	} {
+		tests = append(tests, apiKeyCase{"fuera de los scopes", domain.APIKeyScopes, path.method, path.path, http.StatusForbidden})
+	}
+
+	for _, tt := range tests {
+		key.Scopes = tt.scopes
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Authorization", "Bearer "+domain.APIKeyPrefix+"secret")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: %s %s = %d; want %d (%s)", tt.name, tt.method, tt.path, w.Code, tt.want, w.Body.String())
+		}
+	}
+}
@@ -11,13 +11,15 @@ type Middleware struct {
 	authService   *service.AuthService
 	authenticator auth.Authenticator
 	userRepo      domain.UserRepo
+	apiKeys       *service.APIKeyService
 }
 
 // NewMiddleware inicializa el middleware con lo necesario
-func NewMiddleware(authService *service.AuthService, authenticator auth.Authenticator, userRepo domain.UserRepo) *Middleware {
+func NewMiddleware(authService *service.AuthService, authenticator auth.Authenticator, userRepo domain.UserRepo, apiKeys *service.APIKeyService) *Middleware {
 	return &Middleware{
 		authService:   authService,
 		authenticator: authenticator,
 		userRepo:      userRepo,
+		apiKeys:       apiKeys,
 	}
 }
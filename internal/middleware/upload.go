@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead es el margen para los demás campos del formulario y los boundaries.
+const multipartOverhead = 1 << 20
+
+// LimitUploadSize corta las peticiones cuyo cuerpo supera maxFileSize (más el margen del multipart).
+// Si el cliente declara un Content-Length mayor se responde 413 sin leer nada;
+// si no, http.MaxBytesReader hace fallar el parseo del formulario en cuanto se pasa del límite.
+func (m *Middleware) LimitUploadSize(maxFileSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if maxFileSize <= 0 {
+			c.Next()
+			return
+		}
+		maxBody := maxFileSize + multipartOverhead
+		if c.Request.ContentLength > maxBody {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request body too large"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBody)
+		c.Next()
+	}
+}
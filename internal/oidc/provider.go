@@ -0,0 +1,142 @@
+// Package oidc implementa lo necesario de OpenID Connect para iniciar sesión con un
+// proveedor externo (Google, Azure AD, Keycloak...): discovery, claves JWKS y validación
+// del ID token. El intercambio del código lo hace golang.org/x/oauth2.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// ErrInvalidIDToken indica un ID token mal firmado, vencido o emitido para otro cliente.
+var ErrInvalidIDToken = errors.New("invalid id token")
+
+// discoveryTTL es cada cuánto se vuelve a leer el documento de discovery.
+const discoveryTTL = 24 * time.Hour
+
+// Metadata es la parte del documento /.well-known/openid-configuration que usamos.
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserInfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Provider es un proveedor OIDC identificado por su issuer. El discovery se hace la primera
+// vez que se usa (y se renueva cada discoveryTTL), así un proveedor caído no impide
+// arrancar el servidor.
+type Provider struct {
+	issuer string
+	client *http.Client
+
+	mu        sync.Mutex
+	meta      *Metadata
+	fetchedAt time.Time
+	keys      *keySet
+}
+
+// NewProvider crea un proveedor para issuer. Con client nil se usa uno con timeout de 10s.
+func NewProvider(issuer string, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Provider{
+		issuer: strings.TrimSuffix(issuer, "/"),
+		client: client,
+	}
+}
+
+// Issuer devuelve el issuer configurado.
+func (p *Provider) Issuer() string {
+	return p.issuer
+}
+
+// Metadata devuelve el documento de discovery, pidiéndolo si hace falta.
+func (p *Provider) Metadata(ctx context.Context) (*Metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.meta != nil && time.Since(p.fetchedAt) < discoveryTTL {
+		return p.meta, nil
+	}
+
+	var meta Metadata
+	if err := getJSON(ctx, p.client, p.issuer+"/.well-known/openid-configuration", &meta); err != nil {
+		if p.meta != nil {
+			// Mejor seguir con el documento anterior que cortar los logins
+			return p.meta, nil
+		}
+		return nil, fmt.Errorf("oidc discovery for %s: %w", p.issuer, err)
+	}
+	// El issuer del documento tiene que ser exactamente el configurado (OIDC Discovery §4.3)
+	if strings.TrimSuffix(meta.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer %q does not match %q", meta.Issuer, p.issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc discovery for %s: incomplete metadata", p.issuer)
+	}
+
+	p.meta = &meta
+	p.fetchedAt = time.Now()
+	if p.keys == nil || p.keys.url != meta.JWKSURI {
+		p.keys = newKeySet(meta.JWKSURI, p.client)
+	}
+	return p.meta, nil
+}
+
+// Endpoint devuelve los endpoints para configurar un oauth2.Config.
+func (p *Provider) Endpoint(ctx context.Context) (oauth2.Endpoint, error) {
+	meta, err := p.Metadata(ctx)
+	if err != nil {
+		return oauth2.Endpoint{}, err
+	}
+	return oauth2.Endpoint{
+		AuthURL:  meta.AuthorizationEndpoint,
+		TokenURL: meta.TokenEndpoint,
+	}, nil
+}
+
+// HTTPClient es el cliente con el que se habla con el proveedor; sirve para pasárselo
+// a oauth2 en el contexto del Exchange.
+func (p *Provider) HTTPClient() *http.Client {
+	return p.client
+}
+
+func (p *Provider) keySet(ctx context.Context) (*keySet, error) {
+	if _, err := p.Metadata(ctx); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.keys, nil
+}
+
+// getJSON hace un GET y decodifica la respuesta, limitada a 1 MiB.
+func getJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: unexpected status %s", url, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
@@ -0,0 +1,84 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Algoritmos aceptados para el ID token; "none" y HMAC nunca.
+var signingAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// Claims son los datos del usuario que trae un ID token validado. Raw tiene todos los
+// claims para quien necesite mapear otros (grupos, roles...).
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	GivenName     string
+	FamilyName    string
+	Raw           map[string]any
+}
+
+// Verify valida un ID token emitido por el proveedor para clientID (RFC 7519 + OIDC Core §3.1.3.7):
+// firma con una clave del JWKS, iss, aud/azp, exp, iat y que el nonce sea el de la solicitud.
+func (p *Provider) Verify(ctx context.Context, rawIDToken, clientID, nonce string) (*Claims, error) {
+	keys, err := p.keySet(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.Parse(rawIDToken, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return keys.key(ctx, kid)
+	},
+		jwt.WithValidMethods(signingAlgorithms),
+		jwt.WithIssuer(p.issuer),
+		jwt.WithAudience(clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute), // relojes del proveedor y nuestro no van exactos
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	raw, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("%w: unexpected claims", ErrInvalidIDToken)
+	}
+
+	// Con varias audiencias el token tiene que estar autorizado para nosotros (azp)
+	if aud, _ := raw.GetAudience(); len(aud) > 1 {
+		if azp, _ := raw["azp"].(string); azp != clientID {
+			return nil, fmt.Errorf("%w: azp does not match client", ErrInvalidIDToken)
+		}
+	}
+
+	tokenNonce, _ := raw["nonce"].(string)
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(tokenNonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+
+	claims := &Claims{Raw: raw}
+	claims.Subject, _ = raw["sub"].(string)
+	claims.Email, _ = raw["email"].(string)
+	claims.Name, _ = raw["name"].(string)
+	claims.GivenName, _ = raw["given_name"].(string)
+	claims.FamilyName, _ = raw["family_name"].(string)
+	// Algunos proveedores mandan email_verified como string
+	switch v := raw["email_verified"].(type) {
+	case bool:
+		claims.EmailVerified = v
+	case string:
+		claims.EmailVerified = v == "true"
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidIDToken)
+	}
+	return claims, nil
+}
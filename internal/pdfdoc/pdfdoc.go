@@ -0,0 +1,192 @@
+// Package pdfdoc trabaja con documentos PDF en Go puro: páginas de texto generadas,
+// sellos sobre páginas existentes, unión de varios PDFs en uno y extracción de texto.
+package pdfdoc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+func init() {
+	// pdfcpu por defecto crea un directorio de configuración en el home del usuario
+	api.DisableConfigDir()
+}
+
+func newConfig() *model.Configuration {
+	conf := model.NewDefaultConfiguration()
+	conf.ValidationMode = model.ValidationRelaxed
+	return conf
+}
+
+// safely corre fn convirtiendo en error los pánicos de pdfcpu con archivos malformados.
+func safely(fn func() error) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("malformed PDF: %v", rec)
+		}
+	}()
+	return fn()
+}
+
+// PageCount devuelve la cantidad de páginas de un PDF.
+func PageCount(rs io.ReadSeeker) (n int, err error) {
+	err = safely(func() error {
+		n, err = api.PageCount(rs, newConfig())
+		return err
+	})
+	return n, err
+}
+
+// StampHeader escribe en w una copia de rs con text como encabezado centrado en cada página.
+func StampHeader(rs io.ReadSeeker, w io.Writer, text string) error {
+	wm, err := api.TextWatermark(text,
+		"fontname:Helvetica-Bold, points:9, position:tc, offset:0 -8, scalefactor:1 abs, rotation:0, fillcolor:#B00000, opacity:1",
+		true, false, types.POINTS)
+	if err != nil {
+		return err
+	}
+	return safely(func() error {
+		return api.AddWatermarks(rs, w, nil, wm, newConfig())
+	})
+}
+
+// Watermark escribe en w una copia de rs con text cruzado en diagonal sobre cada página.
+// Va por encima del contenido para que una imagen de página completa no lo tape.
+// text puede tener varias líneas separadas por "\n".
+func Watermark(rs io.ReadSeeker, w io.Writer, text string) error {
+	wm, err := api.TextWatermark(text,
+		"fontname:Helvetica-Bold, points:24, position:c, diagonal:1, scalefactor:0.8 rel, fillcolor:#808080, opacity:0.35",
+		true, false, types.POINTS)
+	if err != nil {
+		return err
+	}
+	return safely(func() error {
+		return api.AddWatermarks(rs, w, nil, wm, newConfig())
+	})
+}
+
+// Merge une docs en ese orden y escribe el resultado en w.
+func Merge(docs []io.ReadSeeker, w io.Writer) error {
+	if len(docs) == 0 {
+		return fmt.Errorf("nothing to merge")
+	}
+	return safely(func() error {
+		return api.MergeRaw(docs, w, false, newConfig())
+	})
+}
+
+// Medidas de las páginas de texto: A4 con Courier para que las columnas queden alineadas.
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	marginX      = 40
+	marginTop    = 50
+	fontSize     = 9
+	lineHeight   = 12
+	headerLines  = 4 // título, subtítulo, encabezado de columnas y una línea en blanco
+	LinesPerPage = (pageHeight-2*marginTop)/lineHeight - headerLines
+	// LineWidth es la cantidad de caracteres que entran en una línea.
+	LineWidth = (pageWidth - 2*marginX) * 10 / (fontSize * 6)
+)
+
+// TextPages es la cantidad de páginas que ocupan n líneas en WriteTextPages.
+func TextPages(n int) int {
+	if n <= 0 {
+		return 1
+	}
+	return (n + LinesPerPage - 1) / LinesPerPage
+}
+
+// WriteTextPages genera un PDF de texto monoespaciado: cada página repite title,
+// subtitle y columns, y lleva al pie el número de página.
+func WriteTextPages(w io.Writer, title, subtitle, columns string, lines []string) error {
+	pages := TextPages(len(lines))
+
+	var objects []string
+	// 1 catálogo, 2 árbol de páginas, 3 fuente; después página y contenido por cada hoja
+	kids := make([]string, pages)
+	for p := 0; p < pages; p++ {
+		kids[p] = fmt.Sprintf("%d 0 R", 4+2*p)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pages),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+	)
+
+	for p := 0; p < pages; p++ {
+		start := p * LinesPerPage
+		end := min(start+LinesPerPage, len(lines))
+
+		var content bytes.Buffer
+		y := pageHeight - marginTop
+		writeLine := func(text string, bold bool) {
+			size := fontSize
+			if bold {
+				size = fontSize + 3
+			}
+			fmt.Fprintf(&content, "BT /F1 %d Tf %d %d Td (%s) Tj ET\n", size, marginX, y, escape(text))
+			y -= lineHeight
+		}
+		writeLine(title, true)
+		writeLine(subtitle, false)
+		writeLine(columns, false)
+		y -= lineHeight
+		for _, line := range lines[start:end] {
+			writeLine(line, false)
+		}
+		fmt.Fprintf(&content, "BT /F1 %d Tf %d %d Td (%s) Tj ET\n",
+			fontSize, pageWidth/2-30, marginTop/2, escape(fmt.Sprintf("Page %d of %d", p+1, pages)))
+
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+				pageWidth, pageHeight, 5+2*p),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// escape convierte text a un string literal de PDF en WinAnsi: escapa los delimitadores
+// y reemplaza lo que no entra en Latin-1 (que coincide con WinAnsi para letras acentuadas).
+func escape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 128:
+			b.WriteRune(r)
+		case r < 256:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,101 @@
+package pdfdoc
+
+import (
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// ExtractText devuelve la capa de texto de un PDF, una línea por renglón de cada página.
+// Corta en maxBytes (0 = sin límite). Un PDF escaneado sin OCR no tiene texto y devuelve "".
+func ExtractText(r io.ReaderAt, size int64, maxBytes int) (text string, err error) {
+	var b strings.Builder
+	err = safely(func() error {
+		reader, err := pdf.NewReader(r, size)
+		if err != nil {
+			return err
+		}
+		for i := 1; i <= reader.NumPage(); i++ {
+			page := reader.Page(i)
+			if page.V.IsNull() {
+				continue
+			}
+			for _, row := range groupRows(page.Content().Text) {
+				line := joinRow(row)
+				if line == "" {
+					continue
+				}
+				b.WriteString(line)
+				b.WriteByte('\n')
+				if maxBytes > 0 && b.Len() >= maxBytes {
+					return nil
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return cleanText(b.String(), maxBytes), nil
+}
+
+// groupRows agrupa los fragmentos por renglón (misma altura, con tolerancia) de arriba
+// hacia abajo y ordena cada renglón de izquierda a derecha.
+func groupRows(texts []pdf.Text) [][]pdf.Text {
+	sorted := make([]pdf.Text, len(texts))
+	copy(sorted, texts)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Y > sorted[j].Y
+	})
+
+	var rows [][]pdf.Text
+	for _, t := range sorted {
+		n := len(rows)
+		if n > 0 {
+			first := rows[n-1][0]
+			tolerance := math.Max(first.FontSize, 1) / 2
+			if math.Abs(first.Y-t.Y) <= tolerance {
+				rows[n-1] = append(rows[n-1], t)
+				continue
+			}
+		}
+		rows = append(rows, []pdf.Text{t})
+	}
+	for _, row := range rows {
+		sort.SliceStable(row, func(i, j int) bool {
+			return row[i].X < row[j].X
+		})
+	}
+	return rows
+}
+
+// joinRow une los fragmentos de un renglón. Muchos PDFs posicionan cada palabra (o letra)
+// por separado, así que el espacio se deduce de la distancia entre fragmentos.
+func joinRow(texts []pdf.Text) string {
+	var b strings.Builder
+	for i, t := range texts {
+		if i > 0 {
+			prev := texts[i-1]
+			gap := t.X - (prev.X + prev.W)
+			if gap > prev.FontSize*0.2 && !strings.HasSuffix(prev.S, " ") && !strings.HasPrefix(t.S, " ") {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(t.S)
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// cleanText deja el texto apto para guardarlo en Postgres: UTF-8 válido y sin NUL.
+func cleanText(s string, maxBytes int) string {
+	s = strings.ToValidUTF8(s, "")
+	s = strings.ReplaceAll(s, "\x00", "")
+	if maxBytes > 0 && len(s) > maxBytes {
+		s = strings.ToValidUTF8(s[:maxBytes], "")
+	}
+	return strings.TrimSpace(s)
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"gorm.io/gorm"
+)
+
+// applyTransactionFilter agrega a q las condiciones comunes de ingresos y gastos.
+func applyTransactionFilter(q *gorm.DB, f domain.TransactionFilter) *gorm.DB {
+	if f.From != nil {
+		q = q.Where("date >= ?", *f.From)
+	}
+	if f.To != nil {
+		q = q.Where("date <= ?", *f.To)
+	}
+	if f.Type != "" {
+		q = q.Where("type = ?", f.Type)
+	}
+	if f.CreatedBy != 0 {
+		q = q.Where("created_by = ?", f.CreatedBy)
+	}
+	return q
+}
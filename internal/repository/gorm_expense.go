@@ -41,6 +41,19 @@ func (r *GormExpenseRepo) List(ctx context.Context) ([]domain.Expense, error) {
 	return expenses, nil
 }
 
+func (r *GormExpenseRepo) ListFiltered(ctx context.Context, filter domain.TransactionFilter) ([]domain.Expense, error) {
+	var expenses []domain.Expense
+	q := r.db.WithContext(ctx).
+		Preload("Receipt").
+		Where("deleted_at IS NULL")
+	if err := applyTransactionFilter(q, filter).
+		Order("date, id").
+		Find(&expenses).Error; err != nil {
+		return nil, err
+	}
+	return expenses, nil
+}
+
 func (r *GormExpenseRepo) CreateWithReceipt(
 	ctx context.Context,
 	expense *domain.Expense,
@@ -50,6 +63,10 @@ func (r *GormExpenseRepo) CreateWithReceipt(
 		if err := tx.Create(expense).Error; err != nil {
 			return err
 		}
+		// Sin comprobante el gasto queda pendiente
+		if receipt == nil {
+			return nil
+		}
 		receipt.ExpenseID = &expense.ID
 		receipt.IncomeID = nil
 		if err := tx.Create(receipt).Error; err != nil {
@@ -90,6 +107,26 @@ func (r *GormExpenseRepo) UpdateWithReceipt(
 	})
 }
 
+// AttachReceipt marca el gasto como completo en la misma transacción en que se guarda
+// el comprobante; el UPDATE condicional evita adjuntar dos comprobantes a la vez.
+func (r *GormExpenseRepo) AttachReceipt(ctx context.Context, id uint, receipt *domain.Receipt) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.Expense{}).
+			Where("id = ? AND deleted_at IS NULL AND receipt_pending", id).
+			Updates(map[string]any{"receipt_pending": false, "receipt_reminded_at": nil})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrReceiptAttached
+		}
+
+		receipt.ExpenseID = &id
+		receipt.IncomeID = nil
+		return tx.Create(receipt).Error
+	})
+}
+
 func (r *GormExpenseRepo) Delete(ctx context.Context, id uint) error {
 	return r.db.WithContext(ctx).Unscoped().Delete(&domain.Expense{}, id).Error
 }
@@ -103,8 +140,66 @@ func (r *GormExpenseRepo) SoftDelete(ctx context.Context, id uint) error {
 }
 
 func (r *GormExpenseRepo) Restore(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).
+	result := r.db.WithContext(ctx).
 		Model(&domain.Expense{}).
-		Where("id = ?", id).
-		Update("deleted_at", nil).Error
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *GormExpenseRepo) GetDeletedByID(ctx context.Context, id uint) (*domain.Expense, error) {
+	var expense domain.Expense
+	if err := r.db.WithContext(ctx).
+		Preload("Receipt").
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		First(&expense).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &expense, nil
+}
+
+func (r *GormExpenseRepo) ListDeleted(ctx context.Context) ([]domain.Expense, error) {
+	var expenses []domain.Expense
+	if err := r.db.WithContext(ctx).
+		Preload("Receipt").
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC, id").
+		Find(&expenses).Error; err != nil {
+		return nil, err
+	}
+	return expenses, nil
+}
+
+func (r *GormExpenseRepo) ListDeletedBefore(ctx context.Context, cutoff time.Time) ([]domain.Expense, error) {
+	var expenses []domain.Expense
+	if err := r.db.WithContext(ctx).
+		Preload("Receipt").
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Order("deleted_at, id").
+		Find(&expenses).Error; err != nil {
+		return nil, err
+	}
+	return expenses, nil
+}
+
+// Purge vuelve a comprobar la fecha en el DELETE: si alguien lo restauró
+// después del listado, no se borra.
+func (r *GormExpenseRepo) Purge(ctx context.Context, id uint, cutoff time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL AND deleted_at < ?", id, cutoff).
+		Delete(&domain.Expense{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
 }
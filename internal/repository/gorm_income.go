@@ -41,6 +41,19 @@ func (r *GormIncomeRepo) List(ctx context.Context) ([]domain.Income, error) {
 	return incomes, nil
 }
 
+func (r *GormIncomeRepo) ListFiltered(ctx context.Context, filter domain.TransactionFilter) ([]domain.Income, error) {
+	var incomes []domain.Income
+	q := r.db.WithContext(ctx).
+		Preload("Receipt").
+		Where("deleted_at IS NULL")
+	if err := applyTransactionFilter(q, filter).
+		Order("date, id").
+		Find(&incomes).Error; err != nil {
+		return nil, err
+	}
+	return incomes, nil
+}
+
 func (r *GormIncomeRepo) CreateWithReceipt(
 	ctx context.Context,
 	income *domain.Income,
@@ -50,6 +63,10 @@ func (r *GormIncomeRepo) CreateWithReceipt(
 		if err := tx.Create(income).Error; err != nil {
 			return err
 		}
+		// Sin comprobante el ingreso queda pendiente
+		if receipt == nil {
+			return nil
+		}
 
 		// En el repo, después de tx.Create(income)
 		receipt.IncomeID = &income.ID
@@ -95,6 +112,26 @@ func (r *GormIncomeRepo) UpdateWithReceipt(
 	})
 }
 
+// AttachReceipt marca el ingreso como completo en la misma transacción en que se guarda
+// el comprobante; el UPDATE condicional evita adjuntar dos comprobantes a la vez.
+func (r *GormIncomeRepo) AttachReceipt(ctx context.Context, id uint, receipt *domain.Receipt) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.Income{}).
+			Where("id = ? AND deleted_at IS NULL AND receipt_pending", id).
+			Updates(map[string]any{"receipt_pending": false, "receipt_reminded_at": nil})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrReceiptAttached
+		}
+
+		receipt.IncomeID = &id
+		receipt.ExpenseID = nil
+		return tx.Create(receipt).Error
+	})
+}
+
 func (r *GormIncomeRepo) Delete(ctx context.Context, id uint) error {
 	return r.db.WithContext(ctx).Unscoped().Delete(&domain.Income{}, id).Error
 }
@@ -108,8 +145,66 @@ func (r *GormIncomeRepo) SoftDelete(ctx context.Context, id uint) error {
 }
 
 func (r *GormIncomeRepo) Restore(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).
+	result := r.db.WithContext(ctx).
 		Model(&domain.Income{}).
-		Where("id = ?", id).
-		Update("deleted_at", nil).Error
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *GormIncomeRepo) GetDeletedByID(ctx context.Context, id uint) (*domain.Income, error) {
+	var income domain.Income
+	if err := r.db.WithContext(ctx).
+		Preload("Receipt").
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		First(&income).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &income, nil
+}
+
+func (r *GormIncomeRepo) ListDeleted(ctx context.Context) ([]domain.Income, error) {
+	var incomes []domain.Income
+	if err := r.db.WithContext(ctx).
+		Preload("Receipt").
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC, id").
+		Find(&incomes).Error; err != nil {
+		return nil, err
+	}
+	return incomes, nil
+}
+
+func (r *GormIncomeRepo) ListDeletedBefore(ctx context.Context, cutoff time.Time) ([]domain.Income, error) {
+	var incomes []domain.Income
+	if err := r.db.WithContext(ctx).
+		Preload("Receipt").
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Order("deleted_at, id").
+		Find(&incomes).Error; err != nil {
+		return nil, err
+	}
+	return incomes, nil
+}
+
+// Purge vuelve a comprobar la fecha en el DELETE: si alguien lo restauró
+// después del listado, no se borra.
+func (r *GormIncomeRepo) Purge(ctx context.Context, id uint, cutoff time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Unscoped().
+		Where("id = ? AND deleted_at IS NOT NULL AND deleted_at < ?", id, cutoff).
+		Delete(&domain.Income{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
 }
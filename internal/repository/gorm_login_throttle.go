@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"gorm.io/gorm"
+)
+
+type GormLoginThrottleRepo struct {
+	db *gorm.DB
+}
+
+func NewGormLoginThrottleRepo(db *gorm.DB) domain.LoginThrottleRepo {
+	return &GormLoginThrottleRepo{db: db}
+}
+
+func (r *GormLoginThrottleRepo) Get(ctx context.Context, keys ...string) ([]domain.LoginThrottle, error) {
+	var throttles []domain.LoginThrottle
+	if len(keys) == 0 {
+		return throttles, nil
+	}
+	err := r.db.WithContext(ctx).Where("key IN ?", keys).Find(&throttles).Error
+	return throttles, err
+}
+
+// RecordFailure suma el fallo con un upsert para que los intentos simultáneos no se pisen.
+func (r *GormLoginThrottleRepo) RecordFailure(ctx context.Context, key string, at, windowStart time.Time) (*domain.LoginThrottle, error) {
+	var throttle domain.LoginThrottle
+	err := r.db.WithContext(ctx).Raw(`
+		INSERT INTO login_throttles (key, failures, last_failure_at)
+		VALUES (?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_throttles.last_failure_at < ? THEN 1 ELSE login_throttles.failures + 1 END,
+			last_failure_at = EXCLUDED.last_failure_at
+		RETURNING key, failures, last_failure_at, locked_until`,
+		key, at, windowStart,
+	).Scan(&throttle).Error
+	if err != nil {
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+func (r *GormLoginThrottleRepo) Lock(ctx context.Context, key string, until time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.LoginThrottle{}).
+		Where("key = ?", key).
+		Updates(map[string]any{"failures": 0, "locked_until": until}).Error
+}
+
+func (r *GormLoginThrottleRepo) Reset(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Where("key IN ?", keys).Delete(&domain.LoginThrottle{}).Error
+}
+
+// DeleteStale borra los contadores sin fallos desde before y sin un bloqueo vigente.
+func (r *GormLoginThrottleRepo) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", before, before).
+		Delete(&domain.LoginThrottle{})
+	return result.RowsAffected, result.Error
+}
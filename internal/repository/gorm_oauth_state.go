@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormOAuthStateRepo struct {
+	db *gorm.DB
+}
+
+func NewGormOAuthStateRepo(db *gorm.DB) domain.OAuthStateRepo {
+	return &GormOAuthStateRepo{db: db}
+}
+
+func (r *GormOAuthStateRepo) Create(ctx context.Context, state *domain.OAuthState) error {
+	return r.db.WithContext(ctx).Create(state).Error
+}
+
+// Consume borra el state y lo devuelve con DELETE ... RETURNING: si llegan dos callbacks
+// con el mismo state, solo uno lo obtiene.
+func (r *GormOAuthStateRepo) Consume(ctx context.Context, id string) (*domain.OAuthState, error) {
+	var states []domain.OAuthState
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.Returning{}).
+		Where("id = ?", id).
+		Delete(&states).Error; err != nil {
+		return nil, err
+	}
+	if len(states) == 0 {
+		return nil, domain.ErrNotFound
+	}
+	return &states[0], nil
+}
+
+func (r *GormOAuthStateRepo) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("expires_at < ?", before).
+		Delete(&domain.OAuthState{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"gorm.io/gorm"
+)
+
+type GormRefreshTokenRepo struct {
+	db *gorm.DB
+}
+
+func NewGormRefreshTokenRepo(db *gorm.DB) domain.RefreshTokenRepo {
+	return &GormRefreshTokenRepo{db: db}
+}
+
+func (r *GormRefreshTokenRepo) Create(ctx context.Context, token *domain.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *GormRefreshTokenRepo) GetByHash(ctx context.Context, hash string) (*domain.RefreshToken, error) {
+	var token domain.RefreshToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed solo marca el token si nadie lo usó ni revocó antes (evita dos rotaciones
+// concurrentes con el mismo token).
+func (r *GormRefreshTokenRepo) MarkUsed(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&domain.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *GormRefreshTokenRepo) RevokeFamily(ctx context.Context, familyID string) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *GormRefreshTokenRepo) RevokeUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeOthers revoca los tokens del usuario que no son de la familia keepFamilyID.
+func (r *GormRefreshTokenRepo) RevokeOthers(ctx context.Context, userID uint, keepFamilyID string) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.RefreshToken{}).
+		Where("user_id = ? AND family_id <> ? AND revoked_at IS NULL", userID, keepFamilyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// DeleteExpired borra los tokens vencidos antes de before; ya no sirven ni para detectar
+// reusos porque un token vencido se rechaza igual.
+func (r *GormRefreshTokenRepo) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("expires_at < ?", before).
+		Delete(&domain.RefreshToken{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"gorm.io/gorm"
+)
+
+// pendingSQL lista ingresos y gastos con el comprobante pendiente, los más viejos primero.
+// Con @due solo devuelve los que toca recordar: creados antes de @cutoff y sin recordatorio
+// desde entonces.
+const pendingSQL = `
+SELECT * FROM (
+	SELECT 'income' AS kind, id, date, type, amount, description, created_by, created_at,
+		receipt_reminded_at AS reminded_at
+	FROM incomes
+	WHERE receipt_pending AND deleted_at IS NULL
+	UNION ALL
+	SELECT 'expense' AS kind, id, date, type, amount, description, created_by, created_at,
+		receipt_reminded_at AS reminded_at
+	FROM expenses
+	WHERE receipt_pending AND deleted_at IS NULL
+) pending
+WHERE (@user_id = 0 OR created_by = @user_id)
+	AND (NOT @due OR (created_at < @cutoff AND (reminded_at IS NULL OR reminded_at < @cutoff)))
+ORDER BY created_at, kind, id`
+
+// reminderVisible deja fuera los recordatorios cuya transacción ya tiene comprobante
+// o fue borrada.
+const reminderVisible = `
+	(kind = 'income' AND EXISTS (
+		SELECT 1 FROM incomes t WHERE t.id = transaction_id AND t.receipt_pending AND t.deleted_at IS NULL))
+	OR (kind = 'expense' AND EXISTS (
+		SELECT 1 FROM expenses t WHERE t.id = transaction_id AND t.receipt_pending AND t.deleted_at IS NULL))`
+
+type GormReminderRepo struct {
+	db *gorm.DB
+}
+
+func NewGormReminderRepo(db *gorm.DB) domain.ReminderRepo {
+	return &GormReminderRepo{db: db}
+}
+
+func (r *GormReminderRepo) ListPending(ctx context.Context, userID uint) ([]domain.PendingReceipt, error) {
+	return r.pending(ctx, userID, false, time.Time{})
+}
+
+func (r *GormReminderRepo) ListDue(ctx context.Context, cutoff time.Time) ([]domain.PendingReceipt, error) {
+	return r.pending(ctx, 0, true, cutoff)
+}
+
+func (r *GormReminderRepo) pending(ctx context.Context, userID uint, due bool, cutoff time.Time) ([]domain.PendingReceipt, error) {
+	var items []domain.PendingReceipt
+	if err := r.db.WithContext(ctx).
+		Raw(pendingSQL, map[string]any{
+			"user_id": userID,
+			"due":     due,
+			"cutoff":  cutoff,
+		}).
+		Scan(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Remind marca la transacción como recordada y guarda el recordatorio en una sola
+// transacción. La marca solo se pone si nadie recordó la misma fila desde que se listó,
+// así dos instancias corriendo el job no duplican avisos.
+func (r *GormReminderRepo) Remind(ctx context.Context, item domain.PendingReceipt, reminder *domain.Reminder) (bool, error) {
+	var model any
+	switch item.Kind {
+	case "income":
+		model = &domain.Income{}
+	case "expense":
+		model = &domain.Expense{}
+	default:
+		return false, fmt.Errorf("%w: unknown kind %q", domain.ErrInvalidInput, item.Kind)
+	}
+
+	reminded := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(model).
+			Where("id = ? AND receipt_pending AND deleted_at IS NULL", item.ID).
+			Where("receipt_reminded_at IS NOT DISTINCT FROM ?", item.RemindedAt).
+			Update("receipt_reminded_at", reminder.CreatedAt)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := tx.Create(reminder).Error; err != nil {
+			return err
+		}
+		reminded = true
+		return nil
+	})
+	return reminded, err
+}
+
+func (r *GormReminderRepo) ListByUser(ctx context.Context, userID uint) ([]domain.Reminder, error) {
+	var reminders []domain.Reminder
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Where(reminderVisible).
+		Order("read_at IS NOT NULL, created_at DESC, id DESC").
+		Find(&reminders).Error; err != nil {
+		return nil, err
+	}
+	return reminders, nil
+}
+
+func (r *GormReminderRepo) MarkRead(ctx context.Context, userID, id uint) error {
+	result := r.db.WithContext(ctx).
+		Model(&domain.Reminder{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("read_at", gorm.Expr("COALESCE(read_at, NOW())"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"gorm.io/gorm"
+)
+
+// searchSQL busca con full-text search de Postgres en descripción y tipo de ingresos y gastos
+// (search_vector de cada tabla) y en el texto de sus comprobantes (receipts.search_vector).
+// La consulta se interpreta en español y en inglés a la vez; el ranking pesa más la descripción
+// que el tipo y el tipo más que el texto del PDF. ts_headline es caro, por eso se calcula
+// solo para la página de resultados.
+const searchSQL = `
+WITH query AS (
+	SELECT websearch_to_tsquery('spanish', @text) || websearch_to_tsquery('english', @text) AS q
+),
+matches AS (
+	SELECT 'income' AS kind, t.id, t.date, t.type, t.amount, t.description, t.created_by,
+		r.id AS receipt_id, r.file_name, r.content,
+		t.search_vector || coalesce(r.search_vector, ''::tsvector) AS document,
+		coalesce(r.search_vector @@ query.q, false) AS in_receipt
+	FROM incomes t
+	CROSS JOIN query
+	LEFT JOIN receipts r ON r.income_id = t.id
+	WHERE @kind IN ('', 'income')
+		AND t.deleted_at IS NULL
+		AND (@created_by = 0 OR t.created_by = @created_by)
+		AND (t.search_vector @@ query.q OR r.search_vector @@ query.q)
+	UNION ALL
+	SELECT 'expense' AS kind, t.id, t.date, t.type, t.amount, t.description, t.created_by,
+		r.id AS receipt_id, r.file_name, r.content,
+		t.search_vector || coalesce(r.search_vector, ''::tsvector) AS document,
+		coalesce(r.search_vector @@ query.q, false) AS in_receipt
+	FROM expenses t
+	CROSS JOIN query
+	LEFT JOIN receipts r ON r.expense_id = t.id
+	WHERE @kind IN ('', 'expense')
+		AND t.deleted_at IS NULL
+		AND (@created_by = 0 OR t.created_by = @created_by)
+		AND (t.search_vector @@ query.q OR r.search_vector @@ query.q)
+),
+ranked AS (
+	SELECT m.*, ts_rank(m.document, query.q) AS rank
+	FROM matches m
+	CROSS JOIN query
+	ORDER BY rank DESC, m.date DESC, m.id DESC
+	LIMIT @limit OFFSET @offset
+)
+SELECT ranked.kind, ranked.id, ranked.date, ranked.type, ranked.amount, ranked.description,
+	ranked.created_by, ranked.receipt_id, ranked.file_name, ranked.rank,
+	CASE WHEN ranked.in_receipt
+		THEN ts_headline('spanish', ranked.content, query.q,
+			'MaxFragments=2, MinWords=5, MaxWords=15, StartSel=«, StopSel=»')
+		ELSE ''
+	END AS snippet
+FROM ranked
+CROSS JOIN query
+ORDER BY ranked.rank DESC, ranked.date DESC, ranked.id DESC`
+
+type GormSearchRepo struct {
+	db *gorm.DB
+}
+
+func NewGormSearchRepo(db *gorm.DB) domain.SearchRepo {
+	return &GormSearchRepo{db: db}
+}
+
+func (r *GormSearchRepo) Search(ctx context.Context, query domain.SearchQuery) ([]domain.SearchResult, error) {
+	var results []domain.SearchResult
+	if err := r.db.WithContext(ctx).
+		Raw(searchSQL, map[string]any{
+			"text":       query.Text,
+			"kind":       query.Kind,
+			"created_by": query.CreatedBy,
+			"limit":      query.Limit,
+			"offset":     query.Offset,
+		}).
+		Scan(&results).Error; err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"gorm.io/gorm"
+)
+
+type GormSecurityEventRepo struct {
+	db *gorm.DB
+}
+
+func NewGormSecurityEventRepo(db *gorm.DB) domain.SecurityEventRepo {
+	return &GormSecurityEventRepo{db: db}
+}
+
+func (r *GormSecurityEventRepo) Create(ctx context.Context, event *domain.SecurityEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *GormSecurityEventRepo) List(ctx context.Context, filter domain.SecurityEventFilter) ([]domain.SecurityEvent, error) {
+	query := r.db.WithContext(ctx).Model(&domain.SecurityEvent{})
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+
+	var events []domain.SecurityEvent
+	err := query.Order("created_at DESC, id DESC").Find(&events).Error
+	return events, err
+}
+
+func (r *GormSecurityEventRepo) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("created_at < ?", before).Delete(&domain.SecurityEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"gorm.io/gorm"
+)
+
+type GormSessionRepo struct {
+	db *gorm.DB
+}
+
+func NewGormSessionRepo(db *gorm.DB) domain.SessionRepo {
+	return &GormSessionRepo{db: db}
+}
+
+func (r *GormSessionRepo) Create(ctx context.Context, session *domain.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+func (r *GormSessionRepo) GetByID(ctx context.Context, id uint) (*domain.Session, error) {
+	var session domain.Session
+	if err := r.db.WithContext(ctx).First(&session, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *GormSessionRepo) GetByFamily(ctx context.Context, familyID string) (*domain.Session, error) {
+	var session domain.Session
+	if err := r.db.WithContext(ctx).Where("family_id = ?", familyID).First(&session).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *GormSessionRepo) ListActive(ctx context.Context, userID uint, now time.Time) ([]domain.Session, error) {
+	var sessions []domain.Session
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_seen_at DESC, id DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *GormSessionRepo) Seen(ctx context.Context, id uint, at time.Time, ip string) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.Session{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"last_seen_at": at, "ip": ip}).Error
+}
+
+func (r *GormSessionRepo) Extend(ctx context.Context, id uint, at time.Time, device string, client domain.ClientInfo, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.Session{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"last_seen_at": at,
+			"device":       device,
+			"ip":           client.IP,
+			"user_agent":   client.UserAgent,
+			"expires_at":   expiresAt,
+		}).Error
+}
+
+func (r *GormSessionRepo) RevokeFamily(ctx context.Context, familyID string) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.Session{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *GormSessionRepo) RevokeUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeOthers revoca las sesiones del usuario salvo la de la familia keepFamilyID.
+func (r *GormSessionRepo) RevokeOthers(ctx context.Context, userID uint, keepFamilyID string) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.Session{}).
+		Where("user_id = ? AND family_id <> ? AND revoked_at IS NULL", userID, keepFamilyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// DeleteExpired borra las sesiones vencidas antes de before, revocadas o no: sus access
+// tokens ya vencieron también.
+func (r *GormSessionRepo) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("expires_at < ?", before).
+		Delete(&domain.Session{})
+	return result.RowsAffected, result.Error
+}
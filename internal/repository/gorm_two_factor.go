@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormTwoFactorRepo struct {
+	db *gorm.DB
+}
+
+func NewGormTwoFactorRepo(db *gorm.DB) domain.TwoFactorRepo {
+	return &GormTwoFactorRepo{db: db}
+}
+
+func (r *GormTwoFactorRepo) Get(ctx context.Context, userID uint) (*domain.TwoFactor, error) {
+	var tf domain.TwoFactor
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&tf).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &tf, nil
+}
+
+// Begin pisa un alta pendiente con el secreto nuevo; una confirmada no se toca.
+func (r *GormTwoFactorRepo) Begin(ctx context.Context, enrolment *domain.TwoFactor) error {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"secret", "last_step", "created_at", "updated_at"}),
+			Where:     clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "two_factors.confirmed_at IS NULL"}}},
+		}).
+		Create(enrolment)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrTwoFactorEnabled
+	}
+	return nil
+}
+
+func (r *GormTwoFactorRepo) Enable(ctx context.Context, userID uint, step int64, codeHashes []string) (bool, error) {
+	enabled := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&domain.TwoFactor{}).
+			Where("user_id = ? AND confirmed_at IS NULL", userID).
+			Updates(map[string]any{"confirmed_at": time.Now(), "last_step": step, "updated_at": time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		enabled = true
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+	return enabled, err
+}
+
+func (r *GormTwoFactorRepo) Delete(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&domain.TwoFactor{}).Error
+	})
+}
+
+// UseStep avanza last_step solo si el intervalo es posterior al último aceptado.
+func (r *GormTwoFactorRepo) UseStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&domain.TwoFactor{}).
+		Where("user_id = ? AND last_step < ?", userID, step).
+		Updates(map[string]any{"last_step": step, "updated_at": time.Now()})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *GormTwoFactorRepo) ReplaceRecoveryCodes(ctx context.Context, userID uint, codeHashes []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, userID, codeHashes)
+	})
+}
+
+func (r *GormTwoFactorRepo) UseRecoveryCode(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *GormTwoFactorRepo) CountRecoveryCodes(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&domain.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *GormTwoFactorRepo) ListPolicies(ctx context.Context) ([]domain.TwoFactorPolicy, error) {
+	var policies []domain.TwoFactorPolicy
+	if err := r.db.WithContext(ctx).Order("role").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+func (r *GormTwoFactorRepo) SetPolicy(ctx context.Context, policy *domain.TwoFactorPolicy) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "role"}},
+			DoUpdates: clause.AssignmentColumns([]string{"required", "updated_at"}),
+		}).
+		Create(policy).Error
+}
+
+func (r *GormTwoFactorRepo) IsRequired(ctx context.Context, role string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).
+		Model(&domain.TwoFactorPolicy{}).
+		Where("role = ? AND required", role).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// replaceRecoveryCodes borra los códigos anteriores, usados o no, y guarda los nuevos.
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, codeHashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&domain.RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]domain.RecoveryCode, len(codeHashes))
+	for i, h := range codeHashes {
+		codes[i] = domain.RecoveryCode{UserID: userID, CodeHash: h}
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return tx.Create(&codes).Error
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"gorm.io/gorm"
+)
+
+type GormUploadRepo struct {
+	db *gorm.DB
+}
+
+func NewGormUploadRepo(db *gorm.DB) domain.UploadRepo {
+	return &GormUploadRepo{db: db}
+}
+
+func (r *GormUploadRepo) GetByID(ctx context.Context, id string) (*domain.Upload, error) {
+	var upload domain.Upload
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&upload).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &upload, nil
+}
+
+func (r *GormUploadRepo) List(ctx context.Context) ([]domain.Upload, error) {
+	var uploads []domain.Upload
+	if err := r.db.WithContext(ctx).Find(&uploads).Error; err != nil {
+		return nil, err
+	}
+	return uploads, nil
+}
+
+func (r *GormUploadRepo) ListExpired(ctx context.Context, now time.Time) ([]domain.Upload, error) {
+	var uploads []domain.Upload
+	if err := r.db.WithContext(ctx).Where("expires_at < ?", now).Find(&uploads).Error; err != nil {
+		return nil, err
+	}
+	return uploads, nil
+}
+
+func (r *GormUploadRepo) Create(ctx context.Context, upload *domain.Upload) error {
+	return r.db.WithContext(ctx).Create(upload).Error
+}
+
+// UpdateOffset avanza el offset solo si sigue valiendo from (evita pisar un PATCH concurrente).
+func (r *GormUploadRepo) UpdateOffset(ctx context.Context, id string, from, to int64) error {
+	result := r.db.WithContext(ctx).
+		Model(&domain.Upload{}).
+		Where("id = ? AND upload_offset = ?", id, from).
+		Update("upload_offset", to)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrOffsetMismatch
+	}
+	return nil
+}
+
+func (r *GormUploadRepo) Update(ctx context.Context, upload *domain.Upload) error {
+	return r.db.WithContext(ctx).Save(upload).Error
+}
+
+func (r *GormUploadRepo) Consume(ctx context.Context, id string) (bool, error) {
+	result := r.db.WithContext(ctx).Where("id = ?", id).Delete(&domain.Upload{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *GormUploadRepo) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&domain.Upload{}).Error
+}
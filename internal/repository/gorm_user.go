@@ -3,6 +3,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/SaidMg10/gestor-one/internal/domain"
 	"gorm.io/gorm"
@@ -38,6 +39,17 @@ func (r *GormUserRepo) GetByID(ctx context.Context, id uint) (*domain.User, erro
 	return &user, nil
 }
 
+func (r *GormUserRepo) GetByGoogleID(ctx context.Context, googleID string) (*domain.User, error) {
+	var user domain.User
+	if err := r.db.WithContext(ctx).Where("google_id = ?", googleID).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
 func (r *GormUserRepo) List(ctx context.Context) ([]domain.User, error) {
 	var users []domain.User
 	if err := r.db.WithContext(ctx).Find(&users).Error; err != nil {
@@ -58,6 +70,29 @@ func (r *GormUserRepo) Update(ctx context.Context, user *domain.User) error {
 		Error
 }
 
+// UpdateProfile escribe solo los campos presentes en profile. A diferencia de Update,
+// un string vacío se guarda: así se borra el teléfono.
+func (r *GormUserRepo) UpdateProfile(ctx context.Context, id uint, profile domain.ProfileUpdate) error {
+	fields := map[string]any{}
+	if profile.Name != nil {
+		fields["name"] = *profile.Name
+	}
+	if profile.LastName != nil {
+		fields["last_name"] = *profile.LastName
+	}
+	if profile.Phone != nil {
+		fields["phone"] = *profile.Phone
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).
+		Model(&domain.User{}).
+		Where("id = ?", id).
+		Updates(fields).
+		Error
+}
+
 func (r *GormUserRepo) Delete(ctx context.Context, id uint) error {
 	return r.db.WithContext(ctx).Delete(&domain.User{}, id).Error
 }
@@ -69,3 +104,11 @@ func (r *GormUserRepo) ExistsByEmail(ctx context.Context, email string) (bool, e
 	}
 	return count > 0, nil
 }
+
+func (r *GormUserRepo) SetEmailVerifiedAt(ctx context.Context, id uint, at *time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.User{}).
+		Where("id = ?", id).
+		Update("email_verified_at", at).
+		Error
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormUserIdentityRepo struct {
+	db *gorm.DB
+}
+
+func NewGormUserIdentityRepo(db *gorm.DB) domain.UserIdentityRepo {
+	return &GormUserIdentityRepo{db: db}
+}
+
+func (r *GormUserIdentityRepo) GetUser(ctx context.Context, provider, subject string) (*domain.User, error) {
+	var user domain.User
+	if err := r.db.WithContext(ctx).
+		Joins("JOIN user_identities ON user_identities.user_id = users.id").
+		Where("user_identities.provider = ? AND user_identities.subject = ?", provider, subject).
+		First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, domain.ErrNotFound
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Link se apoya en los índices únicos (provider, subject) y (user_id, provider):
+// si alguno ya existe no inserta nada.
+func (r *GormUserIdentityRepo) Link(ctx context.Context, identity *domain.UserIdentity) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(identity)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *GormUserIdentityRepo) CreateUser(ctx context.Context, user *domain.User, identity *domain.UserIdentity) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type GormUserTokenRepo struct {
+	db *gorm.DB
+}
+
+func NewGormUserTokenRepo(db *gorm.DB) domain.UserTokenRepo {
+	return &GormUserTokenRepo{db: db}
+}
+
+func (r *GormUserTokenRepo) Create(ctx context.Context, token *domain.UserToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+// Consume marca el token como usado con UPDATE ... RETURNING: si llegan dos pedidos con
+// el mismo token, solo uno lo obtiene.
+func (r *GormUserTokenRepo) Consume(ctx context.Context, purpose, hash string) (*domain.UserToken, error) {
+	var tokens []domain.UserToken
+	now := time.Now()
+	if err := r.db.WithContext(ctx).
+		Model(&tokens).
+		Clauses(clause.Returning{}).
+		Where("token_hash = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", hash, purpose, now).
+		Update("used_at", now).Error; err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, domain.ErrNotFound
+	}
+	return &tokens[0], nil
+}
+
+func (r *GormUserTokenRepo) DeleteByUser(ctx context.Context, userID uint, purpose string) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND purpose = ?", userID, purpose).
+		Delete(&domain.UserToken{}).Error
+}
+
+func (r *GormUserTokenRepo) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("expires_at < ? OR used_at IS NOT NULL", before).
+		Delete(&domain.UserToken{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,121 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+)
+
+// clamdChunkSize es el tamaño de cada bloque enviado con INSTREAM.
+const clamdChunkSize = 32 << 10
+
+// ClamAVScanner habla con clamd usando el comando INSTREAM:
+// el archivo se envía en bloques <largo uint32 big-endian><datos> y se termina con un bloque de largo 0.
+// clamd responde "stream: OK", "stream: <firma> FOUND" o "<motivo> ERROR".
+type ClamAVScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamAVScanner recibe la dirección de clamd como "unix:///var/run/clamav/clamd.ctl"
+// o "tcp://localhost:3310". Sin esquema se asume un socket unix.
+func NewClamAVScanner(address string, timeout time.Duration) (domain.MalwareScanner, error) {
+	if address == "" {
+		return nil, fmt.Errorf("clamav scanner requires an address")
+	}
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	network := "unix"
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		address = strings.TrimPrefix(address, "unix://")
+	case strings.HasPrefix(address, "tcp://"):
+		network = "tcp"
+		address = strings.TrimPrefix(address, "tcp://")
+	}
+
+	return &ClamAVScanner{
+		network: network,
+		address: address,
+		timeout: timeout,
+	}, nil
+}
+
+func (s *ClamAVScanner) Scan(ctx context.Context, r io.Reader) (*domain.ScanResult, error) {
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to clamd: %w", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, fmt.Errorf("cannot send command to clamd: %w", err)
+	}
+
+	buf := make([]byte, clamdChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				return nil, fmt.Errorf("cannot stream to clamd: %w", err)
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return nil, fmt.Errorf("cannot stream to clamd: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return nil, fmt.Errorf("cannot stream to clamd: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("cannot read clamd reply: %w", err)
+	}
+	return parseClamdReply(string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+func parseClamdReply(reply string) (*domain.ScanResult, error) {
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return &domain.ScanResult{Status: domain.ScanStatusClean}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return &domain.ScanResult{
+			Status:    domain.ScanStatusInfected,
+			Signature: strings.TrimSuffix(reply, " FOUND"),
+		}, nil
+	default:
+		return nil, fmt.Errorf("clamd error: %s", reply)
+	}
+}
@@ -0,0 +1,39 @@
+// Package scanner implements malware scanners for uploaded receipts.
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/SaidMg10/gestor-one/internal/config"
+	"github.com/SaidMg10/gestor-one/internal/domain"
+)
+
+const (
+	DriverNone   = "none"
+	DriverClamAV = "clamav"
+)
+
+// New construye el scanner indicado en la configuración. Por defecto no se escanea.
+func New(cfg config.ScannerConfig) (domain.MalwareScanner, error) {
+	switch cfg.Driver {
+	case "", DriverNone:
+		return NewNoopScanner(), nil
+	case DriverClamAV:
+		return NewClamAVScanner(cfg.Address, cfg.Timeout)
+	default:
+		return nil, fmt.Errorf("unknown scanner driver %q", cfg.Driver)
+	}
+}
+
+// NoopScanner no analiza nada; marca los archivos como "skipped".
+type NoopScanner struct{}
+
+func NewNoopScanner() domain.MalwareScanner {
+	return &NoopScanner{}
+}
+
+func (n *NoopScanner) Scan(ctx context.Context, r io.Reader) (*domain.ScanResult, error) {
+	return &domain.ScanResult{Status: domain.ScanStatusSkipped}, nil
+}
@@ -0,0 +1,284 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/validator"
+)
+
+// resetAction es la acción con la que se cuentan los pedidos de recuperación en
+// LoginGuardService.Allow.
+const resetAction = "reset"
+
+// ResetLimits son cuántos emails de recuperación se mandan como mucho por email y por IP
+// dentro de la ventana de LoginPolicy. 0 no limita.
+type ResetLimits struct {
+	PerEmail int
+	PerIP    int
+}
+
+// AccountService recupera contraseñas olvidadas y verifica emails con tokens de un solo
+// uso que se mandan por email. Los tokens son aleatorios y en la base solo queda su hash.
+type AccountService struct {
+	userRepo    domain.UserRepo
+	tokenRepo   domain.UserTokenRepo
+	authSvc     *AuthService
+	mailer      domain.Mailer
+	baseURL     string
+	resetTTL    time.Duration
+	verifyTTL   time.Duration
+	resetLimits ResetLimits
+}
+
+// NewAccountService crea el servicio; baseURL es la URL del frontend que arma los enlaces.
+func NewAccountService(
+	userRepo domain.UserRepo,
+	tokenRepo domain.UserTokenRepo,
+	authSvc *AuthService,
+	mailer domain.Mailer,
+	baseURL string,
+	resetTTL time.Duration,
+	verifyTTL time.Duration,
+	resetLimits ResetLimits,
+) *AccountService {
+	return &AccountService{
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		authSvc:     authSvc,
+		mailer:      mailer,
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		resetTTL:    resetTTL,
+		verifyTTL:   verifyTTL,
+		resetLimits: resetLimits,
+	}
+}
+
+// ForgotPassword pide el enlace para elegir una contraseña nueva. No informa si el email
+// existe: la búsqueda y el envío van en segundo plano y sus errores solo quedan en el log,
+// así ni la respuesta ni su demora cambian. Los pedidos se limitan por email y por IP para
+// que no sirva para llenar la casilla de nadie; los que se pasan se descartan.
+func (s *AccountService) ForgotPassword(ctx context.Context, email, ip string) {
+	allowed, err := s.authSvc.Guard.Allow(ctx, resetAction, email, ip, s.resetLimits.PerEmail, s.resetLimits.PerIP)
+	if err != nil {
+		log.Printf("failed to rate limit password reset for %q: %v", email, err)
+		return
+	}
+	if !allowed {
+		return
+	}
+
+	// El pedido HTTP termina antes que el envío
+	ctx = context.WithoutCancel(ctx)
+	go func() {
+		if err := s.sendReset(ctx, email); err != nil {
+			log.Printf("failed to send password reset email to %q: %v", email, err)
+		}
+	}()
+}
+
+// sendReset manda el enlace de recuperación. Para un email desconocido, una cuenta
+// inactiva o una que entra por SSO no hace nada.
+func (s *AccountService) sendReset(ctx context.Context, email string) error {
+	if s.authSvc.SSOProvider(email) != "" {
+		return nil
+	}
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.Active != nil && !*user.Active {
+		return nil
+	}
+
+	// Solo sirve el último enlace pedido
+	if err := s.tokenRepo.DeleteByUser(ctx, user.ID, domain.TokenPurposePasswordReset); err != nil {
+		return err
+	}
+	token, err := s.issue(ctx, user, domain.TokenPurposePasswordReset, s.resetTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, &domain.Mail{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"We received a request to reset your password. Open this link to choose a new one:\n\n"+
+			"%s\n\n"+
+			"The link expires in %s and works only once. If you did not ask for it, ignore this email.\n",
+			user.Name, s.link("/reset-password", token), s.resetTTL),
+	})
+}
+
+// ResetPassword cambia la contraseña con el token del email. Cierra todas las sesiones del
+// usuario y, como el token llegó a su casilla, da el email por verificado. Si el usuario
+// cambió de email después de pedirlo, el token no sirve.
+func (s *AccountService) ResetPassword(ctx context.Context, token, password string) error {
+	if err := validator.ValidatePassword(password); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidPassword, err)
+	}
+
+	stored, err := s.tokenRepo.Consume(ctx, domain.TokenPurposePasswordReset, hashToken(token))
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.GetByID(ctx, stored.UserID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+	if user.Email != stored.Email {
+		return domain.ErrInvalidToken
+	}
+
+	updates := &domain.User{ID: user.ID}
+	if err := updates.Password.Set(password); err != nil {
+		return fmt.Errorf("error al hashear la contraseña: %w", err)
+	}
+	if err := s.userRepo.Update(ctx, updates); err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		if err := s.userRepo.SetEmailVerifiedAt(ctx, user.ID, &now); err != nil {
+			return err
+		}
+	}
+	log.Printf("password reset for user %d", user.ID)
+	return s.authSvc.LogoutAll(ctx, user.ID)
+}
+
+// ChangePassword cambia la contraseña del usuario autenticado, que tiene que dar la actual.
+// Una contraseña actual equivocada cuenta como login fallido, así que quien tenga un token
+// robado no puede probar contraseñas sin límite. Cierra las demás sesiones del usuario
+// y deja abierta sessionID, la de la petición.
+func (s *AccountService) ChangePassword(ctx context.Context, user *domain.User, current, password, ip string, sessionID uint) error {
+	if provider := s.authSvc.SSOProvider(user.Email); provider != "" {
+		return fmt.Errorf("%w: the password is managed by %s", domain.ErrSSORequired, provider)
+	}
+	if err := s.authSvc.Guard.Check(ctx, user.Email, ip); err != nil {
+		return err
+	}
+	if err := user.Password.Compare(current); err != nil {
+		s.authSvc.loginFailed(ctx, user.Email, ip, &user.ID, "wrong current password")
+		return fmt.Errorf("%w: current password is incorrect", domain.ErrInvalidPassword)
+	}
+	if current == password {
+		return fmt.Errorf("%w: the new password must be different", domain.ErrInvalidPassword)
+	}
+	if err := validator.ValidatePassword(password); err != nil {
+		return fmt.Errorf("%w: %v", domain.ErrInvalidPassword, err)
+	}
+
+	updates := &domain.User{ID: user.ID}
+	if err := updates.Password.Set(password); err != nil {
+		return fmt.Errorf("error al hashear la contraseña: %w", err)
+	}
+	if err := s.userRepo.Update(ctx, updates); err != nil {
+		return err
+	}
+	s.authSvc.Guard.Record(ctx, &domain.SecurityEvent{
+		Type:   domain.SecurityEventPasswordChanged,
+		UserID: &user.ID,
+		Email:  user.Email,
+		IP:     ip,
+	})
+	return s.authSvc.LogoutOthers(ctx, user.ID, sessionID)
+}
+
+// RevokePasswordResets invalida los enlaces de recuperación pendientes del usuario.
+func (s *AccountService) RevokePasswordResets(ctx context.Context, userID uint) error {
+	return s.tokenRepo.DeleteByUser(ctx, userID, domain.TokenPurposePasswordReset)
+}
+
+// SendVerification manda al usuario el enlace para verificar su email.
+func (s *AccountService) SendVerification(ctx context.Context, user *domain.User) error {
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	if err := s.tokenRepo.DeleteByUser(ctx, user.ID, domain.TokenPurposeEmailVerification); err != nil {
+		return err
+	}
+	token, err := s.issue(ctx, user, domain.TokenPurposeEmailVerification, s.verifyTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, &domain.Mail{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Please confirm that this is your email address by opening this link:\n\n"+
+			"%s\n\n"+
+			"The link expires in %s.\n",
+			user.Name, s.link("/verify-email", token), s.verifyTTL),
+	})
+}
+
+// VerifyEmail marca el email como verificado con el token del email. Si el usuario cambió
+// de email después de recibirlo, el token no sirve.
+func (s *AccountService) VerifyEmail(ctx context.Context, token string) error {
+	stored, err := s.tokenRepo.Consume(ctx, domain.TokenPurposeEmailVerification, hashToken(token))
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.GetByID(ctx, stored.UserID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.ErrInvalidToken
+	}
+	if err != nil {
+		return err
+	}
+	if user.Email != stored.Email {
+		return domain.ErrInvalidToken
+	}
+	if user.EmailVerifiedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	return s.userRepo.SetEmailVerifiedAt(ctx, user.ID, &now)
+}
+
+// PurgeExpiredTokens borra los tokens vencidos o ya usados.
+func (s *AccountService) PurgeExpiredTokens(ctx context.Context) (int64, error) {
+	return s.tokenRepo.DeleteExpired(ctx, time.Now())
+}
+
+func (s *AccountService) issue(ctx context.Context, user *domain.User, purpose string, ttl time.Duration) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	if err := s.tokenRepo.Create(ctx, &domain.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		return "", fmt.Errorf("failed to store %s token: %w", purpose, err)
+	}
+	return token, nil
+}
+
+func (s *AccountService) link(path, token string) string {
+	return s.baseURL + path + "?token=" + url.QueryEscape(token)
+}
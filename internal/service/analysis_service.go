@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+)
+
+// AnalysisService sugiere los datos de una transacción a partir del comprobante antes de
+// crearla. El archivo solo se lee: no se guarda y un upload analizado sigue disponible
+// para adjuntarlo después.
+type AnalysisService struct {
+	extractor   domain.ReceiptExtractor
+	uploadRepo  domain.UploadRepo
+	fileStorage domain.FileStorage
+}
+
+func NewAnalysisService(
+	e domain.ReceiptExtractor,
+	u domain.UploadRepo,
+	fS domain.FileStorage,
+) *AnalysisService {
+	return &AnalysisService{
+		extractor:   e,
+		uploadRepo:  u,
+		fileStorage: fS,
+	}
+}
+
+// AnalyzeFile analiza un PDF recibido en el request (un multipart.File sirve como r).
+func (s *AnalysisService) AnalyzeFile(ctx context.Context, r io.ReaderAt, size int64) (*domain.ReceiptAnalysis, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("%w: empty file", domain.ErrInvalidPDF)
+	}
+	return s.extractor.Extract(ctx, r, size)
+}
+
+// AnalyzeUpload analiza el archivo de un upload reanudable completado del usuario.
+func (s *AnalysisService) AnalyzeUpload(ctx context.Context, userID uint, uploadID string) (*domain.ReceiptAnalysis, error) {
+	upload, err := s.uploadRepo.GetByID(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.UserID != userID || time.Now().After(upload.ExpiresAt) {
+		return nil, domain.ErrNotFound
+	}
+	if upload.CompletedAt == nil {
+		return nil, domain.ErrUploadIncomplete
+	}
+	if upload.Quarantined {
+		return nil, domain.ErrQuarantined
+	}
+
+	file, err := s.fileStorage.OpenPDF(ctx, upload.RelPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	// El extractor necesita acceso aleatorio y el storage solo da un stream
+	tmp, err := os.CreateTemp("", "gestor-analyze-*.pdf")
+	if err != nil {
+		return nil, fmt.Errorf("cannot create temp file: %w", err)
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+	size, err := io.Copy(tmp, file)
+	if err != nil {
+		return nil, err
+	}
+	return s.AnalyzeFile(ctx, tmp, size)
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+)
+
+const (
+	// apiKeyMaxPerUser es cuántas API keys vigentes puede tener un usuario.
+	apiKeyMaxPerUser = 20
+	// apiKeyTouchInterval evita escribir en la base en cada request: el último uso se
+	// actualiza si pasó este tiempo o si la key llega desde otra IP.
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIKeyService gestiona las API keys (personal access tokens) con las que los scripts y
+// las integraciones se autentican sin guardar la contraseña del usuario.
+type APIKeyService struct {
+	repo       domain.APIKeyRepo
+	userRepo   domain.UserRepo
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+}
+
+// NewAPIKeyService crea el servicio; las keys sin vencimiento pedido duran defaultTTL y
+// ninguna puede durar más de maxTTL.
+func NewAPIKeyService(repo domain.APIKeyRepo, userRepo domain.UserRepo, defaultTTL, maxTTL time.Duration) *APIKeyService {
+	return &APIKeyService{
+		repo:       repo,
+		userRepo:   userRepo,
+		defaultTTL: defaultTTL,
+		maxTTL:     maxTTL,
+	}
+}
+
+// Create emite una key para el usuario y devuelve también el secreto, que no se guarda y
+// no se vuelve a mostrar. ttl 0 usa el vencimiento por defecto.
+func (s *APIKeyService) Create(ctx context.Context, user *domain.User, name string, scopes []string, ttl time.Duration) (*domain.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return nil, "", fmt.Errorf("%w: name is required and must be at most 100 characters", domain.ErrInvalidInput)
+	}
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("%w: at least one scope is required", domain.ErrInvalidInput)
+	}
+	for _, scope := range scopes {
+		if !domain.IsValidAPIKeyScope(scope) {
+			return nil, "", fmt.Errorf("%w: unknown scope %q", domain.ErrInvalidInput, scope)
+		}
+	}
+	if ttl == 0 {
+		ttl = s.defaultTTL
+	}
+	if ttl < 0 || ttl > s.maxTTL {
+		return nil, "", fmt.Errorf("%w: expiration must be between now and %s", domain.ErrInvalidInput, s.maxTTL)
+	}
+
+	count, err := s.repo.CountUsable(ctx, user.ID, time.Now())
+	if err != nil {
+		return nil, "", err
+	}
+	if count >= apiKeyMaxPerUser {
+		return nil, "", fmt.Errorf("%w: at most %d active api keys per user, revoke one first", domain.ErrInvalidInput, apiKeyMaxPerUser)
+	}
+
+	random, err := randomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	secret := domain.APIKeyPrefix + random
+	scopes = slices.Clone(scopes)
+	slices.Sort(scopes)
+	key := &domain.APIKey{
+		UserID:    user.ID,
+		Name:      name,
+		Prefix:    secret[:12],
+		TokenHash: hashToken(secret),
+		Scopes:    slices.Compact(scopes),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, "", fmt.Errorf("failed to store api key: %w", err)
+	}
+	log.Printf("api key %d (%s) created for user %d with scopes %v", key.ID, key.Prefix, user.ID, key.Scopes)
+	return key, secret, nil
+}
+
+// List devuelve las keys del usuario, vigentes o no, sin el secreto.
+func (s *APIKeyService) List(ctx context.Context, userID uint) ([]domain.APIKey, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+// Revoke revoca la key id. Con ownerID distinto de cero solo revoca keys de ese usuario;
+// un admin pasa 0 para revocar cualquiera.
+func (s *APIKeyService) Revoke(ctx context.Context, ownerID, id uint) error {
+	key, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if ownerID != 0 && key.UserID != ownerID {
+		return domain.ErrNotFound
+	}
+	if _, err := s.repo.Revoke(ctx, id); err != nil {
+		return err
+	}
+	log.Printf("api key %d (%s) of user %d revoked", key.ID, key.Prefix, key.UserID)
+	return nil
+}
+
+// Authenticate valida una key presentada como Bearer y devuelve la key y su usuario.
+// Anota el último uso y la IP. Las keys de usuarios inactivos dan ErrAccountDisabled.
+func (s *APIKeyService) Authenticate(ctx context.Context, secret, ip string) (*domain.APIKey, *domain.User, error) {
+	key, err := s.repo.GetByHash(ctx, hashToken(secret))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, nil, domain.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	if !key.Usable(now) {
+		return nil, nil, domain.ErrInvalidToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, key.UserID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, nil, domain.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if user.Active != nil && !*user.Active {
+		return nil, nil, domain.ErrAccountDisabled
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval || key.LastUsedIP != ip {
+		if err := s.repo.Touch(ctx, key.ID, now, ip); err != nil {
+			log.Printf("failed to record use of api key %d: %v", key.ID, err)
+		}
+	}
+	return key, user, nil
+}
@@ -2,7 +2,14 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
 	"github.com/SaidMg10/gestor-one/internal/auth"
@@ -10,100 +17,496 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// mfaTokenType marca el token intermedio del login con 2FA; no sirve como access token.
+const mfaTokenType = "mfa"
+
+// sessionSeenInterval evita escribir en la base en cada request: la última actividad de
+// la sesión se actualiza si pasó este tiempo o si llega desde otra IP.
+const sessionSeenInterval = time.Minute
+
+// LoginResult es el resultado de un login. Si el usuario tiene (o su rol exige) 2FA, en
+// lugar de tokens trae MFAToken, que se canjea junto con el código; MFAEnrollment indica
+// que antes hay que dar de alta el 2FA. RecoveryCodes solo viene al terminar ese alta.
+type LoginResult struct {
+	AccessToken   string
+	RefreshToken  string
+	MFAToken      string
+	MFAEnrollment bool
+	RecoveryCodes []string
+}
+
 type AuthService struct {
 	UserRepo      domain.UserRepo
+	TokenRepo     domain.RefreshTokenRepo
+	SessionRepo   domain.SessionRepo
 	Authenticator auth.Authenticator
+	TwoFactor     *TwoFactorService
+	Guard         *LoginGuardService
 	AccessTTL     time.Duration
 	RefreshTTL    time.Duration
+	MFATTL        time.Duration
 	Issuer        string
+
+	// dominio de email -> proveedor OIDC por el que tienen que entrar esas cuentas.
+	// Se carga al arrancar con RequireSSO y después solo se lee.
+	ssoDomains map[string]string
 }
 
 func NewAuthService(
 	userRepo domain.UserRepo,
+	tokenRepo domain.RefreshTokenRepo,
+	sessionRepo domain.SessionRepo,
 	authenticator auth.Authenticator,
+	twoFactor *TwoFactorService,
+	guard *LoginGuardService,
 	accessTTL time.Duration,
 	refreshTTL time.Duration,
+	mfaTTL time.Duration,
 	issuer string,
 ) *AuthService {
 	return &AuthService{
 		UserRepo:      userRepo,
+		TokenRepo:     tokenRepo,
+		SessionRepo:   sessionRepo,
 		Authenticator: authenticator,
+		TwoFactor:     twoFactor,
+		Guard:         guard,
 		AccessTTL:     accessTTL,
 		RefreshTTL:    refreshTTL,
+		MFATTL:        mfaTTL,
 		Issuer:        issuer,
 	}
 }
 
-// Login is the method to authenticate a user and generate tokens
-func (s *AuthService) Login(ctx context.Context, email, password string) (string, string, error) {
+// Login is the method to authenticate a user and generate tokens. The session is
+// recorded with the client's ip and user agent.
+// Accounts whose email domain is enforced to an SSO provider get ErrSSORequired.
+// While the account or the client ip is throttled it returns a *domain.ThrottledError
+// without checking the password; inactive users get ErrAccountDisabled.
+// Users with two-factor authentication (or whose role requires it) get an MFA token
+// instead, to be completed with CompleteLogin or CompleteEnrollment.
+func (s *AuthService) Login(ctx context.Context, email, password string, client domain.ClientInfo) (*LoginResult, error) {
+	if provider := s.SSOProvider(email); provider != "" {
+		return nil, fmt.Errorf("%w: use %s", domain.ErrSSORequired, provider)
+	}
+	ip := client.IP
+	if err := s.Guard.Check(ctx, email, ip); err != nil {
+		return nil, err
+	}
+
 	// Buscar usuario
 	user, err := s.UserRepo.GetByEmail(ctx, email)
+	if errors.Is(err, domain.ErrNotFound) {
+		s.loginFailed(ctx, email, ip, nil, "unknown email")
+		return nil, err
+	}
 	if err != nil {
-		return "", "", err
+		return nil, err
 	}
 
 	// Comparar contraseña
 	if err := user.Password.Compare(password); err != nil {
+		s.loginFailed(ctx, email, ip, &user.ID, "wrong password")
+		return nil, err
+	}
+	if user.Active != nil && !*user.Active {
+		s.Guard.Record(ctx, &domain.SecurityEvent{
+			Type:   domain.SecurityEventInactiveLogin,
+			UserID: &user.ID,
+			Email:  user.Email,
+			IP:     ip,
+		})
+		return nil, domain.ErrAccountDisabled
+	}
+
+	return s.secondFactor(ctx, user, client)
+}
+
+// ExternalLogin finishes a login in which an external identity provider already
+// authenticated the user. It goes through the same two-factor step as Login: users with
+// 2FA (or whose role requires it) get an MFA token instead of a session.
+func (s *AuthService) ExternalLogin(ctx context.Context, user *domain.User, client domain.ClientInfo) (*LoginResult, error) {
+	return s.secondFactor(ctx, user, client)
+}
+
+// CompleteLogin finishes a two-step login with a TOTP or recovery code. Wrong codes
+// count as failed logins of the account, like wrong passwords.
+func (s *AuthService) CompleteLogin(ctx context.Context, mfaToken, code string, client domain.ClientInfo) (*LoginResult, error) {
+	user, err := s.MFAUser(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Guard.Check(ctx, user.Email, client.IP); err != nil {
+		return nil, err
+	}
+	if err := s.TwoFactor.Verify(ctx, user.ID, code); err != nil {
+		if errors.Is(err, domain.ErrInvalidCode) {
+			s.loginFailed(ctx, user.Email, client.IP, &user.ID, "invalid two-factor code")
+		}
+		return nil, err
+	}
+	return s.loginSession(ctx, user, client)
+}
+
+// StartEnrollment begins the 2FA enrolment of a user whose role requires it and who
+// only has an MFA token. It returns the secret and the otpauth:// URI for the QR code.
+func (s *AuthService) StartEnrollment(ctx context.Context, mfaToken string) (string, string, error) {
+	user, err := s.MFAUser(ctx, mfaToken)
+	if err != nil {
 		return "", "", err
 	}
+	return s.TwoFactor.Setup(ctx, user)
+}
 
-	// === ACCESS TOKEN ===
-	accessClaims := jwt.MapClaims{
-		"sub": user.ID,
-		"rol": user.Role,
-		"exp": time.Now().Add(s.AccessTTL).Unix(),
-		"iat": time.Now().Unix(),
-		"iss": s.Issuer,
-		"aud": s.Issuer,
+// CompleteEnrollment confirms the enrolment started with StartEnrollment and signs the
+// user in. The result carries the recovery codes, which are shown only this once.
+func (s *AuthService) CompleteEnrollment(ctx context.Context, mfaToken, code string, client domain.ClientInfo) (*LoginResult, error) {
+	user, err := s.MFAUser(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Guard.Check(ctx, user.Email, client.IP); err != nil {
+		return nil, err
+	}
+	codes, err := s.TwoFactor.Confirm(ctx, user.ID, code)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCode) {
+			s.loginFailed(ctx, user.Email, client.IP, &user.ID, "invalid two-factor code")
+		}
+		return nil, err
+	}
+	result, err := s.loginSession(ctx, user, client)
+	if err != nil {
+		return nil, err
 	}
+	result.RecoveryCodes = codes
+	return result, nil
+}
 
-	accessToken, err := s.Authenticator.GenerateToken(accessClaims)
+// MFAUser returns the user of a valid MFA token. Users deactivated since the password
+// step get ErrAccountDisabled.
+func (s *AuthService) MFAUser(ctx context.Context, mfaToken string) (*domain.User, error) {
+	token, err := s.Authenticator.ValidateToken(mfaToken)
+	if err != nil {
+		return nil, domain.ErrInvalidToken
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, domain.ErrInvalidToken
+	}
+	if typ, _ := claims["typ"].(string); typ != mfaTokenType {
+		return nil, domain.ErrInvalidToken
+	}
+	sub, ok := claims["sub"].(float64)
+	if !ok {
+		return nil, domain.ErrInvalidToken
+	}
+	user, err := s.UserRepo.GetByID(ctx, uint(sub))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, domain.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.Active != nil && !*user.Active {
+		return nil, domain.ErrAccountDisabled
+	}
+	return user, nil
+}
+
+// RequireSSO makes every account with an email in emailDomain sign in through the OIDC
+// provider instead of with a password. It must be called before serving requests.
+func (s *AuthService) RequireSSO(emailDomain, provider string) {
+	if s.ssoDomains == nil {
+		s.ssoDomains = make(map[string]string)
+	}
+	s.ssoDomains[strings.ToLower(emailDomain)] = provider
+}
+
+// SSOProvider returns the provider enforced for the email's domain, or "" if the account
+// may sign in with a password.
+func (s *AuthService) SSOProvider(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return s.ssoDomains[strings.ToLower(email[at+1:])]
+}
+
+// IssueSession issues an access token and a refresh token of a new family for a user
+// that was already authenticated (password, external identity provider...), and records
+// the new session with the client's ip and user agent.
+func (s *AuthService) IssueSession(ctx context.Context, user *domain.User, client domain.ClientInfo) (string, string, error) {
+	familyID, err := randomToken(16)
 	if err != nil {
 		return "", "", err
 	}
+	session, err := s.createSession(ctx, user.ID, familyID, client)
+	if err != nil {
+		return "", "", err
+	}
+	return s.issueTokens(ctx, user, familyID, session.ID)
+}
 
-	// === REFRESH TOKEN ===
-	refreshClaims := jwt.MapClaims{
-		"sub": user.ID,
-		"typ": "refresh",
-		"exp": time.Now().Add(s.RefreshTTL).Unix(),
-		"iat": time.Now().Unix(),
-		"iss": s.Issuer,
-		"aud": s.Issuer,
+// Refresh rotates a refresh token: it returns a new access token and a new refresh token
+// of the same family, and the one presented stops working. If a token that was already
+// rotated comes back, someone else has a copy: the whole family is revoked and
+// ErrTokenReused is returned, which logs out both the thief and the legitimate user.
+// The session's last activity, ip and user agent are updated. Deactivated users get
+// ErrAccountDisabled and lose the session.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string, client domain.ClientInfo) (string, string, error) {
+	stored, err := s.TokenRepo.GetByHash(ctx, hashToken(refreshToken))
+	if errors.Is(err, domain.ErrNotFound) {
+		return "", "", domain.ErrInvalidToken
+	}
+	if err != nil {
+		return "", "", err
 	}
 
-	refreshToken, err := s.Authenticator.GenerateToken(refreshClaims)
+	if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return "", "", domain.ErrInvalidToken
+	}
+	if stored.UsedAt != nil {
+		return "", "", s.revokeReused(ctx, stored)
+	}
+	// Marcado condicional: de dos refresh simultáneos con el mismo token gana uno solo
+	ok, err := s.TokenRepo.MarkUsed(ctx, stored.ID)
 	if err != nil {
 		return "", "", err
 	}
+	if !ok {
+		return "", "", s.revokeReused(ctx, stored)
+	}
 
-	return accessToken, refreshToken, nil
+	user, err := s.UserRepo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		return "", "", err
+	}
+	if user.Active != nil && !*user.Active {
+		if err := s.revokeFamily(ctx, stored.FamilyID); err != nil {
+			return "", "", err
+		}
+		return "", "", domain.ErrAccountDisabled
+	}
+
+	now := time.Now()
+	session, err := s.SessionRepo.GetByFamily(ctx, stored.FamilyID)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		// Familia emitida antes de que existieran las sesiones
+		session, err = s.createSession(ctx, user.ID, stored.FamilyID, client)
+		if err != nil {
+			return "", "", err
+		}
+	case err != nil:
+		return "", "", err
+	case !session.Active(now):
+		return "", "", domain.ErrInvalidToken
+	default:
+		device := deviceName(client.UserAgent)
+		client.UserAgent = truncate(client.UserAgent, 255)
+		if err := s.SessionRepo.Extend(ctx, session.ID, now, device, client, now.Add(s.RefreshTTL)); err != nil {
+			return "", "", fmt.Errorf("failed to update session: %w", err)
+		}
+	}
+	return s.issueTokens(ctx, user, stored.FamilyID, session.ID)
 }
 
-// Refresh is the method to refresh an access token using a valid refresh token
-func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (string, error) {
-	// Validar refresh
-	token, err := s.Authenticator.ValidateToken(refreshToken)
+// Logout revokes the family of the given refresh token (this device/session).
+// Unknown or already revoked tokens are not an error.
+func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+	stored, err := s.TokenRepo.GetByHash(ctx, hashToken(refreshToken))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil
+	}
 	if err != nil {
-		return "", err
+		return err
 	}
+	return s.revokeFamily(ctx, stored.FamilyID)
+}
 
-	claims := token.Claims.(jwt.MapClaims)
+// LogoutAll revokes every session and refresh token of the user. Access tokens already
+// issued stop working too, since their session is checked on every request.
+func (s *AuthService) LogoutAll(ctx context.Context, userID uint) error {
+	if err := s.SessionRepo.RevokeUser(ctx, userID); err != nil {
+		return err
+	}
+	return s.TokenRepo.RevokeUser(ctx, userID)
+}
+
+// LogoutOthers revokes every session and refresh token of the user except the session
+// keepSessionID, which must belong to the user.
+func (s *AuthService) LogoutOthers(ctx context.Context, userID, keepSessionID uint) error {
+	keep, err := s.SessionRepo.GetByID(ctx, keepSessionID)
+	if err != nil {
+		return err
+	}
+	if keep.UserID != userID {
+		return domain.ErrNotFound
+	}
+	if err := s.SessionRepo.RevokeOthers(ctx, userID, keep.FamilyID); err != nil {
+		return err
+	}
+	return s.TokenRepo.RevokeOthers(ctx, userID, keep.FamilyID)
+}
 
-	if claims["typ"] != "refresh" {
-		return "", fmt.Errorf("invalid token type")
+// ActiveSession checks the session an access token belongs to. Revoked, expired or
+// unknown sessions, or sessions of another user, get ErrInvalidToken. It records the
+// session's last activity and ip.
+func (s *AuthService) ActiveSession(ctx context.Context, sessionID, userID uint, ip string) (*domain.Session, error) {
+	session, err := s.SessionRepo.GetByID(ctx, sessionID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, domain.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if session.UserID != userID || !session.Active(now) {
+		return nil, domain.ErrInvalidToken
 	}
 
-	userID := uint(claims["sub"].(float64))
+	if now.Sub(session.LastSeenAt) >= sessionSeenInterval || session.IP != ip {
+		if err := s.SessionRepo.Seen(ctx, session.ID, now, ip); err != nil {
+			log.Printf("failed to record activity of session %d: %v", session.ID, err)
+		}
+	}
+	return session, nil
+}
 
-	user, err := s.UserRepo.GetByID(ctx, userID)
+// Sessions returns the active sessions of the user, most recently used first.
+func (s *AuthService) Sessions(ctx context.Context, userID uint) ([]domain.Session, error) {
+	return s.SessionRepo.ListActive(ctx, userID, time.Now())
+}
+
+// RevokeSession revokes session id and its refresh tokens; its access tokens are rejected
+// from then on. With a non-zero ownerID only sessions of that user can be revoked; an
+// admin passes 0 to revoke any.
+func (s *AuthService) RevokeSession(ctx context.Context, ownerID, id uint) error {
+	session, err := s.SessionRepo.GetByID(ctx, id)
 	if err != nil {
-		return "", err
+		return err
+	}
+	if ownerID != 0 && session.UserID != ownerID {
+		return domain.ErrNotFound
+	}
+	if err := s.revokeFamily(ctx, session.FamilyID); err != nil {
+		return err
 	}
+	log.Printf("session %d of user %d revoked", session.ID, session.UserID)
+	return nil
+}
+
+// PurgeExpiredTokens deletes refresh tokens that expired before now.
+func (s *AuthService) PurgeExpiredTokens(ctx context.Context) (int64, error) {
+	return s.TokenRepo.DeleteExpired(ctx, time.Now())
+}
+
+// PurgeExpiredSessions deletes sessions that expired before now.
+func (s *AuthService) PurgeExpiredSessions(ctx context.Context) (int64, error) {
+	return s.SessionRepo.DeleteExpired(ctx, time.Now())
+}
 
-	// Generar nuevo access
-	newAccessClaims := jwt.MapClaims{
+func (s *AuthService) revokeReused(ctx context.Context, stored *domain.RefreshToken) error {
+	if err := s.revokeFamily(ctx, stored.FamilyID); err != nil {
+		return fmt.Errorf("failed to revoke reused token family: %w", err)
+	}
+	return domain.ErrTokenReused
+}
+
+// revokeFamily revoca la sesión y los refresh tokens de una familia.
+func (s *AuthService) revokeFamily(ctx context.Context, familyID string) error {
+	if err := s.SessionRepo.RevokeFamily(ctx, familyID); err != nil {
+		return err
+	}
+	return s.TokenRepo.RevokeFamily(ctx, familyID)
+}
+
+// createSession registra la sesión de una familia nueva.
+func (s *AuthService) createSession(ctx context.Context, userID uint, familyID string, client domain.ClientInfo) (*domain.Session, error) {
+	now := time.Now()
+	session := &domain.Session{
+		UserID:     userID,
+		FamilyID:   familyID,
+		Device:     deviceName(client.UserAgent),
+		IP:         client.IP,
+		UserAgent:  truncate(client.UserAgent, 255),
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.RefreshTTL),
+	}
+	if err := s.SessionRepo.Create(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to store session: %w", err)
+	}
+	return session, nil
+}
+
+// session emite los tokens de una sesión nueva como LoginResult.
+func (s *AuthService) session(ctx context.Context, user *domain.User, client domain.ClientInfo) (*LoginResult, error) {
+	accessToken, refreshToken, err := s.IssueSession(ctx, user, client)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResult{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+// secondFactor sigue un login con el primer factor ya validado: si el usuario tiene (o su
+// rol exige) 2FA devuelve el token intermedio; si no, emite la sesión.
+func (s *AuthService) secondFactor(ctx context.Context, user *domain.User, client domain.ClientInfo) (*LoginResult, error) {
+	enabled, err := s.TwoFactor.Enabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	required, err := s.TwoFactor.Required(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	if enabled || required {
+		mfaToken, err := s.mfaToken(user)
+		if err != nil {
+			return nil, err
+		}
+		// Los fallos de la cuenta se limpian recién cuando el segundo factor también pasa
+		return &LoginResult{MFAToken: mfaToken, MFAEnrollment: !enabled}, nil
+	}
+	return s.loginSession(ctx, user, client)
+}
+
+// loginSession cierra un login completo: limpia los fallos de la cuenta y emite la sesión.
+func (s *AuthService) loginSession(ctx context.Context, user *domain.User, client domain.ClientInfo) (*LoginResult, error) {
+	if err := s.Guard.Success(ctx, user.Email); err != nil {
+		return nil, err
+	}
+	return s.session(ctx, user, client)
+}
+
+// loginFailed anota el intento fallido. Si no se puede guardar el login igual falla,
+// así que solo queda en el log.
+func (s *AuthService) loginFailed(ctx context.Context, email, ip string, userID *uint, reason string) {
+	if err := s.Guard.Failure(ctx, email, ip, userID, reason); err != nil {
+		log.Printf("failed to record failed login for %q: %v", email, err)
+	}
+}
+
+// mfaToken firma el token intermedio que prueba que la contraseña ya se validó.
+func (s *AuthService) mfaToken(user *domain.User) (string, error) {
+	return s.Authenticator.GenerateToken(jwt.MapClaims{
+		"sub": user.ID,
+		"typ": mfaTokenType,
+		"exp": time.Now().Add(s.MFATTL).Unix(),
+		"iat": time.Now().Unix(),
+		"iss": s.Issuer,
+		"aud": s.Issuer,
+	})
+}
+
+// issueTokens firma un access token de la sesión y emite un refresh token nuevo de la
+// familia indicada. El refresh token es un valor aleatorio opaco; en la base solo queda
+// su hash.
+func (s *AuthService) issueTokens(ctx context.Context, user *domain.User, familyID string, sessionID uint) (string, string, error) {
+	// === ACCESS TOKEN ===
+	accessClaims := jwt.MapClaims{
 		"sub": user.ID,
+		"sid": sessionID,
 		"rol": user.Role,
 		"exp": time.Now().Add(s.AccessTTL).Unix(),
 		"iat": time.Now().Unix(),
@@ -111,10 +514,40 @@ func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (string,
 		"aud": s.Issuer,
 	}
 
-	accessToken, err := s.Authenticator.GenerateToken(newAccessClaims)
+	accessToken, err := s.Authenticator.GenerateToken(accessClaims)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 
-	return accessToken, nil
+	// === REFRESH TOKEN ===
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.TokenRepo.Create(ctx, &domain.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.RefreshTTL),
+	}); err != nil {
+		return "", "", fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// randomToken devuelve n bytes aleatorios en base64 URL-safe.
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("cannot generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken es el hash con el que se guardan los tokens. Son aleatorios de 256 bits,
+// así que alcanza con SHA-256 sin sal.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
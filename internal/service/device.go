@@ -0,0 +1,69 @@
+package service
+
+import "strings"
+
+// Navegadores y sistemas reconocidos en el user agent, en orden: Edge y Opera también
+// dicen Chrome, y Chrome también dice Safari.
+var (
+	uaBrowsers = []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"CriOS/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+		{"PostmanRuntime/", "Postman"},
+		{"okhttp/", "Android app"},
+	}
+	uaSystems = []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// deviceName describe el dispositivo de un user agent para la lista de sesiones, por
+// ejemplo "Firefox on Windows". No busca ser exacto: el user agent completo también
+// queda en la sesión.
+func deviceName(userAgent string) string {
+	var browser, system string
+	for _, b := range uaBrowsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, s := range uaSystems {
+		if strings.Contains(userAgent, s.token) {
+			system = s.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	case strings.TrimSpace(userAgent) == "":
+		return "Unknown device"
+	}
+	// Cliente no reconocido: el producto del user agent ("MyScript/1.2" -> "MyScript")
+	product, _, _ := strings.Cut(strings.Fields(userAgent)[0], "/")
+	return truncate(product, 100)
+}
+
+// truncate corta s a max bytes sin dejar un carácter UTF-8 a medias.
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return strings.ToValidUTF8(s[:max], "")
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log"
 	"mime/multipart"
 	"time"
 
@@ -12,13 +14,22 @@ import (
 
 type ExpenseService struct {
 	expenseRepo domain.ExpenseRepo
+	uploadRepo  domain.UploadRepo
 	fileStorage domain.FileStorage
+	watermarker *ReceiptWatermarker
 }
 
-func NewExpenseService(e domain.ExpenseRepo, fS domain.FileStorage) *ExpenseService {
+func NewExpenseService(
+	e domain.ExpenseRepo,
+	u domain.UploadRepo,
+	fS domain.FileStorage,
+	wm *ReceiptWatermarker,
+) *ExpenseService {
 	return &ExpenseService{
 		expenseRepo: e,
+		uploadRepo:  u,
 		fileStorage: fS,
+		watermarker: wm,
 	}
 }
 
@@ -27,6 +38,7 @@ func (s *ExpenseService) Create(
 	expense *domain.Expense,
 	file multipart.File,
 	fileHeader *multipart.FileHeader,
+	uploadID string,
 ) error {
 	if expense == nil {
 		return errors.New("expense cannot be nil")
@@ -50,24 +62,34 @@ func (s *ExpenseService) Create(
 		expense.Date = time.Now()
 	}
 
-	fileName, checksum, relPath, err := s.fileStorage.SavePDF(fileHeader)
+	// Sin comprobante (p. ej. un cargo de tarjeta antes de la factura) queda pendiente
+	if (file == nil || fileHeader == nil) && uploadID == "" {
+		expense.ReceiptPending = true
+		if err := s.expenseRepo.CreateWithReceipt(ctx, expense, nil); err != nil {
+			return fmt.Errorf("failed to create expense: %w", err)
+		}
+		return nil
+	}
+
+	stored, upload, err := saveReceiptFile(ctx, s.fileStorage, s.uploadRepo, file, fileHeader, uploadID, expense.CreatedBy)
 	if err != nil {
-		return fmt.Errorf("failed to save pdf: %w", err)
+		return err
 	}
 
 	receipt := &domain.Receipt{
-		FileName:   fileName,
-		RelPath:    relPath,
+		FileName:   stored.FileName,
+		RelPath:    stored.RelPath,
 		MimeType:   "application/pdf",
 		UploadedBy: expense.CreatedBy,
-		Checksum:   checksum,
+		Checksum:   stored.Checksum,
+		KeyID:      stored.KeyID,
 	}
+	applyInspection(receipt, stored)
 
 	err = s.expenseRepo.CreateWithReceipt(ctx, expense, receipt)
 	if err != nil {
-		if rmErr := s.fileStorage.DeletePDF(relPath); rmErr != nil {
-			fmt.Printf("failded to remove file after tx error: %v", rmErr)
-		}
+		// Si DB falla, eliminar archivo (o devolver el upload) para evitar basura
+		rollbackReceiptFile(ctx, s.fileStorage, s.uploadRepo, stored, upload)
 		return fmt.Errorf("failed to create expense with receipt: %w", err)
 	}
 	return nil
@@ -77,16 +99,52 @@ func (s *ExpenseService) GetByID(ctx context.Context, id uint) (*domain.Expense,
 	return s.expenseRepo.GetByID(ctx, id)
 }
 
+// OpenReceipt devuelve el expense junto con un lector del archivo del comprobante.
+// Solo puede abrirlo quien puede ver el movimiento (domain.CanViewTransaction); si el rol
+// de user tiene configurada marca de agua, el lector entrega una copia marcada.
+// El llamador es responsable de cerrar el lector.
+func (s *ExpenseService) OpenReceipt(ctx context.Context, id uint, user *domain.User) (*domain.Expense, io.ReadCloser, error) {
+	expense, err := s.expenseRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !domain.CanViewTransaction(user, expense.CreatedBy) {
+		return nil, nil, domain.ErrForbidden
+	}
+	if expense.Receipt.RelPath == "" {
+		return nil, nil, domain.ErrNotFound
+	}
+	if expense.Receipt.Quarantined {
+		return nil, nil, domain.ErrQuarantined
+	}
+
+	file, err := s.fileStorage.OpenPDF(ctx, expense.Receipt.RelPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err = s.watermarker.Wrap(file, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return expense, file, nil
+}
+
 func (s *ExpenseService) List(ctx context.Context) ([]domain.Expense, error) {
 	return s.expenseRepo.List(ctx)
 }
 
+// ListDeleted devuelve los gastos de la papelera, los borrados más recientes primero.
+func (s *ExpenseService) ListDeleted(ctx context.Context) ([]domain.Expense, error) {
+	return s.expenseRepo.ListDeleted(ctx)
+}
+
 func (s ExpenseService) Update(
 	ctx context.Context,
 	id uint,
 	partial *domain.Expense,
 	file multipart.File,
 	fileHeader *multipart.FileHeader,
+	uploadID string,
 	userID uint,
 ) error {
 	existing, err := s.expenseRepo.GetByID(ctx, id)
@@ -114,27 +172,36 @@ func (s ExpenseService) Update(
 	}
 
 	var oldFilePath string
+	var oldQuarantined bool
 	var receiptToUpdate *domain.Receipt
+	var stored *domain.StoredFile
+	var upload *domain.Upload
 
-	if fileHeader != nil {
+	if fileHeader != nil || uploadID != "" {
+		if existing.ReceiptPending {
+			return errors.New("receipt is pending for this expense, attach it first")
+		}
 		if existing.Receipt.ID == 0 {
 			return errors.New("receipt not found for this expense")
 		}
 
-		fileName, checksum, relPath, err := s.fileStorage.SavePDF(fileHeader)
+		stored, upload, err = saveReceiptFile(ctx, s.fileStorage, s.uploadRepo, file, fileHeader, uploadID, userID)
 		if err != nil {
-			return fmt.Errorf("failed to save pdf: %w", err)
+			return err
 		}
 
-		if existing.Receipt.Checksum != "" && existing.Receipt.Checksum != checksum {
+		if existing.Receipt.Checksum != "" && existing.Receipt.Checksum != stored.Checksum {
 			oldFilePath = existing.Receipt.RelPath
+			oldQuarantined = existing.Receipt.Quarantined
 		}
 
-		existing.Receipt.FileName = fileName
-		existing.Receipt.RelPath = relPath
+		existing.Receipt.FileName = stored.FileName
+		existing.Receipt.RelPath = stored.RelPath
 		existing.Receipt.MimeType = "application/pdf"
 		existing.Receipt.UploadedBy = userID
-		existing.Receipt.Checksum = checksum
+		existing.Receipt.Checksum = stored.Checksum
+		existing.Receipt.KeyID = stored.KeyID
+		applyInspection(&existing.Receipt, stored)
 
 		receiptToUpdate = &existing.Receipt
 	}
@@ -142,35 +209,91 @@ func (s ExpenseService) Update(
 	err = s.expenseRepo.UpdateWithReceipt(ctx, existing, receiptToUpdate)
 	if err != nil {
 		if receiptToUpdate != nil {
-			if rmErr := s.fileStorage.DeletePDF(receiptToUpdate.RelPath); rmErr != nil {
-				fmt.Printf("failed to remove new receipt after update error: %v", rmErr)
-			}
+			rollbackReceiptFile(ctx, s.fileStorage, s.uploadRepo, stored, upload)
 		}
 		return fmt.Errorf("failed to update expense with receipt: %w", err)
 	}
 
 	if oldFilePath != "" {
-		if rmErr := s.fileStorage.DeletePDF(oldFilePath); rmErr != nil {
-			fmt.Printf("failed to remove old receipt file: %v", rmErr)
+		if rmErr := deleteStoredFile(ctx, s.fileStorage, oldFilePath, oldQuarantined); rmErr != nil {
+			log.Printf("failed to remove old receipt file %s: %v", oldFilePath, rmErr)
 		}
 	}
 	return nil
 }
 
+// AttachReceipt adjunta el comprobante a un gasto creado sin él. Solo puede hacerlo quien
+// lo creó; si el gasto ya tiene comprobante devuelve ErrReceiptAttached (para reemplazarlo
+// está Update).
+func (s *ExpenseService) AttachReceipt(
+	ctx context.Context,
+	id uint,
+	file multipart.File,
+	fileHeader *multipart.FileHeader,
+	uploadID string,
+	userID uint,
+) (*domain.Expense, error) {
+	if (file == nil || fileHeader == nil) && uploadID == "" {
+		return nil, errors.New("receipt file is required")
+	}
+
+	existing, err := s.expenseRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing.CreatedBy != userID {
+		return nil, domain.ErrForbidden
+	}
+	if !existing.ReceiptPending {
+		return nil, domain.ErrReceiptAttached
+	}
+
+	stored, upload, err := saveReceiptFile(ctx, s.fileStorage, s.uploadRepo, file, fileHeader, uploadID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := &domain.Receipt{
+		FileName:   stored.FileName,
+		RelPath:    stored.RelPath,
+		MimeType:   "application/pdf",
+		UploadedBy: userID,
+		Checksum:   stored.Checksum,
+		KeyID:      stored.KeyID,
+	}
+	applyInspection(receipt, stored)
+
+	if err := s.expenseRepo.AttachReceipt(ctx, id, receipt); err != nil {
+		rollbackReceiptFile(ctx, s.fileStorage, s.uploadRepo, stored, upload)
+		if errors.Is(err, domain.ErrReceiptAttached) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to attach receipt: %w", err)
+	}
+
+	existing.ReceiptPending = false
+	existing.ReceiptRemindedAt = nil
+	existing.Receipt = *receipt
+	return existing, nil
+}
+
 func (s *ExpenseService) SoftDelete(ctx context.Context, id uint, userID uint) error {
 	expense, err := s.expenseRepo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
 	if userID != expense.CreatedBy {
-		fmt.Println("userID:", userID)
 		return errors.New("only the creator can delete this expense/receipt")
 	}
 	return s.expenseRepo.SoftDelete(ctx, id)
 }
 
+// Delete borra definitivamente el gasto y su archivo, esté o no en la papelera.
 func (s *ExpenseService) Delete(ctx context.Context, id uint) error {
 	expense, err := s.expenseRepo.GetByID(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		expense, err = s.expenseRepo.GetDeletedByID(ctx, id)
+	}
 	if err != nil {
 		return err
 	}
@@ -182,15 +305,19 @@ func (s *ExpenseService) Delete(ctx context.Context, id uint) error {
 		return err
 	}
 
-	if err := s.fileStorage.DeletePDF(expense.Receipt.RelPath); err != nil {
-		fmt.Printf("failed to remove receipt file after expense delete: %v", err)
+	if expense.Receipt.RelPath == "" {
+		return nil
+	}
+	if err := deleteStoredFile(ctx, s.fileStorage, expense.Receipt.RelPath, expense.Receipt.Quarantined); err != nil {
+		log.Printf("failed to remove receipt file %s after expense delete: %v", expense.Receipt.RelPath, err)
 	}
 
 	return nil
 }
 
+// Restore saca un gasto de la papelera.
 func (s *ExpenseService) Restore(ctx context.Context, id uint) error {
-	expense, err := s.expenseRepo.GetByID(ctx, id)
+	expense, err := s.expenseRepo.GetDeletedByID(ctx, id)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,463 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/pdfdoc"
+)
+
+// Tipos de transacción que se pueden exportar.
+const (
+	KindIncome  = "income"
+	KindExpense = "expense"
+)
+
+// Estado del comprobante de cada fila del manifiesto.
+const (
+	exportIncluded    = "included"
+	exportMissing     = "missing"
+	exportQuarantined = "quarantined"
+	exportNoReceipt   = "no_receipt"
+	exportUnreadable  = "unreadable" // el PDF guardado no se pudo marcar o procesar para el PDF unificado
+)
+
+// ExportService arma exportaciones de comprobantes (ZIP, PDF unificado) a partir
+// de los mismos filtros que los listados. Si el rol del usuario lleva marca de agua,
+// cada comprobante se marca igual que en la descarga individual.
+type ExportService struct {
+	incomeRepo  domain.IncomeRepo
+	expenseRepo domain.ExpenseRepo
+	fileStorage domain.FileStorage
+	watermarker *ReceiptWatermarker
+}
+
+func NewExportService(i domain.IncomeRepo, e domain.ExpenseRepo, fS domain.FileStorage, wm *ReceiptWatermarker) *ExportService {
+	return &ExportService{
+		incomeRepo:  i,
+		expenseRepo: e,
+		fileStorage: fS,
+		watermarker: wm,
+	}
+}
+
+// ExportFilter son los filtros de una exportación.
+type ExportFilter struct {
+	domain.TransactionFilter
+	Kind string // income, expense o vacío para ambos
+}
+
+// exportItem es una transacción (ingreso o gasto) a exportar.
+type exportItem struct {
+	Kind        string
+	ID          uint
+	Date        time.Time
+	Type        string
+	Amount      float64
+	Description string
+	CreatedBy   uint
+	Receipt     domain.Receipt
+}
+
+// collect devuelve las transacciones visibles para user que cumplen el filtro,
+// ordenadas por fecha. Los usuarios sin permiso global solo ven las propias.
+func (s *ExportService) collect(ctx context.Context, user *domain.User, filter ExportFilter) ([]exportItem, error) {
+	if filter.Kind != "" && filter.Kind != KindIncome && filter.Kind != KindExpense {
+		return nil, fmt.Errorf("%w: kind must be %q or %q", domain.ErrInvalidInput, KindIncome, KindExpense)
+	}
+	if !domain.CanViewAllTransactions(user) {
+		filter.CreatedBy = user.ID
+	}
+
+	var items []exportItem
+	if filter.Kind == "" || filter.Kind == KindIncome {
+		incomes, err := s.incomeRepo.ListFiltered(ctx, filter.TransactionFilter)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list incomes: %w", err)
+		}
+		for _, income := range incomes {
+			items = append(items, exportItem{
+				Kind:        KindIncome,
+				ID:          income.ID,
+				Date:        income.Date,
+				Type:        string(income.Type),
+				Amount:      income.Amount,
+				Description: income.Description,
+				CreatedBy:   income.CreatedBy,
+				Receipt:     income.Receipt,
+			})
+		}
+	}
+	if filter.Kind == "" || filter.Kind == KindExpense {
+		expenses, err := s.expenseRepo.ListFiltered(ctx, filter.TransactionFilter)
+		if err != nil {
+			return nil, fmt.Errorf("cannot list expenses: %w", err)
+		}
+		for _, expense := range expenses {
+			items = append(items, exportItem{
+				Kind:        KindExpense,
+				ID:          expense.ID,
+				Date:        expense.Date,
+				Type:        string(expense.Type),
+				Amount:      expense.Amount,
+				Description: expense.Description,
+				CreatedBy:   expense.CreatedBy,
+				Receipt:     expense.Receipt,
+			})
+		}
+	}
+
+	sortItems(items)
+	return items, nil
+}
+
+// WriteReceiptsZIP escribe en w un ZIP con los comprobantes que cumplen el filtro
+// y un manifest.csv con todas las transacciones. Los archivos se copian uno a uno
+// desde el storage, sin cargar el ZIP en memoria.
+func (s *ExportService) WriteReceiptsZIP(ctx context.Context, w io.Writer, user *domain.User, filter ExportFilter) error {
+	items, err := s.collect(ctx, user, filter)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	statuses := make([]string, len(items))
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.fileName()
+		statuses[i], err = s.addToZIP(ctx, zw, names[i], item, user)
+		if err != nil {
+			return err
+		}
+	}
+
+	manifest, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     "manifest.csv",
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeManifest(manifest, items, names, statuses); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (s *ExportService) addToZIP(ctx context.Context, zw *zip.Writer, name string, item exportItem, user *domain.User) (string, error) {
+	switch {
+	case item.Receipt.RelPath == "":
+		return exportNoReceipt, nil
+	case item.Receipt.Quarantined:
+		return exportQuarantined, nil
+	}
+
+	file, err := s.fileStorage.OpenPDF(ctx, item.Receipt.RelPath)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return exportMissing, nil
+		}
+		return "", fmt.Errorf("cannot open receipt of %s %d: %w", item.Kind, item.ID, err)
+	}
+	// Un PDF que no se puede marcar no se entrega sin marca: queda fuera del ZIP
+	file, err = s.watermarker.Wrap(file, user)
+	if err != nil {
+		log.Printf("zip export: cannot watermark receipt of %s %d: %v", item.Kind, item.ID, err)
+		return exportUnreadable, nil
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	// Los PDFs ya vienen comprimidos, no vale la pena deflate
+	entry, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Store,
+		Modified: item.Date,
+	})
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(entry, file); err != nil {
+		return "", fmt.Errorf("cannot copy receipt of %s %d: %w", item.Kind, item.ID, err)
+	}
+	return exportIncluded, nil
+}
+
+// WriteReceiptsBinder escribe en w un único PDF con los comprobantes que cumplen el filtro.
+// Empieza con un índice de todas las transacciones y la página en la que arranca cada
+// comprobante; cada página de un comprobante lleva en el encabezado el ID de su transacción.
+// Los comprobantes se sellan de a uno en un directorio temporal y al final se unen.
+func (s *ExportService) WriteReceiptsBinder(ctx context.Context, w io.Writer, user *domain.User, filter ExportFilter) error {
+	items, err := s.collect(ctx, user, filter)
+	if err != nil {
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "gestor-binder-*")
+	if err != nil {
+		return fmt.Errorf("cannot create temp dir: %w", err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+
+	statuses := make([]string, len(items))
+	paths := make([]string, len(items))
+	pages := make([]int, len(items))
+	for i, item := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		statuses[i], paths[i], pages[i], err = s.stageReceipt(ctx, dir, i, item, user)
+		if err != nil {
+			return err
+		}
+	}
+
+	// El índice se arma al final, pero su largo solo depende de la cantidad de transacciones
+	next := pdfdoc.TextPages(len(items)) + 1
+	starts := make([]int, len(items))
+	for i := range items {
+		if statuses[i] == exportIncluded {
+			starts[i] = next
+			next += pages[i]
+		}
+	}
+
+	indexPath := filepath.Join(dir, "index.pdf")
+	if err := writeBinderIndex(indexPath, filter, items, statuses, starts); err != nil {
+		return err
+	}
+
+	docPaths := []string{indexPath}
+	for i, path := range paths {
+		if statuses[i] == exportIncluded {
+			docPaths = append(docPaths, path)
+		}
+	}
+	return mergeFiles(docPaths, w)
+}
+
+// stageReceipt copia el comprobante de item al directorio temporal con el sello del
+// encabezado y, si el rol de user lo pide, la marca de agua. Devuelve el estado, la ruta
+// del PDF sellado y su cantidad de páginas. Un PDF que no se puede procesar no corta la
+// exportación: queda marcado en el índice.
+func (s *ExportService) stageReceipt(ctx context.Context, dir string, i int, item exportItem, user *domain.User) (string, string, int, error) {
+	switch {
+	case item.Receipt.RelPath == "":
+		return exportNoReceipt, "", 0, nil
+	case item.Receipt.Quarantined:
+		return exportQuarantined, "", 0, nil
+	}
+
+	file, err := s.fileStorage.OpenPDF(ctx, item.Receipt.RelPath)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return exportMissing, "", 0, nil
+		}
+		return "", "", 0, fmt.Errorf("cannot open receipt of %s %d: %w", item.Kind, item.ID, err)
+	}
+	file, err = s.watermarker.Wrap(file, user)
+	if err != nil {
+		log.Printf("binder: cannot watermark receipt of %s %d: %v", item.Kind, item.ID, err)
+		return exportUnreadable, "", 0, nil
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	// pdfcpu necesita un io.ReadSeeker y el storage (S3, cifrado) solo da un stream
+	raw, err := os.CreateTemp(dir, "raw-*.pdf")
+	if err != nil {
+		return "", "", 0, fmt.Errorf("cannot create temp file: %w", err)
+	}
+	defer func() {
+		_ = raw.Close()
+		_ = os.Remove(raw.Name())
+	}()
+	if _, err := io.Copy(raw, file); err != nil {
+		return "", "", 0, fmt.Errorf("cannot copy receipt of %s %d: %w", item.Kind, item.ID, err)
+	}
+	if _, err := raw.Seek(0, io.SeekStart); err != nil {
+		return "", "", 0, err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%06d.pdf", i))
+	stamped, err := os.Create(path)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("cannot create temp file: %w", err)
+	}
+	defer func() {
+		_ = stamped.Close()
+	}()
+
+	stamp := fmt.Sprintf("%s #%d - %s", strings.ToUpper(item.Kind), item.ID, item.Date.Format("2006-01-02"))
+	if err := pdfdoc.StampHeader(raw, stamped, stamp); err != nil {
+		log.Printf("binder: cannot stamp receipt of %s %d: %v", item.Kind, item.ID, err)
+		return exportUnreadable, "", 0, nil
+	}
+	if _, err := stamped.Seek(0, io.SeekStart); err != nil {
+		return "", "", 0, err
+	}
+	count, err := pdfdoc.PageCount(stamped)
+	if err != nil || count == 0 {
+		log.Printf("binder: cannot count pages of %s %d: %v", item.Kind, item.ID, err)
+		return exportUnreadable, "", 0, nil
+	}
+	return exportIncluded, path, count, nil
+}
+
+// writeBinderIndex genera las páginas de índice: una línea por transacción con la página
+// donde empieza su comprobante, o el motivo por el que no está.
+func writeBinderIndex(path string, filter ExportFilter, items []exportItem, statuses []string, starts []int) error {
+	subtitle := fmt.Sprintf("Generated %s - %d transactions", time.Now().Format("2006-01-02 15:04"), len(items))
+	if filter.From != nil || filter.To != nil {
+		from, to := "...", "..."
+		if filter.From != nil {
+			from = filter.From.Format("2006-01-02")
+		}
+		if filter.To != nil {
+			to = filter.To.Format("2006-01-02")
+		}
+		subtitle += fmt.Sprintf(" - period %s to %s", from, to)
+	}
+	columns := fmt.Sprintf("%5s  %-10s  %-7s  %7s  %-12s  %12s  %s", "PAGE", "DATE", "KIND", "ID", "TYPE", "AMOUNT", "DESCRIPTION")
+
+	lines := make([]string, len(items))
+	for i, item := range items {
+		page := "-"
+		description := item.Description
+		if statuses[i] == exportIncluded {
+			page = strconv.Itoa(starts[i])
+		} else {
+			description = "[" + statuses[i] + "] " + description
+		}
+		line := fmt.Sprintf("%5s  %-10s  %-7s  %7d  %-12.12s  %12.2f  %s",
+			page, item.Date.Format("2006-01-02"), item.Kind, item.ID, item.Type, item.Amount, description)
+		if runes := []rune(line); len(runes) > pdfdoc.LineWidth {
+			line = string(runes[:pdfdoc.LineWidth-3]) + "..."
+		}
+		lines[i] = line
+	}
+	if len(lines) == 0 {
+		lines = []string{"No transactions match the filters."}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("cannot create index: %w", err)
+	}
+	if err := pdfdoc.WriteTextPages(f, "Receipts binder", subtitle, columns, lines); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("cannot write index: %w", err)
+	}
+	return f.Close()
+}
+
+// mergeFiles une los PDFs de paths, en orden, y escribe el resultado en w.
+func mergeFiles(paths []string, w io.Writer) error {
+	if len(paths) == 1 {
+		f, err := os.Open(paths[0])
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		_, err = io.Copy(w, f)
+		return err
+	}
+
+	docs := make([]io.ReadSeeker, 0, len(paths))
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = f.Close()
+		}()
+		docs = append(docs, f)
+	}
+	if err := pdfdoc.Merge(docs, w); err != nil {
+		return fmt.Errorf("cannot merge receipts: %w", err)
+	}
+	return nil
+}
+
+func writeManifest(w io.Writer, items []exportItem, names, statuses []string) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{
+		"kind", "id", "date", "type", "amount", "description", "created_by",
+		"file", "checksum", "receipt_status",
+	}); err != nil {
+		return err
+	}
+	for i, item := range items {
+		file := names[i]
+		if statuses[i] != exportIncluded {
+			file = ""
+		}
+		if err := cw.Write([]string{
+			item.Kind,
+			strconv.FormatUint(uint64(item.ID), 10),
+			item.Date.Format("2006-01-02"),
+			csvSafe(item.Type),
+			strconv.FormatFloat(item.Amount, 'f', 2, 64),
+			csvSafe(item.Description),
+			strconv.FormatUint(uint64(item.CreatedBy), 10),
+			file,
+			item.Receipt.Checksum,
+			statuses[i],
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// fileName arma un nombre legible y único: fecha_income|expense_tipo_monto_id.pdf
+func (item exportItem) fileName() string {
+	typ := unsafeNameChars.ReplaceAllString(strings.ToLower(item.Type), "-")
+	return fmt.Sprintf("%s_%s_%s_%.2f_%d.pdf", item.Date.Format("2006-01-02"), item.Kind, typ, item.Amount, item.ID)
+}
+
+// csvSafe evita que una planilla interprete el campo como fórmula.
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func sortItems(items []exportItem) {
+	// A igual fecha, ingresos antes que gastos y después por ID
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		if a.Kind != b.Kind {
+			return a.Kind == KindIncome
+		}
+		return a.ID < b.ID
+	})
+}
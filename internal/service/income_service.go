@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log"
 	"mime/multipart"
 	"time"
 
@@ -12,13 +14,22 @@ import (
 
 type IncomeService struct {
 	incomeRepo  domain.IncomeRepo
+	uploadRepo  domain.UploadRepo
 	fileStorage domain.FileStorage
+	watermarker *ReceiptWatermarker
 }
 
-func NewIncomeService(i domain.IncomeRepo, fS domain.FileStorage) *IncomeService {
+func NewIncomeService(
+	i domain.IncomeRepo,
+	u domain.UploadRepo,
+	fS domain.FileStorage,
+	wm *ReceiptWatermarker,
+) *IncomeService {
 	return &IncomeService{
 		incomeRepo:  i,
+		uploadRepo:  u,
 		fileStorage: fS,
+		watermarker: wm,
 	}
 }
 
@@ -27,6 +38,7 @@ func (s *IncomeService) Create(
 	income *domain.Income,
 	file multipart.File,
 	fileHeader *multipart.FileHeader,
+	uploadID string,
 ) error {
 	if income == nil {
 		return errors.New("income cannot be nil")
@@ -50,26 +62,35 @@ func (s *IncomeService) Create(
 		income.Date = time.Now()
 	}
 
-	fileName, checksum, relPath, err := s.fileStorage.SavePDF(fileHeader)
+	// Sin comprobante (p. ej. un cargo de tarjeta antes de la factura) queda pendiente
+	if (file == nil || fileHeader == nil) && uploadID == "" {
+		income.ReceiptPending = true
+		if err := s.incomeRepo.CreateWithReceipt(ctx, income, nil); err != nil {
+			return fmt.Errorf("failed to create income: %w", err)
+		}
+		return nil
+	}
+
+	stored, upload, err := saveReceiptFile(ctx, s.fileStorage, s.uploadRepo, file, fileHeader, uploadID, income.CreatedBy)
 	if err != nil {
-		return fmt.Errorf("failed to save pdf: %w", err)
+		return err
 	}
 
 	// Crear Receipt con la URL donde se guardó
 	receipt := &domain.Receipt{
-		FileName:   fileName,
-		RelPath:    relPath,
+		FileName:   stored.FileName,
+		RelPath:    stored.RelPath,
 		MimeType:   "application/pdf",
 		UploadedBy: income.CreatedBy,
-		Checksum:   checksum,
+		Checksum:   stored.Checksum,
+		KeyID:      stored.KeyID,
 	}
+	applyInspection(receipt, stored)
 
 	err = s.incomeRepo.CreateWithReceipt(ctx, income, receipt)
 	if err != nil {
-		// Si DB falla, eliminar archivo para evitar basura
-		if rmErr := s.fileStorage.DeletePDF(relPath); rmErr != nil {
-			fmt.Printf("failed to remove file after tx error: %v", rmErr)
-		}
+		// Si DB falla, eliminar archivo (o devolver el upload) para evitar basura
+		rollbackReceiptFile(ctx, s.fileStorage, s.uploadRepo, stored, upload)
 		return fmt.Errorf("failed to create income with receipt: %w", err)
 	}
 
@@ -80,16 +101,52 @@ func (s *IncomeService) GetByID(ctx context.Context, id uint) (*domain.Income, e
 	return s.incomeRepo.GetByID(ctx, id)
 }
 
+// OpenReceipt devuelve el income junto con un lector del archivo del comprobante.
+// Solo puede abrirlo quien puede ver el movimiento (domain.CanViewTransaction); si el rol
+// de user tiene configurada marca de agua, el lector entrega una copia marcada.
+// El llamador es responsable de cerrar el lector.
+func (s *IncomeService) OpenReceipt(ctx context.Context, id uint, user *domain.User) (*domain.Income, io.ReadCloser, error) {
+	income, err := s.incomeRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !domain.CanViewTransaction(user, income.CreatedBy) {
+		return nil, nil, domain.ErrForbidden
+	}
+	if income.Receipt.RelPath == "" {
+		return nil, nil, domain.ErrNotFound
+	}
+	if income.Receipt.Quarantined {
+		return nil, nil, domain.ErrQuarantined
+	}
+
+	file, err := s.fileStorage.OpenPDF(ctx, income.Receipt.RelPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err = s.watermarker.Wrap(file, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return income, file, nil
+}
+
 func (s *IncomeService) List(ctx context.Context) ([]domain.Income, error) {
 	return s.incomeRepo.List(ctx)
 }
 
+// ListDeleted devuelve los ingresos de la papelera, los borrados más recientes primero.
+func (s *IncomeService) ListDeleted(ctx context.Context) ([]domain.Income, error) {
+	return s.incomeRepo.ListDeleted(ctx)
+}
+
 func (s *IncomeService) Update(
 	ctx context.Context,
 	id uint,
 	partial *domain.Income,
 	file multipart.File,
 	fileHeader *multipart.FileHeader,
+	uploadID string,
 	userID uint,
 ) error {
 	// Obtener income existente
@@ -121,30 +178,39 @@ func (s *IncomeService) Update(
 	}
 
 	var oldFilePath string
+	var oldQuarantined bool
 	var receiptToUpdate *domain.Receipt
+	var stored *domain.StoredFile
+	var upload *domain.Upload
 
 	// Actualizar receipt solo si hay un archivo nuevo
-	if fileHeader != nil {
+	if fileHeader != nil || uploadID != "" {
+		if existing.ReceiptPending {
+			return errors.New("receipt is pending for this income, attach it first")
+		}
 		if existing.Receipt.ID == 0 {
 			return errors.New("receipt not found for this income")
 		}
 
-		filename, checksum, relPath, err := s.fileStorage.SavePDF(fileHeader)
+		stored, upload, err = saveReceiptFile(ctx, s.fileStorage, s.uploadRepo, file, fileHeader, uploadID, userID)
 		if err != nil {
-			return fmt.Errorf("failed to save PDF: %w", err)
+			return err
 		}
 
 		// Guardar path antiguo para eliminar después si cambia
-		if existing.Receipt.Checksum != "" && existing.Receipt.Checksum != checksum {
+		if existing.Receipt.Checksum != "" && existing.Receipt.Checksum != stored.Checksum {
 			oldFilePath = existing.Receipt.RelPath
+			oldQuarantined = existing.Receipt.Quarantined
 		}
 
 		// Actualizar campos del receipt existente
-		existing.Receipt.FileName = filename
-		existing.Receipt.RelPath = relPath
+		existing.Receipt.FileName = stored.FileName
+		existing.Receipt.RelPath = stored.RelPath
 		existing.Receipt.MimeType = "application/pdf"
 		existing.Receipt.UploadedBy = userID
-		existing.Receipt.Checksum = checksum
+		existing.Receipt.Checksum = stored.Checksum
+		existing.Receipt.KeyID = stored.KeyID
+		applyInspection(&existing.Receipt, stored)
 
 		receiptToUpdate = &existing.Receipt
 	}
@@ -154,37 +220,93 @@ func (s *IncomeService) Update(
 	if err != nil {
 		// rollback del archivo nuevo si hubo error
 		if receiptToUpdate != nil {
-			if rmErr := s.fileStorage.DeletePDF(receiptToUpdate.RelPath); rmErr != nil {
-				fmt.Printf("failed to remove new receipt after update error: %v", rmErr)
-			}
+			rollbackReceiptFile(ctx, s.fileStorage, s.uploadRepo, stored, upload)
 		}
 		return fmt.Errorf("failed to update income with receipt: %w", err)
 	}
 
 	// eliminar archivo antiguo si cambió
 	if oldFilePath != "" {
-		if rmErr := s.fileStorage.DeletePDF(oldFilePath); rmErr != nil {
-			fmt.Printf("failed to remove old receipt file: %v", rmErr)
+		if rmErr := deleteStoredFile(ctx, s.fileStorage, oldFilePath, oldQuarantined); rmErr != nil {
+			log.Printf("failed to remove old receipt file %s: %v", oldFilePath, rmErr)
 		}
 	}
 
 	return nil
 }
 
+// AttachReceipt adjunta el comprobante a un ingreso creado sin él. Solo puede hacerlo quien
+// lo creó; si el ingreso ya tiene comprobante devuelve ErrReceiptAttached (para reemplazarlo
+// está Update).
+func (s *IncomeService) AttachReceipt(
+	ctx context.Context,
+	id uint,
+	file multipart.File,
+	fileHeader *multipart.FileHeader,
+	uploadID string,
+	userID uint,
+) (*domain.Income, error) {
+	if (file == nil || fileHeader == nil) && uploadID == "" {
+		return nil, errors.New("receipt file is required")
+	}
+
+	existing, err := s.incomeRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing.CreatedBy != userID {
+		return nil, domain.ErrForbidden
+	}
+	if !existing.ReceiptPending {
+		return nil, domain.ErrReceiptAttached
+	}
+
+	stored, upload, err := saveReceiptFile(ctx, s.fileStorage, s.uploadRepo, file, fileHeader, uploadID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := &domain.Receipt{
+		FileName:   stored.FileName,
+		RelPath:    stored.RelPath,
+		MimeType:   "application/pdf",
+		UploadedBy: userID,
+		Checksum:   stored.Checksum,
+		KeyID:      stored.KeyID,
+	}
+	applyInspection(receipt, stored)
+
+	if err := s.incomeRepo.AttachReceipt(ctx, id, receipt); err != nil {
+		rollbackReceiptFile(ctx, s.fileStorage, s.uploadRepo, stored, upload)
+		if errors.Is(err, domain.ErrReceiptAttached) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to attach receipt: %w", err)
+	}
+
+	existing.ReceiptPending = false
+	existing.ReceiptRemindedAt = nil
+	existing.Receipt = *receipt
+	return existing, nil
+}
+
 func (s *IncomeService) SoftDelete(ctx context.Context, id uint, userID uint) error {
 	income, err := s.incomeRepo.GetByID(ctx, id)
 	if err != nil {
 		return err
 	}
 	if userID != income.CreatedBy {
-		fmt.Println("userID:", userID)
 		return errors.New("only the creator can delete this income/receipt")
 	}
 	return s.incomeRepo.SoftDelete(ctx, id)
 }
 
+// Delete borra definitivamente el ingreso y su archivo, esté o no en la papelera.
 func (s *IncomeService) Delete(ctx context.Context, id uint) error {
 	income, err := s.incomeRepo.GetByID(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		income, err = s.incomeRepo.GetDeletedByID(ctx, id)
+	}
 	if err != nil {
 		return err
 	}
@@ -196,15 +318,19 @@ func (s *IncomeService) Delete(ctx context.Context, id uint) error {
 		return err
 	}
 
-	if err := s.fileStorage.DeletePDF(income.Receipt.RelPath); err != nil {
-		fmt.Printf("failed to remove receipt file after income delete: %v", err)
+	if income.Receipt.RelPath == "" {
+		return nil
+	}
+	if err := deleteStoredFile(ctx, s.fileStorage, income.Receipt.RelPath, income.Receipt.Quarantined); err != nil {
+		log.Printf("failed to remove receipt file %s after income delete: %v", income.Receipt.RelPath, err)
 	}
 
 	return nil
 }
 
+// Restore saca un ingreso de la papelera.
 func (s *IncomeService) Restore(ctx context.Context, id uint) error {
-	income, err := s.incomeRepo.GetByID(ctx, id)
+	income, err := s.incomeRepo.GetDeletedByID(ctx, id)
 	if err != nil {
 		return err
 	}
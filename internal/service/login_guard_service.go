@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+)
+
+const (
+	securityEventDefaultLimit = 50
+	securityEventMaxLimit     = 200
+)
+
+// LoginPolicy configura la protección contra fuerza bruta. Cada fallo dentro de
+// FailureWindow suma en la cuenta y en la IP. Desde el fallo BackoffAfter de una cuenta
+// hay que esperar BackoffBase antes del siguiente intento, el doble con cada fallo más,
+// hasta BackoffMax; las IPs no se demoran, porque detrás de una puede haber una oficina
+// entera. Con AccountMaxFailures (o IPMaxFailures) fallos la cuenta (o la IP) queda
+// bloqueada LockoutDuration. Un umbral en 0 desactiva esa parte.
+type LoginPolicy struct {
+	FailureWindow      time.Duration
+	BackoffAfter       int
+	BackoffBase        time.Duration
+	BackoffMax         time.Duration
+	AccountMaxFailures int
+	IPMaxFailures      int
+	LockoutDuration    time.Duration
+}
+
+// LoginGuardService cuenta los logins fallidos por cuenta y por IP, demora o bloquea los
+// siguientes intentos y deja cada caso en el registro de seguridad. Los contadores están
+// en la base, así que valen para todas las instancias de la API.
+type LoginGuardService struct {
+	throttleRepo domain.LoginThrottleRepo
+	eventRepo    domain.SecurityEventRepo
+	userRepo     domain.UserRepo
+	policy       LoginPolicy
+}
+
+func NewLoginGuardService(
+	throttleRepo domain.LoginThrottleRepo,
+	eventRepo domain.SecurityEventRepo,
+	userRepo domain.UserRepo,
+	policy LoginPolicy,
+) *LoginGuardService {
+	return &LoginGuardService{
+		throttleRepo: throttleRepo,
+		eventRepo:    eventRepo,
+		userRepo:     userRepo,
+		policy:       policy,
+	}
+}
+
+// Check rechaza el intento con un *domain.ThrottledError si la cuenta o la IP están
+// bloqueadas o todavía tienen que esperar. Va antes de mirar la contraseña, así que
+// durante la espera no se puede probar ninguna.
+func (g *LoginGuardService) Check(ctx context.Context, email, ip string) error {
+	throttles, err := g.throttleRepo.Get(ctx, g.keys(email, ip)...)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var throttled *domain.ThrottledError
+	for _, t := range throttles {
+		var wait time.Duration
+		reason := domain.ErrTooManyAttempts
+		isAccount := strings.HasPrefix(t.Key, accountKeyPrefix)
+		if t.LockedUntil != nil && now.Before(*t.LockedUntil) {
+			wait = t.LockedUntil.Sub(now)
+			if isAccount {
+				reason = domain.ErrAccountLocked
+			}
+		} else if since := now.Sub(t.LastFailureAt); isAccount && since < g.policy.FailureWindow {
+			wait = g.policy.backoff(t.Failures) - since
+		}
+		if wait > 0 && (throttled == nil || wait > throttled.RetryAfter) {
+			throttled = &domain.ThrottledError{Err: reason, RetryAfter: wait}
+		}
+	}
+	if throttled == nil {
+		return nil
+	}
+
+	g.Record(ctx, &domain.SecurityEvent{
+		Type:   domain.SecurityEventLoginThrottled,
+		Email:  email,
+		IP:     ip,
+		Detail: fmt.Sprintf("%v, retry in %s", throttled.Err, throttled.RetryAfter.Round(time.Second)),
+	})
+	return throttled
+}
+
+// Failure anota un intento fallido en la cuenta y en la IP y las bloquea si llegaron al
+// umbral. userID es nil si el email no es de ninguna cuenta.
+func (g *LoginGuardService) Failure(ctx context.Context, email, ip string, userID *uint, reason string) error {
+	g.Record(ctx, &domain.SecurityEvent{
+		Type:   domain.SecurityEventLoginFailed,
+		UserID: userID,
+		Email:  email,
+		IP:     ip,
+		Detail: reason,
+	})
+
+	now := time.Now()
+	windowStart := now.Add(-g.policy.FailureWindow)
+	limits := map[string]int{accountKey(email): g.policy.AccountMaxFailures}
+	if ip != "" {
+		limits[ipKey(ip)] = g.policy.IPMaxFailures
+	}
+	for key, limit := range limits {
+		throttle, err := g.throttleRepo.RecordFailure(ctx, key, now, windowStart)
+		if err != nil {
+			return err
+		}
+		if limit <= 0 || throttle.Failures < limit || g.policy.LockoutDuration <= 0 {
+			continue
+		}
+		if err := g.throttleRepo.Lock(ctx, key, now.Add(g.policy.LockoutDuration)); err != nil {
+			return err
+		}
+
+		event := &domain.SecurityEvent{
+			Type:   domain.SecurityEventAccountLocked,
+			UserID: userID,
+			Email:  email,
+			IP:     ip,
+			Detail: fmt.Sprintf("%d failed attempts, locked for %s", throttle.Failures, g.policy.LockoutDuration),
+		}
+		if key != accountKey(email) {
+			event.Type = domain.SecurityEventIPLocked
+			event.UserID = nil
+		}
+		g.Record(ctx, event)
+	}
+	return nil
+}
+
+// Success limpia los fallos de la cuenta. Los de la IP no: con una cuenta propia se
+// podría limpiar el contador de una IP que prueba contraseñas de otras.
+func (g *LoginGuardService) Success(ctx context.Context, email string) error {
+	return g.throttleRepo.Reset(ctx, accountKey(email))
+}
+
+// Allow cuenta un pedido de action (ej: "reset" para la recuperación de contraseña) del
+// email y de la IP y dice si los dos siguen dentro de sus límites en FailureWindow. Los
+// contadores son aparte de los del login: pasarse no bloquea el login de la cuenta. Un
+// límite en 0 no limita.
+func (g *LoginGuardService) Allow(ctx context.Context, action, email, ip string, emailLimit, ipLimit int) (bool, error) {
+	now := time.Now()
+	windowStart := now.Add(-g.policy.FailureWindow)
+	limits := map[string]int{action + ":" + accountKey(email): emailLimit}
+	if ip != "" {
+		limits[action+":"+ipKey(ip)] = ipLimit
+	}
+
+	allowed := true
+	for key, limit := range limits {
+		counter, err := g.throttleRepo.RecordFailure(ctx, key, now, windowStart)
+		if err != nil {
+			return false, err
+		}
+		if limit > 0 && counter.Failures > limit {
+			allowed = false
+		}
+	}
+	if !allowed {
+		g.Record(ctx, &domain.SecurityEvent{
+			Type:   domain.SecurityEventRequestThrottled,
+			Email:  email,
+			IP:     ip,
+			Detail: action,
+		})
+	}
+	return allowed, nil
+}
+
+// UnlockUser quita el bloqueo y los fallos de la cuenta del usuario (lo hace un admin).
+func (g *LoginGuardService) UnlockUser(ctx context.Context, userID, adminID uint) error {
+	user, err := g.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := g.throttleRepo.Reset(ctx, accountKey(user.Email)); err != nil {
+		return err
+	}
+	g.Record(ctx, &domain.SecurityEvent{
+		Type:   domain.SecurityEventAccountUnlocked,
+		UserID: &user.ID,
+		Email:  user.Email,
+		Detail: fmt.Sprintf("unlocked by user %d", adminID),
+	})
+	return nil
+}
+
+// UnlockIP quita el bloqueo y los fallos de una IP (lo hace un admin).
+func (g *LoginGuardService) UnlockIP(ctx context.Context, ip string, adminID uint) error {
+	if ip == "" {
+		return fmt.Errorf("%w: ip is required", domain.ErrInvalidInput)
+	}
+	if err := g.throttleRepo.Reset(ctx, ipKey(ip)); err != nil {
+		return err
+	}
+	g.Record(ctx, &domain.SecurityEvent{
+		Type:   domain.SecurityEventIPUnlocked,
+		IP:     ip,
+		Detail: fmt.Sprintf("unlocked by user %d", adminID),
+	})
+	return nil
+}
+
+// Record deja el evento en el log y en el registro de seguridad. Si no se puede guardar
+// solo se avisa en el log: el login no falla por eso.
+func (g *LoginGuardService) Record(ctx context.Context, event *domain.SecurityEvent) {
+	if len(event.Detail) > 255 {
+		event.Detail = strings.ToValidUTF8(event.Detail[:255], "")
+	}
+	log.Printf("security event %s: user=%v email=%q ip=%s %s",
+		event.Type, userIDOrNone(event.UserID), event.Email, event.IP, event.Detail)
+	if err := g.eventRepo.Create(ctx, event); err != nil {
+		log.Printf("failed to store security event %s: %v", event.Type, err)
+	}
+}
+
+// Events devuelve el registro de seguridad, lo más reciente primero.
+func (g *LoginGuardService) Events(ctx context.Context, filter domain.SecurityEventFilter) ([]domain.SecurityEvent, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = securityEventDefaultLimit
+	}
+	filter.Limit = min(filter.Limit, securityEventMaxLimit)
+	filter.Offset = max(filter.Offset, 0)
+	return g.eventRepo.List(ctx, filter)
+}
+
+// PurgeStale borra los contadores sin fallos recientes ni bloqueo vigente.
+func (g *LoginGuardService) PurgeStale(ctx context.Context) (int64, error) {
+	return g.throttleRepo.DeleteStale(ctx, time.Now().Add(-g.policy.FailureWindow))
+}
+
+// PurgeEvents borra los eventos de seguridad anteriores a retention.
+func (g *LoginGuardService) PurgeEvents(ctx context.Context, retention time.Duration) (int64, error) {
+	if retention <= 0 {
+		return 0, errors.New("retention must be positive")
+	}
+	return g.eventRepo.DeleteBefore(ctx, time.Now().Add(-retention))
+}
+
+func (g *LoginGuardService) keys(email, ip string) []string {
+	keys := []string{accountKey(email)}
+	if ip != "" {
+		keys = append(keys, ipKey(ip))
+	}
+	return keys
+}
+
+// backoff es cuánto hay que esperar después del último de failures fallos.
+func (p LoginPolicy) backoff(failures int) time.Duration {
+	if p.BackoffAfter <= 0 || p.BackoffBase <= 0 || failures < p.BackoffAfter {
+		return 0
+	}
+	limit := p.BackoffMax
+	if limit <= 0 {
+		limit = 24 * time.Hour
+	}
+	wait := p.BackoffBase
+	for i := p.BackoffAfter; i < failures && wait < limit; i++ {
+		wait *= 2
+	}
+	return min(wait, limit)
+}
+
+const (
+	accountKeyPrefix = "account:"
+	ipKeyPrefix      = "ip:"
+)
+
+// accountKey cuenta también los emails que no son de ninguna cuenta, para que la
+// respuesta no diga cuáles existen.
+func accountKey(email string) string {
+	return accountKeyPrefix + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipKey(ip string) string {
+	return ipKeyPrefix + ip
+}
+
+func userIDOrNone(id *uint) any {
+	if id == nil {
+		return "none"
+	}
+	return *id
+}
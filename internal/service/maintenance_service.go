@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/pdfdoc"
+	"github.com/SaidMg10/gestor-one/internal/storage"
+)
+
+// MaintenanceService agrupa tareas de mantenimiento que cruzan la base de datos con el storage.
+type MaintenanceService struct {
+	receiptRepo domain.ReceiptRepo
+	uploadRepo  domain.UploadRepo
+	fileStorage domain.FileStorage
+	orphanGrace time.Duration
+}
+
+func NewMaintenanceService(
+	r domain.ReceiptRepo,
+	u domain.UploadRepo,
+	fS domain.FileStorage,
+	orphanGrace time.Duration,
+) *MaintenanceService {
+	if orphanGrace <= 0 {
+		orphanGrace = 24 * time.Hour
+	}
+	return &MaintenanceService{
+		receiptRepo: r,
+		uploadRepo:  u,
+		fileStorage: fS,
+		orphanGrace: orphanGrace,
+	}
+}
+
+// StorageCheckOptions controla qué hace CheckStorage.
+type StorageCheckOptions struct {
+	VerifyChecksums bool // leer cada archivo y comparar su sha256 con receipts.checksum
+	Repair          bool // poner en cuarentena huérfanos viejos y recalcular checksums
+}
+
+// MissingFile es un receipt cuyo archivo no existe en el storage.
+type MissingFile struct {
+	ReceiptID uint   `json:"receipt_id"`
+	RelPath   string `json:"rel_path"`
+}
+
+// OrphanFile es un archivo del storage que ningún receipt referencia.
+type OrphanFile struct {
+	RelPath     string    `json:"rel_path"`
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"mod_time"`
+	Quarantined bool      `json:"quarantined"`
+}
+
+// ChecksumMismatch es un receipt cuyo checksum guardado no coincide con el archivo.
+type ChecksumMismatch struct {
+	ReceiptID uint   `json:"receipt_id"`
+	RelPath   string `json:"rel_path"`
+	Expected  string `json:"expected"`
+	Actual    string `json:"actual"`
+	Fixed     bool   `json:"fixed"`
+}
+
+// StorageReport es el resultado de CheckStorage.
+type StorageReport struct {
+	CheckedReceipts  int                `json:"checked_receipts"`
+	CheckedFiles     int                `json:"checked_files"`
+	Missing          []MissingFile      `json:"missing"`
+	Orphans          []OrphanFile       `json:"orphans"`
+	ChecksumMismatch []ChecksumMismatch `json:"checksum_mismatch"`
+	Errors           []string           `json:"errors,omitempty"`
+}
+
+// CheckStorage compara la tabla receipts con los archivos del storage y reporta:
+//   - receipts cuyo archivo falta (solo se reportan, no hay forma de recuperarlos),
+//   - archivos huérfanos (en Repair se ponen en cuarentena si superan el periodo de gracia),
+//   - checksums que no coinciden o están vacíos (en Repair se recalculan).
+//
+// El periodo de gracia evita tocar archivos de subidas que todavía no hicieron commit en la BD.
+func (s *MaintenanceService) CheckStorage(ctx context.Context, opts StorageCheckOptions) (*StorageReport, error) {
+	receipts, err := s.receiptRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list receipts: %w", err)
+	}
+	uploads, err := s.uploadRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list uploads: %w", err)
+	}
+	objects, err := s.fileStorage.ListPDFs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list storage: %w", err)
+	}
+
+	report := &StorageReport{
+		CheckedReceipts:  len(receipts),
+		CheckedFiles:     len(objects),
+		Missing:          []MissingFile{},
+		Orphans:          []OrphanFile{},
+		ChecksumMismatch: []ChecksumMismatch{},
+	}
+
+	stored := make(map[string]domain.StoredObject, len(objects))
+	for _, obj := range objects {
+		stored[obj.RelPath] = obj
+	}
+
+	// Los uploads completados todavía no tienen receipt pero su archivo no es huérfano
+	referenced := make(map[string]bool, len(receipts)+len(uploads))
+	for _, upload := range uploads {
+		if upload.RelPath != "" {
+			referenced[upload.RelPath] = true
+		}
+	}
+	for i := range receipts {
+		receipt := &receipts[i]
+		referenced[receipt.RelPath] = true
+
+		// Los archivos en cuarentena viven fuera del área normal a propósito
+		if receipt.Quarantined {
+			continue
+		}
+
+		if _, ok := stored[receipt.RelPath]; !ok {
+			report.Missing = append(report.Missing, MissingFile{ReceiptID: receipt.ID, RelPath: receipt.RelPath})
+			continue
+		}
+
+		if !opts.VerifyChecksums && receipt.Checksum != "" {
+			continue
+		}
+
+		actual, err := s.checksum(ctx, receipt.RelPath)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("receipt %d: %v", receipt.ID, err))
+			continue
+		}
+		if actual == receipt.Checksum {
+			continue
+		}
+
+		mismatch := ChecksumMismatch{
+			ReceiptID: receipt.ID,
+			RelPath:   receipt.RelPath,
+			Expected:  receipt.Checksum,
+			Actual:    actual,
+		}
+		if opts.Repair {
+			receipt.Checksum = actual
+			if err := s.receiptRepo.Update(ctx, receipt); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("receipt %d: cannot update checksum: %v", receipt.ID, err))
+			} else {
+				mismatch.Fixed = true
+			}
+		}
+		report.ChecksumMismatch = append(report.ChecksumMismatch, mismatch)
+	}
+
+	cutoff := time.Now().Add(-s.orphanGrace)
+	for _, obj := range objects {
+		if referenced[obj.RelPath] {
+			continue
+		}
+
+		orphan := OrphanFile{RelPath: obj.RelPath, Size: obj.Size, ModTime: obj.ModTime}
+		if opts.Repair && obj.ModTime.Before(cutoff) {
+			if err := s.fileStorage.QuarantinePDF(ctx, obj.RelPath); err != nil && !errors.Is(err, domain.ErrNotFound) {
+				report.Errors = append(report.Errors, fmt.Sprintf("%s: cannot quarantine: %v", obj.RelPath, err))
+			} else {
+				orphan.Quarantined = true
+			}
+		}
+		report.Orphans = append(report.Orphans, orphan)
+	}
+
+	return report, nil
+}
+
+func (s *MaintenanceService) checksum(ctx context.Context, relPath string) (string, error) {
+	file, err := s.fileStorage.OpenPDF(ctx, relPath)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+// ReEncryptReport es el resultado de ReEncrypt.
+type ReEncryptReport struct {
+	CheckedReceipts int      `json:"checked_receipts"`
+	ReEncrypted     int      `json:"re_encrypted"`
+	Errors          []string `json:"errors,omitempty"`
+}
+
+// ReEncrypt vuelve a guardar los comprobantes que no están cifrados con activeKeyID
+// (o todos si all es true). Se usa al rotar la master key: el archivo se descifra con la key vieja,
+// se guarda de nuevo con la activa, se actualiza el receipt y recién entonces se borra el archivo viejo.
+func (s *MaintenanceService) ReEncrypt(ctx context.Context, activeKeyID string, all bool) (*ReEncryptReport, error) {
+	receipts, err := s.receiptRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list receipts: %w", err)
+	}
+
+	report := &ReEncryptReport{CheckedReceipts: len(receipts)}
+	for i := range receipts {
+		receipt := &receipts[i]
+		if receipt.Quarantined || (!all && receipt.KeyID == activeKeyID) {
+			continue
+		}
+		if err := s.reEncryptOne(ctx, receipt); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("receipt %d: %v", receipt.ID, err))
+			continue
+		}
+		report.ReEncrypted++
+	}
+	return report, nil
+}
+
+func (s *MaintenanceService) reEncryptOne(ctx context.Context, receipt *domain.Receipt) error {
+	file, err := s.fileStorage.OpenPDF(ctx, receipt.RelPath)
+	if err != nil {
+		return err
+	}
+	stored, err := s.fileStorage.SavePDF(ctx, file, -1)
+	_ = file.Close()
+	if err != nil {
+		return err
+	}
+
+	// Si el contenido no coincide con lo registrado no tocamos el receipt
+	if receipt.Checksum != "" && receipt.Checksum != stored.Checksum {
+		if rmErr := s.fileStorage.DeletePDF(ctx, stored.RelPath); rmErr != nil {
+			log.Printf("failed to remove re-encrypted copy %s of receipt %d: %v", stored.RelPath, receipt.ID, rmErr)
+		}
+		return fmt.Errorf("checksum mismatch, run storage-check first")
+	}
+
+	oldRelPath := receipt.RelPath
+	receipt.FileName = stored.FileName
+	receipt.RelPath = stored.RelPath
+	receipt.Checksum = stored.Checksum
+	receipt.KeyID = stored.KeyID
+	if err := s.receiptRepo.Update(ctx, receipt); err != nil {
+		if rmErr := s.fileStorage.DeletePDF(ctx, stored.RelPath); rmErr != nil {
+			log.Printf("failed to remove re-encrypted copy %s of receipt %d: %v", stored.RelPath, receipt.ID, rmErr)
+		}
+		return err
+	}
+
+	if err := s.fileStorage.DeletePDF(ctx, oldRelPath); err != nil {
+		log.Printf("failed to remove old file %s of receipt %d: %v", oldRelPath, receipt.ID, err)
+	}
+	return nil
+}
+
+// ExtractTextReport es el resultado de ExtractText.
+type ExtractTextReport struct {
+	CheckedReceipts int      `json:"checked_receipts"`
+	Extracted       int      `json:"extracted"`
+	WithoutText     int      `json:"without_text"`
+	Errors          []string `json:"errors,omitempty"`
+}
+
+// ExtractText completa el texto de los comprobantes guardados antes de que existiera la
+// búsqueda (o de todos si all es true), para que entren en el índice de texto completo.
+func (s *MaintenanceService) ExtractText(ctx context.Context, all bool) (*ExtractTextReport, error) {
+	receipts, err := s.receiptRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list receipts: %w", err)
+	}
+
+	report := &ExtractTextReport{CheckedReceipts: len(receipts)}
+	for i := range receipts {
+		receipt := &receipts[i]
+		if receipt.Quarantined || (!all && receipt.Content != "") {
+			continue
+		}
+		text, err := s.extractOne(ctx, receipt.RelPath)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("receipt %d: %v", receipt.ID, err))
+			continue
+		}
+		if text == "" {
+			report.WithoutText++
+			continue
+		}
+		if err := s.receiptRepo.Update(ctx, &domain.Receipt{ID: receipt.ID, Content: text}); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("receipt %d: %v", receipt.ID, err))
+			continue
+		}
+		report.Extracted++
+	}
+	return report, nil
+}
+
+func (s *MaintenanceService) extractOne(ctx context.Context, relPath string) (string, error) {
+	file, err := s.fileStorage.OpenPDF(ctx, relPath)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	// La extracción necesita acceso aleatorio y el storage solo da un stream
+	tmp, err := os.CreateTemp("", "gestor-text-*.pdf")
+	if err != nil {
+		return "", fmt.Errorf("cannot create temp file: %w", err)
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+	size, err := io.Copy(tmp, file)
+	if err != nil {
+		return "", err
+	}
+	return pdfdoc.ExtractText(tmp, size, storage.MaxExtractedText)
+}
@@ -0,0 +1,426 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/oidc"
+	"golang.org/x/oauth2"
+)
+
+// oauthStateTTL es el tiempo que tiene el usuario para completar el login en el proveedor.
+const oauthStateTTL = 10 * time.Minute
+
+// ProviderGoogle es el nombre del proveedor de Google en los states y las identidades.
+const ProviderGoogle = "google"
+
+// roleRank ordena los roles que puede asignar un proveedor: si los claims mapean a
+// varios, el usuario se queda con el de más privilegios. Superadmin nunca se asigna por SSO.
+var roleRank = map[string]int{
+	domain.RoleEmployee:   1,
+	domain.RoleAccountant: 2,
+	domain.RoleAdmin:      3,
+}
+
+// SSOProvider es un proveedor OpenID Connect configurado para iniciar sesión
+// (Google, Azure AD, Keycloak...).
+type SSOProvider struct {
+	Name     string
+	OAuth2   *oauth2.Config // client id, secret, redirect y scopes; los endpoints salen del discovery
+	Provider *oidc.Provider
+
+	// RoleClaim es la ruta del claim con los roles o grupos (ej: "groups" o "realm_access.roles").
+	// Si está configurado el proveedor manda: el rol se recalcula en cada login.
+	RoleClaim string
+	// RoleMap traduce valores del claim (sin distinguir mayúsculas) a domain.Role*.
+	RoleMap map[string]string
+	// DefaultRole es el rol cuando ningún valor del claim está en RoleMap (employee si está vacío).
+	DefaultRole string
+	// JIT crea el usuario en su primer login si no existe ninguno con ese email.
+	JIT bool
+	// Domains son los dominios de email que solo pueden entrar por este proveedor. El
+	// proveedor es la autoridad de esos emails aunque no mande email_verified.
+	Domains []string
+}
+
+// OIDCService inicia sesión con proveedores OpenID Connect: authorization code con PKCE,
+// state de un solo uso y nonce en el ID token. Una vez identificado el usuario emite
+// nuestros propios tokens a través de AuthService.
+type OIDCService struct {
+	providers    map[string]*SSOProvider
+	stateRepo    domain.OAuthStateRepo
+	identityRepo domain.UserIdentityRepo
+	userRepo     domain.UserRepo
+	authSvc      *AuthService
+}
+
+// NewOIDCService valida los proveedores y registra en authSvc los dominios que cada uno
+// impone, así esas cuentas dejan de poder entrar con contraseña.
+func NewOIDCService(
+	stateRepo domain.OAuthStateRepo,
+	identityRepo domain.UserIdentityRepo,
+	userRepo domain.UserRepo,
+	authSvc *AuthService,
+	providers []*SSOProvider,
+) (*OIDCService, error) {
+	s := &OIDCService{
+		providers:    make(map[string]*SSOProvider, len(providers)),
+		stateRepo:    stateRepo,
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		authSvc:      authSvc,
+	}
+
+	enforced := make(map[string]string)
+	for _, p := range providers {
+		if p.Name == "" || p.OAuth2 == nil || p.OAuth2.ClientID == "" || p.Provider == nil {
+			return nil, fmt.Errorf("oidc provider %q: name, client id and issuer are required", p.Name)
+		}
+		if _, ok := s.providers[p.Name]; ok {
+			return nil, fmt.Errorf("oidc provider %q configured twice", p.Name)
+		}
+		if p.DefaultRole == "" {
+			p.DefaultRole = domain.RoleEmployee
+		}
+		if _, ok := roleRank[p.DefaultRole]; !ok {
+			return nil, fmt.Errorf("oidc provider %q: invalid default role %q", p.Name, p.DefaultRole)
+		}
+		roleMap := make(map[string]string, len(p.RoleMap))
+		for value, role := range p.RoleMap {
+			if _, ok := roleRank[role]; !ok {
+				return nil, fmt.Errorf("oidc provider %q: invalid role %q for %q", p.Name, role, value)
+			}
+			roleMap[strings.ToLower(value)] = role
+		}
+		p.RoleMap = roleMap
+		for i, d := range p.Domains {
+			d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "@"))
+			if other, ok := enforced[d]; ok {
+				return nil, fmt.Errorf("email domain %q is enforced by both %q and %q", d, other, p.Name)
+			}
+			enforced[d] = p.Name
+			p.Domains[i] = d
+		}
+		s.providers[p.Name] = p
+	}
+
+	for d, name := range enforced {
+		authSvc.RequireSSO(d, name)
+	}
+	return s, nil
+}
+
+// Providers devuelve los nombres de los proveedores configurados.
+func (s *OIDCService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// AuthURL registra un login nuevo con el proveedor y devuelve la URL a la que hay que
+// redirigir al usuario. Un proveedor no configurado es ErrNotFound.
+func (s *OIDCService) AuthURL(ctx context.Context, name string) (string, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return "", domain.ErrNotFound
+	}
+	config, err := p.oauthConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	if err := s.stateRepo.Create(ctx, &domain.OAuthState{
+		ID:        hashToken(state),
+		Provider:  p.Name,
+		Nonce:     nonce,
+		Verifier:  verifier,
+		ExpiresAt: time.Now().Add(oauthStateTTL),
+	}); err != nil {
+		return "", fmt.Errorf("failed to store oauth state: %w", err)
+	}
+
+	return config.AuthCodeURL(state,
+		oauth2.S256ChallengeOption(verifier),
+		oauth2.SetAuthURLParam("nonce", nonce),
+	), nil
+}
+
+// Callback completa el login: consume el state, canjea el código con el verifier de
+// PKCE, valida el ID token y su nonce y busca (o crea) al usuario. Como en el login con
+// contraseña, si el usuario tiene (o su rol exige) 2FA el resultado trae el token
+// intermedio; si no, los tokens de una sesión nueva del cliente.
+func (s *OIDCService) Callback(ctx context.Context, name, code, state string, client domain.ClientInfo) (*LoginResult, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	if code == "" || state == "" {
+		return nil, fmt.Errorf("%w: code and state are required", domain.ErrInvalidInput)
+	}
+
+	pending, err := s.stateRepo.Consume(ctx, hashToken(state))
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, domain.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if pending.Provider != p.Name || time.Now().After(pending.ExpiresAt) {
+		return nil, domain.ErrInvalidToken
+	}
+
+	config, err := p.oauthConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	exchangeCtx := context.WithValue(ctx, oauth2.HTTPClient, p.Provider.HTTPClient())
+	token, err := config.Exchange(exchangeCtx, code, oauth2.VerifierOption(pending.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("%w: code exchange failed: %v", domain.ErrInvalidToken, err)
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, fmt.Errorf("%w: no id_token in token response", domain.ErrInvalidToken)
+	}
+
+	claims, err := p.Provider.Verify(ctx, rawIDToken, config.ClientID, pending.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidToken, err)
+	}
+
+	user, err := s.resolveUser(ctx, p, claims)
+	if err != nil {
+		return nil, err
+	}
+	return s.authSvc.ExternalLogin(ctx, user, client)
+}
+
+// resolveUser busca al usuario de una identidad externa: primero por la identidad ya
+// vinculada, para Google también por users.google_id, y si no, por email, siempre que el
+// email sea confiable. En esos casos se vincula la cuenta para que los próximos logins la
+// encuentren aunque cambie el email. Si no hay usuario y el proveedor tiene JIT, se crea.
+func (s *OIDCService) resolveUser(ctx context.Context, p *SSOProvider, claims *oidc.Claims) (*domain.User, error) {
+	user, err := s.identityRepo.GetUser(ctx, p.Name, claims.Subject)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
+	}
+	if user == nil && p.Name == ProviderGoogle {
+		if user, err = s.googleIDUser(ctx, claims); err != nil {
+			return nil, err
+		}
+	}
+
+	if user == nil {
+		if !p.trustsEmail(claims) {
+			return nil, domain.ErrForbidden
+		}
+		if err := s.checkEnforced(p, claims.Email); err != nil {
+			return nil, err
+		}
+		identity := &domain.UserIdentity{Provider: p.Name, Subject: claims.Subject, Email: claims.Email}
+
+		user, err = s.userRepo.GetByEmail(ctx, claims.Email)
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			user, err = s.provision(ctx, p, claims, identity)
+			if err != nil {
+				return nil, err
+			}
+		case err != nil:
+			return nil, err
+		default:
+			identity.UserID = user.ID
+			linked, err := s.identityRepo.Link(ctx, identity)
+			if err != nil {
+				return nil, err
+			}
+			if !linked {
+				// El usuario ya tiene otra cuenta de este proveedor vinculada
+				return nil, domain.ErrForbidden
+			}
+			log.Printf("linked %s account to user %d", p.Name, user.ID)
+		}
+	}
+
+	if err := s.checkEnforced(p, user.Email); err != nil {
+		return nil, err
+	}
+	if user.Active != nil && !*user.Active {
+		return nil, domain.ErrForbidden
+	}
+
+	if p.RoleClaim != "" && user.Role != domain.RoleSuperAdmin {
+		if role := p.role(claims); role != user.Role {
+			log.Printf("%s changed role of user %d from %s to %s", p.Name, user.ID, user.Role, role)
+			user.Role = role
+			if err := s.userRepo.Update(ctx, &domain.User{ID: user.ID, Role: role}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return user, nil
+}
+
+// googleIDUser busca al usuario con el google_id del login con Google anterior a las
+// identidades (o cargado después por un admin) y completa su identidad vinculada. Devuelve
+// nil si no hay ninguno.
+func (s *OIDCService) googleIDUser(ctx context.Context, claims *oidc.Claims) (*domain.User, error) {
+	user, err := s.userRepo.GetByGoogleID(ctx, claims.Subject)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	linked, err := s.identityRepo.Link(ctx, &domain.UserIdentity{
+		UserID:   user.ID,
+		Provider: ProviderGoogle,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !linked {
+		// El usuario ya tiene otra cuenta de Google vinculada
+		return nil, domain.ErrForbidden
+	}
+	log.Printf("linked google account to user %d by google_id", user.ID)
+	return user, nil
+}
+
+// provision crea el usuario en su primer login (JIT). Solo si el proveedor lo permite y,
+// cuando impone dominios, para emails de esos dominios.
+func (s *OIDCService) provision(ctx context.Context, p *SSOProvider, claims *oidc.Claims, identity *domain.UserIdentity) (*domain.User, error) {
+	if !p.JIT || (len(p.Domains) > 0 && !p.ownsEmail(claims.Email)) {
+		return nil, domain.ErrForbidden
+	}
+
+	name, lastName := claims.GivenName, claims.FamilyName
+	if name == "" {
+		name, lastName, _ = strings.Cut(strings.TrimSpace(claims.Name), " ")
+	}
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+	active := true
+	user := &domain.User{
+		Name:     name,
+		LastName: lastName,
+		Email:    claims.Email,
+		Role:     p.role(claims),
+		Active:   &active,
+	}
+	if err := s.identityRepo.CreateUser(ctx, user, identity); err != nil {
+		return nil, fmt.Errorf("failed to provision user: %w", err)
+	}
+	log.Printf("provisioned user %d from %s", user.ID, p.Name)
+	return user, nil
+}
+
+// checkEnforced rechaza emails de un dominio impuesto a otro proveedor.
+func (s *OIDCService) checkEnforced(p *SSOProvider, email string) error {
+	if enforced := s.authSvc.SSOProvider(email); enforced != "" && enforced != p.Name {
+		return fmt.Errorf("%w: use %s", domain.ErrSSORequired, enforced)
+	}
+	return nil
+}
+
+// PurgeExpiredStates borra los logins que nunca volvieron del proveedor.
+func (s *OIDCService) PurgeExpiredStates(ctx context.Context) (int64, error) {
+	return s.stateRepo.DeleteExpired(ctx, time.Now())
+}
+
+// oauthConfig completa la configuración con los endpoints del discovery.
+func (p *SSOProvider) oauthConfig(ctx context.Context) (*oauth2.Config, error) {
+	endpoint, err := p.Provider.Endpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config := *p.OAuth2
+	config.Endpoint = endpoint
+	return &config, nil
+}
+
+// trustsEmail indica si el email del token sirve para encontrar o crear al usuario.
+func (p *SSOProvider) trustsEmail(claims *oidc.Claims) bool {
+	if claims.Email == "" {
+		return false
+	}
+	return claims.EmailVerified || p.ownsEmail(claims.Email)
+}
+
+func (p *SSOProvider) ownsEmail(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	d := strings.ToLower(email[at+1:])
+	for _, own := range p.Domains {
+		if d == own {
+			return true
+		}
+	}
+	return false
+}
+
+// role calcula el rol a partir del claim configurado; sin coincidencias, DefaultRole.
+func (p *SSOProvider) role(claims *oidc.Claims) string {
+	role := p.DefaultRole
+	if p.RoleClaim == "" {
+		return role
+	}
+	best := 0
+	for _, value := range claimValues(claims.Raw, p.RoleClaim) {
+		if mapped, ok := p.RoleMap[strings.ToLower(value)]; ok && roleRank[mapped] > best {
+			role, best = mapped, roleRank[mapped]
+		}
+	}
+	return role
+}
+
+// claimValues sigue una ruta con puntos dentro de los claims y devuelve los strings que
+// encuentra, sea un valor suelto o una lista.
+func claimValues(raw map[string]any, path string) []string {
+	var current any = raw
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+
+	switch v := current.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if str, ok := item.(string); ok {
+				values = append(values, str)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/storage"
+)
+
+// ReceiptService concentra las operaciones sobre comprobantes que no dependen
+// de si pertenecen a un ingreso o a un gasto (por ejemplo, las URLs firmadas).
+type ReceiptService struct {
+	receiptRepo domain.ReceiptRepo
+	incomeRepo  domain.IncomeRepo
+	expenseRepo domain.ExpenseRepo
+	userRepo    domain.UserRepo
+	fileStorage domain.FileStorage
+	signer      *storage.URLSigner
+	watermarker *ReceiptWatermarker
+}
+
+func NewReceiptService(
+	r domain.ReceiptRepo,
+	i domain.IncomeRepo,
+	e domain.ExpenseRepo,
+	u domain.UserRepo,
+	fS domain.FileStorage,
+	signer *storage.URLSigner,
+	wm *ReceiptWatermarker,
+) *ReceiptService {
+	return &ReceiptService{
+		receiptRepo: r,
+		incomeRepo:  i,
+		expenseRepo: e,
+		userRepo:    u,
+		fileStorage: fS,
+		signer:      signer,
+		watermarker: wm,
+	}
+}
+
+// SignedURL es una URL de descarga temporal emitida para un usuario concreto.
+type SignedURL struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// IncomeURL emite una URL firmada para el comprobante de un ingreso.
+func (s *ReceiptService) IncomeURL(ctx context.Context, user *domain.User, incomeID uint) (*SignedURL, error) {
+	income, err := s.incomeRepo.GetByID(ctx, incomeID)
+	if err != nil {
+		return nil, err
+	}
+	if !domain.CanViewTransaction(user, income.CreatedBy) {
+		return nil, domain.ErrForbidden
+	}
+	return s.sign(income.Receipt.ID, user.ID)
+}
+
+// ExpenseURL emite una URL firmada para el comprobante de un gasto.
+func (s *ReceiptService) ExpenseURL(ctx context.Context, user *domain.User, expenseID uint) (*SignedURL, error) {
+	expense, err := s.expenseRepo.GetByID(ctx, expenseID)
+	if err != nil {
+		return nil, err
+	}
+	if !domain.CanViewTransaction(user, expense.CreatedBy) {
+		return nil, domain.ErrForbidden
+	}
+	return s.sign(expense.Receipt.ID, user.ID)
+}
+
+func (s *ReceiptService) sign(receiptID, userID uint) (*SignedURL, error) {
+	if receiptID == 0 {
+		return nil, domain.ErrNotFound
+	}
+	signature, expiresAt := s.signer.Sign(receiptID, userID)
+	return &SignedURL{
+		URL: fmt.Sprintf("/api/v1/files/receipts/%d?uid=%d&exp=%d&sig=%s",
+			receiptID, userID, expiresAt.Unix(), signature),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// OpenSigned valida una URL firmada y abre el archivo del comprobante.
+// Además de la firma y la expiración vuelve a comprobar que el usuario siga activo
+// y que todavía tenga permiso sobre la transacción a la que pertenece el comprobante.
+// La marca de agua se aplica según el rol del usuario para el que se emitió la URL.
+func (s *ReceiptService) OpenSigned(
+	ctx context.Context,
+	receiptID, userID uint,
+	expires int64,
+	signature string,
+) (*domain.Receipt, io.ReadCloser, error) {
+	if err := s.signer.Verify(receiptID, userID, expires, signature); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, nil, domain.ErrForbidden
+		}
+		return nil, nil, err
+	}
+	if user.Active != nil && !*user.Active {
+		return nil, nil, domain.ErrForbidden
+	}
+
+	receipt, err := s.receiptRepo.GetByID(ctx, receiptID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	createdBy, err := s.ownerOf(ctx, receipt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !domain.CanViewTransaction(user, createdBy) {
+		return nil, nil, domain.ErrForbidden
+	}
+
+	if receipt.Quarantined {
+		return nil, nil, domain.ErrQuarantined
+	}
+
+	file, err := s.fileStorage.OpenPDF(ctx, receipt.RelPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err = s.watermarker.Wrap(file, user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return receipt, file, nil
+}
+
+// applyInspection copia al receipt el veredicto del antivirus y el resultado
+// de la inspección del PDF (incluido el texto extraído) del archivo guardado.
+func applyInspection(receipt *domain.Receipt, stored *domain.StoredFile) {
+	receipt.ScanStatus = stored.ScanStatus
+	receipt.ScanResult = stored.ScanResult
+	receipt.ScannedAt = stored.ScannedAt
+	receipt.Quarantined = stored.Quarantined
+	receipt.PageCount = stored.PageCount
+	receipt.Sanitized = stored.Sanitized
+	receipt.Content = stored.Text
+}
+
+// ownerOf devuelve el creador de la transacción (no eliminada) dueña del comprobante.
+func (s *ReceiptService) ownerOf(ctx context.Context, receipt *domain.Receipt) (uint, error) {
+	switch {
+	case receipt.IncomeID != nil:
+		income, err := s.incomeRepo.GetByID(ctx, *receipt.IncomeID)
+		if err != nil {
+			return 0, err
+		}
+		return income.CreatedBy, nil
+	case receipt.ExpenseID != nil:
+		expense, err := s.expenseRepo.GetByID(ctx, *receipt.ExpenseID)
+		if err != nil {
+			return 0, err
+		}
+		return expense.CreatedBy, nil
+	}
+	return 0, domain.ErrNotFound
+}
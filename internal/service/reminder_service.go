@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+)
+
+// ReminderService lista las transacciones con el comprobante pendiente y genera
+// recordatorios para quien las creó cuando pasan los días configurados sin él.
+// Mientras siga pendiente se vuelve a recordar cada vez que pasa ese mismo plazo.
+type ReminderService struct {
+	reminderRepo domain.ReminderRepo
+	after        time.Duration
+}
+
+// NewReminderService crea el servicio; con days <= 0 no se generan recordatorios.
+func NewReminderService(r domain.ReminderRepo, days int) *ReminderService {
+	return &ReminderService{
+		reminderRepo: r,
+		after:        time.Duration(days) * 24 * time.Hour,
+	}
+}
+
+// Enabled indica si hay que programar SendReminders.
+func (s *ReminderService) Enabled() bool {
+	return s.after > 0
+}
+
+// ListPending devuelve los pendientes de userID (0 = el propio user). Solo los roles
+// que ven todas las transacciones pueden consultar los de otro usuario.
+func (s *ReminderService) ListPending(ctx context.Context, user *domain.User, userID uint) ([]domain.PendingReceipt, error) {
+	if userID == 0 {
+		userID = user.ID
+	}
+	if userID != user.ID && !domain.CanViewAllTransactions(user) {
+		return nil, domain.ErrForbidden
+	}
+	return s.reminderRepo.ListPending(ctx, userID)
+}
+
+// ListReminders devuelve los recordatorios del usuario, los no leídos primero.
+func (s *ReminderService) ListReminders(ctx context.Context, user *domain.User) ([]domain.Reminder, error) {
+	return s.reminderRepo.ListByUser(ctx, user.ID)
+}
+
+// MarkRead marca como leído un recordatorio del usuario.
+func (s *ReminderService) MarkRead(ctx context.Context, user *domain.User, id uint) error {
+	return s.reminderRepo.MarkRead(ctx, user.ID, id)
+}
+
+// SendReminders genera un recordatorio por cada transacción que lleva pendiente más de los
+// días configurados y no fue recordada en ese plazo. Devuelve cuántos generó.
+func (s *ReminderService) SendReminders(ctx context.Context) (int, error) {
+	if !s.Enabled() {
+		return 0, fmt.Errorf("%w: reminders are disabled", domain.ErrInvalidInput)
+	}
+
+	now := time.Now()
+	due, err := s.reminderRepo.ListDue(ctx, now.Add(-s.after))
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, item := range due {
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+		reminder := &domain.Reminder{
+			UserID:        item.CreatedBy,
+			Kind:          item.Kind,
+			TransactionID: item.ID,
+			Message:       reminderMessage(item, now),
+			CreatedAt:     now,
+		}
+		ok, err := s.reminderRepo.Remind(ctx, item, reminder)
+		if err != nil {
+			// Un fallo puntual no frena al resto; se reintenta en la próxima pasada
+			log.Printf("failed to remind pending receipt of %s %d: %v", item.Kind, item.ID, err)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+func reminderMessage(item domain.PendingReceipt, now time.Time) string {
+	days := int(now.Sub(item.CreatedAt).Hours() / 24)
+	msg := fmt.Sprintf("Receipt still missing for %s #%d (%.2f, %s) after %d days: %s",
+		item.Kind, item.ID, item.Amount, item.Date.Format("2006-01-02"), days, item.Description)
+	// La columna admite 255 caracteres
+	if runes := []rune(msg); len(runes) > 255 {
+		msg = string(runes[:252]) + "..."
+	}
+	return msg
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+)
+
+// Límites de la búsqueda.
+const (
+	searchDefaultLimit = 20
+	searchMaxLimit     = 100
+	searchMaxLength    = 200
+)
+
+// SearchService busca en ingresos, gastos y el texto de sus comprobantes.
+type SearchService struct {
+	searchRepo domain.SearchRepo
+}
+
+func NewSearchService(s domain.SearchRepo) *SearchService {
+	return &SearchService{
+		searchRepo: s,
+	}
+}
+
+// Search devuelve las transacciones visibles para user que coinciden con query.Text,
+// las más relevantes primero. Los usuarios sin permiso global solo buscan en las propias.
+func (s *SearchService) Search(ctx context.Context, user *domain.User, query domain.SearchQuery) ([]domain.SearchResult, error) {
+	query.Text = strings.TrimSpace(query.Text)
+	if query.Text == "" {
+		return nil, fmt.Errorf("%w: search text is required", domain.ErrInvalidInput)
+	}
+	if utf8.RuneCountInString(query.Text) > searchMaxLength {
+		return nil, fmt.Errorf("%w: search text is too long", domain.ErrInvalidInput)
+	}
+	if query.Kind != "" && query.Kind != KindIncome && query.Kind != KindExpense {
+		return nil, fmt.Errorf("%w: kind must be %q or %q", domain.ErrInvalidInput, KindIncome, KindExpense)
+	}
+	if query.Limit <= 0 {
+		query.Limit = searchDefaultLimit
+	}
+	query.Limit = min(query.Limit, searchMaxLimit)
+	query.Offset = max(query.Offset, 0)
+
+	if !domain.CanViewAllTransactions(user) {
+		query.CreatedBy = user.ID
+	}
+
+	results, err := s.searchRepo.Search(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+)
+
+// TrashService vacía la papelera: borra definitivamente los ingresos y gastos que llevan
+// en ella más que el período de retención, junto con sus comprobantes.
+type TrashService struct {
+	incomeRepo  domain.IncomeRepo
+	expenseRepo domain.ExpenseRepo
+	fileStorage domain.FileStorage
+	retention   time.Duration
+}
+
+func NewTrashService(
+	i domain.IncomeRepo,
+	e domain.ExpenseRepo,
+	fS domain.FileStorage,
+	retention time.Duration,
+) *TrashService {
+	return &TrashService{
+		incomeRepo:  i,
+		expenseRepo: e,
+		fileStorage: fS,
+		retention:   retention,
+	}
+}
+
+// PurgeReport resume una pasada de Purge.
+type PurgeReport struct {
+	Cutoff          time.Time `json:"cutoff"`
+	Incomes         int       `json:"incomes"`
+	Expenses        int       `json:"expenses"`
+	Files           int       `json:"files"`
+	FilesMissing    []string  `json:"files_missing,omitempty"`
+	FilesNotDeleted []string  `json:"files_not_deleted,omitempty"`
+}
+
+// Purge borra lo que fue a la papelera antes de ahora menos la retención.
+// Con retención 0 no hace nada. Un archivo que no se pudo borrar no frena la pasada:
+// queda en el reporte y storage-check lo detecta después como huérfano.
+func (s *TrashService) Purge(ctx context.Context) (*PurgeReport, error) {
+	if s.retention <= 0 {
+		return nil, fmt.Errorf("%w: trash retention is not configured", domain.ErrInvalidInput)
+	}
+	report := &PurgeReport{Cutoff: time.Now().Add(-s.retention)}
+
+	incomes, err := s.incomeRepo.ListDeletedBefore(ctx, report.Cutoff)
+	if err != nil {
+		return report, fmt.Errorf("cannot list deleted incomes: %w", err)
+	}
+	for _, income := range incomes {
+		ok, err := s.incomeRepo.Purge(ctx, income.ID, report.Cutoff)
+		if err != nil {
+			return report, fmt.Errorf("cannot purge income %d: %w", income.ID, err)
+		}
+		if ok {
+			report.Incomes++
+			s.deleteFile(ctx, report, &income.Receipt)
+		}
+	}
+
+	expenses, err := s.expenseRepo.ListDeletedBefore(ctx, report.Cutoff)
+	if err != nil {
+		return report, fmt.Errorf("cannot list deleted expenses: %w", err)
+	}
+	for _, expense := range expenses {
+		ok, err := s.expenseRepo.Purge(ctx, expense.ID, report.Cutoff)
+		if err != nil {
+			return report, fmt.Errorf("cannot purge expense %d: %w", expense.ID, err)
+		}
+		if ok {
+			report.Expenses++
+			s.deleteFile(ctx, report, &expense.Receipt)
+		}
+	}
+	return report, nil
+}
+
+// deleteFile borra el archivo del comprobante. Un archivo que ya no estaba no cuenta como
+// borrado: queda en FilesMissing para que se note.
+func (s *TrashService) deleteFile(ctx context.Context, report *PurgeReport, receipt *domain.Receipt) {
+	if receipt.RelPath == "" {
+		return
+	}
+	err := deleteStoredFile(ctx, s.fileStorage, receipt.RelPath, receipt.Quarantined)
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		log.Printf("receipt file %s was already missing on purge", receipt.RelPath)
+		report.FilesMissing = append(report.FilesMissing, receipt.RelPath)
+	case err != nil:
+		log.Printf("failed to remove receipt file %s after purge: %v", receipt.RelPath, err)
+		report.FilesNotDeleted = append(report.FilesNotDeleted, receipt.RelPath)
+	default:
+		report.Files++
+	}
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/totp"
+)
+
+// recoveryCodeCount es cuántos códigos de recuperación se entregan en cada tanda.
+const recoveryCodeCount = 10
+
+// TwoFactorStatus resume el 2FA de un usuario.
+type TwoFactorStatus struct {
+	Enabled           bool  `json:"enabled"`
+	Required          bool  `json:"required"`
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+}
+
+// TwoFactorService gestiona el segundo factor por TOTP: alta con URI otpauth:// para el
+// QR, confirmación con el primer código, códigos de recuperación de un solo uso y la
+// política de qué roles deben usarlo.
+type TwoFactorService struct {
+	repo   domain.TwoFactorRepo
+	issuer string
+}
+
+// NewTwoFactorService crea el servicio; issuer es el nombre que muestra la app de autenticación.
+func NewTwoFactorService(repo domain.TwoFactorRepo, issuer string) *TwoFactorService {
+	return &TwoFactorService{repo: repo, issuer: issuer}
+}
+
+// Status devuelve si el usuario tiene 2FA, si su rol lo exige y cuántos códigos de
+// recuperación le quedan.
+func (s *TwoFactorService) Status(ctx context.Context, user *domain.User) (*TwoFactorStatus, error) {
+	enabled, err := s.Enabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	required, err := s.repo.IsRequired(ctx, user.Role)
+	if err != nil {
+		return nil, err
+	}
+	status := &TwoFactorStatus{Enabled: enabled, Required: required}
+	if enabled {
+		if status.RecoveryCodesLeft, err = s.repo.CountRecoveryCodes(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// Enabled indica si el usuario tiene el 2FA confirmado.
+func (s *TwoFactorService) Enabled(ctx context.Context, userID uint) (bool, error) {
+	tf, err := s.repo.Get(ctx, userID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return tf.Enabled(), nil
+}
+
+// Required indica si el rol debe usar 2FA.
+func (s *TwoFactorService) Required(ctx context.Context, role string) (bool, error) {
+	return s.repo.IsRequired(ctx, role)
+}
+
+// Setup genera un secreto nuevo y devuelve el secreto y la URI para el QR. El 2FA no
+// queda activo hasta Confirm; repetir Setup antes de confirmar reemplaza el secreto.
+func (s *TwoFactorService) Setup(ctx context.Context, user *domain.User) (string, string, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.repo.Begin(ctx, &domain.TwoFactor{UserID: user.ID, Secret: secret}); err != nil {
+		return "", "", err
+	}
+	return secret, totp.URI(s.issuer, user.Email, secret), nil
+}
+
+// Confirm activa el 2FA con el primer código de la app y devuelve los códigos de
+// recuperación, que no se vuelven a mostrar.
+func (s *TwoFactorService) Confirm(ctx context.Context, userID uint, code string) ([]string, error) {
+	tf, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tf.Enabled() {
+		return nil, domain.ErrTwoFactorEnabled
+	}
+	step, ok := totp.Validate(tf.Secret, code, time.Now())
+	if !ok {
+		return nil, domain.ErrInvalidCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := s.repo.Enable(ctx, userID, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, domain.ErrTwoFactorEnabled
+	}
+	log.Printf("two-factor authentication enabled for user %d", userID)
+	return codes, nil
+}
+
+// Verify acepta un código TOTP o uno de recuperación. Cada código sirve una sola vez.
+func (s *TwoFactorService) Verify(ctx context.Context, userID uint, code string) error {
+	tf, err := s.repo.Get(ctx, userID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.ErrInvalidCode
+	}
+	if err != nil {
+		return err
+	}
+	if !tf.Enabled() {
+		return domain.ErrInvalidCode
+	}
+
+	if step, ok := totp.Validate(tf.Secret, code, time.Now()); ok {
+		used, err := s.repo.UseStep(ctx, userID, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			// Código ya usado (o de un intervalo anterior al último aceptado)
+			return domain.ErrInvalidCode
+		}
+		return nil
+	}
+
+	used, err := s.repo.UseRecoveryCode(ctx, userID, hashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return domain.ErrInvalidCode
+	}
+	log.Printf("user %d signed in with a recovery code", userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes invalida los códigos de recuperación y entrega otros; pide un
+// código válido para que no lo haga cualquiera con la sesión abierta.
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID uint, code string) ([]string, error) {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable quita el 2FA del usuario con un código válido. Si su rol lo exige no puede.
+func (s *TwoFactorService) Disable(ctx context.Context, user *domain.User, code string) error {
+	required, err := s.repo.IsRequired(ctx, user.Role)
+	if err != nil {
+		return err
+	}
+	if required {
+		return domain.ErrForbidden
+	}
+	if err := s.Verify(ctx, user.ID, code); err != nil {
+		return err
+	}
+	log.Printf("two-factor authentication disabled for user %d", user.ID)
+	return s.repo.Delete(ctx, user.ID)
+}
+
+// Reset quita el 2FA de un usuario que perdió la app y los códigos (lo hace un admin).
+// Si su rol lo exige, tendrá que darlo de alta de nuevo en el próximo login.
+func (s *TwoFactorService) Reset(ctx context.Context, userID uint) error {
+	log.Printf("two-factor authentication reset for user %d", userID)
+	return s.repo.Delete(ctx, userID)
+}
+
+// Policies devuelve para cada rol si exige 2FA.
+func (s *TwoFactorService) Policies(ctx context.Context) ([]domain.TwoFactorPolicy, error) {
+	stored, err := s.repo.ListPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byRole := make(map[string]domain.TwoFactorPolicy, len(stored))
+	for _, p := range stored {
+		byRole[p.Role] = p
+	}
+
+	roles := []string{domain.RoleSuperAdmin, domain.RoleAdmin, domain.RoleAccountant, domain.RoleEmployee}
+	policies := make([]domain.TwoFactorPolicy, 0, len(roles))
+	for _, role := range roles {
+		p, ok := byRole[role]
+		if !ok {
+			p = domain.TwoFactorPolicy{Role: role}
+		}
+		policies = append(policies, p)
+	}
+	return policies, nil
+}
+
+// SetRequired cambia si un rol exige 2FA. Los usuarios de ese rol sin 2FA lo tendrán que
+// dar de alta en su próximo login.
+func (s *TwoFactorService) SetRequired(ctx context.Context, role string, required bool) (*domain.TwoFactorPolicy, error) {
+	if !domain.IsValidRole(role) {
+		return nil, fmt.Errorf("%w: unknown role %q", domain.ErrInvalidInput, role)
+	}
+	policy := &domain.TwoFactorPolicy{Role: role, Required: required, UpdatedAt: time.Now()}
+	if err := s.repo.SetPolicy(ctx, policy); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// newRecoveryCodes genera los códigos (10 caracteres base32, "xxxxx-xxxxx") y sus hashes.
+func newRecoveryCodes() ([]string, []string, error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("cannot generate recovery code: %w", err)
+		}
+		raw := strings.ToLower(enc.EncodeToString(b))[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashToken(raw)
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode acepta el código con o sin guion, espacios o mayúsculas.
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,364 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+)
+
+// UploadService maneja las subidas reanudables (tus). Los bytes parciales se acumulan
+// en un directorio local; cuando llega el último se guardan a través del FileStorage
+// (antivirus, validación de PDF, cifrado) y el upload queda listo para que un ingreso
+// o gasto lo reclame por su ID.
+//
+// Los parciales viven en el disco de la instancia que recibe la subida: con varias
+// instancias detrás de un balanceador hace falta afinidad de sesión para /uploads.
+type UploadService struct {
+	uploadRepo  domain.UploadRepo
+	fileStorage domain.FileStorage
+	dir         string
+	maxSize     int64
+	ttl         time.Duration
+	locks       sync.Map // id -> *sync.Mutex, serializa los PATCH de un mismo upload
+}
+
+func NewUploadService(
+	u domain.UploadRepo,
+	fS domain.FileStorage,
+	dir string,
+	maxSize int64,
+	ttl time.Duration,
+) (*UploadService, error) {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "gestor-uploads")
+	}
+	if ttl <= 0 {
+		ttl = 24 * time.Hour
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("cannot create upload dir: %w", err)
+	}
+	return &UploadService{
+		uploadRepo:  u,
+		fileStorage: fS,
+		dir:         dir,
+		maxSize:     maxSize,
+		ttl:         ttl,
+	}, nil
+}
+
+// MaxSize es el tamaño máximo aceptado para un upload (0 = sin límite).
+func (s *UploadService) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Create registra un upload nuevo de length bytes para el usuario.
+func (s *UploadService) Create(ctx context.Context, userID uint, length int64, fileName string) (*domain.Upload, error) {
+	if length <= 0 {
+		return nil, fmt.Errorf("%w: upload length must be greater than 0", domain.ErrInvalidInput)
+	}
+	if s.maxSize > 0 && length > s.maxSize {
+		return nil, domain.ErrFileTooLarge
+	}
+
+	id, err := newUploadID()
+	if err != nil {
+		return nil, err
+	}
+
+	// El parcial se crea vacío para que los PATCH siempre hagan append
+	part, err := os.OpenFile(s.partPath(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create upload file: %w", err)
+	}
+	_ = part.Close()
+
+	upload := &domain.Upload{
+		ID:        id,
+		UserID:    userID,
+		FileName:  fileName,
+		Length:    length,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+	if err := s.uploadRepo.Create(ctx, upload); err != nil {
+		_ = os.Remove(s.partPath(id))
+		return nil, fmt.Errorf("failed to create upload: %w", err)
+	}
+	return upload, nil
+}
+
+// Get devuelve un upload del usuario. Los vencidos se tratan como inexistentes.
+func (s *UploadService) Get(ctx context.Context, userID uint, id string) (*domain.Upload, error) {
+	upload, err := s.uploadRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if upload.UserID != userID || time.Now().After(upload.ExpiresAt) {
+		return nil, domain.ErrNotFound
+	}
+	return upload, nil
+}
+
+// WriteChunk agrega a partir de offset los bytes de r. Si la conexión se corta se guarda
+// lo recibido hasta ese momento, que es lo que permite reanudar. Al completarse el upload
+// el archivo se guarda en el FileStorage; si es rechazado el upload se descarta.
+func (s *UploadService) WriteChunk(
+	ctx context.Context,
+	userID uint,
+	id string,
+	offset int64,
+	r io.Reader,
+) (*domain.Upload, error) {
+	lock := s.lock(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	upload, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Offset != offset {
+		return upload, domain.ErrOffsetMismatch
+	}
+	if upload.CompletedAt != nil {
+		return upload, nil
+	}
+
+	part, err := os.OpenFile(s.partPath(id), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open upload file: %w", err)
+	}
+	// Por si un PATCH anterior escribió bytes que no llegaron a registrarse
+	if err := part.Truncate(upload.Offset); err != nil {
+		_ = part.Close()
+		return nil, fmt.Errorf("cannot truncate upload file: %w", err)
+	}
+
+	remaining := upload.Length - upload.Offset
+	written, copyErr := io.Copy(part, io.LimitReader(r, remaining))
+	if closeErr := part.Close(); copyErr == nil && closeErr != nil {
+		copyErr = closeErr
+	}
+
+	if written > 0 {
+		if err := s.uploadRepo.UpdateOffset(ctx, id, upload.Offset, upload.Offset+written); err != nil {
+			return nil, err
+		}
+		upload.Offset += written
+	}
+	if copyErr != nil {
+		return upload, fmt.Errorf("upload interrupted: %w", copyErr)
+	}
+
+	if upload.Offset < upload.Length {
+		return upload, nil
+	}
+	return upload, s.finish(ctx, upload)
+}
+
+// finish guarda el archivo completo en el FileStorage y borra el parcial.
+func (s *UploadService) finish(ctx context.Context, upload *domain.Upload) error {
+	part, err := os.Open(s.partPath(upload.ID))
+	if err != nil {
+		return fmt.Errorf("cannot open upload file: %w", err)
+	}
+	stored, err := s.fileStorage.SavePDF(ctx, part, upload.Length)
+	_ = part.Close()
+	if err != nil {
+		// El archivo fue rechazado (o no se pudo guardar): reintentar el último chunk no sirve
+		s.discard(ctx, upload)
+		return fmt.Errorf("failed to save pdf: %w", err)
+	}
+
+	now := time.Now()
+	upload.SetStored(stored)
+	upload.CompletedAt = &now
+	if err := s.uploadRepo.Update(ctx, upload); err != nil {
+		if rmErr := deleteStoredFile(ctx, s.fileStorage, stored.RelPath, stored.Quarantined); rmErr != nil {
+			log.Printf("failed to remove file after upload update error: %v", rmErr)
+		}
+		return fmt.Errorf("failed to complete upload: %w", err)
+	}
+
+	if err := os.Remove(s.partPath(upload.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("failed to remove upload part: %v", err)
+	}
+	return nil
+}
+
+// Terminate cancela un upload del usuario y borra lo que se haya guardado.
+func (s *UploadService) Terminate(ctx context.Context, userID uint, id string) error {
+	lock := s.lock(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	upload, err := s.Get(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	s.discard(ctx, upload)
+	return nil
+}
+
+// ExpireStale borra los uploads vencidos: el parcial, el archivo guardado si se completó
+// y el registro. Devuelve cuántos se eliminaron.
+func (s *UploadService) ExpireStale(ctx context.Context) (int, error) {
+	uploads, err := s.uploadRepo.ListExpired(ctx, time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("cannot list expired uploads: %w", err)
+	}
+
+	expired := 0
+	for i := range uploads {
+		upload := &uploads[i]
+		// Consume primero: si un ingreso/gasto lo reclamó justo ahora, el archivo ya no es nuestro
+		ok, err := s.uploadRepo.Consume(ctx, upload.ID)
+		if err != nil {
+			log.Printf("failed to expire upload %s: %v", upload.ID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		s.removeFiles(ctx, upload)
+		s.locks.Delete(upload.ID)
+		expired++
+	}
+	return expired, nil
+}
+
+func (s *UploadService) discard(ctx context.Context, upload *domain.Upload) {
+	if err := s.uploadRepo.Delete(ctx, upload.ID); err != nil {
+		log.Printf("failed to delete upload %s: %v", upload.ID, err)
+	}
+	s.removeFiles(ctx, upload)
+	s.locks.Delete(upload.ID)
+}
+
+func (s *UploadService) removeFiles(ctx context.Context, upload *domain.Upload) {
+	if err := os.Remove(s.partPath(upload.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("failed to remove upload part: %v", err)
+	}
+	if upload.RelPath != "" {
+		if err := deleteStoredFile(ctx, s.fileStorage, upload.RelPath, upload.Quarantined); err != nil {
+			log.Printf("failed to remove staged upload file: %v", err)
+		}
+	}
+}
+
+func (s *UploadService) lock(id string) *sync.Mutex {
+	l, _ := s.locks.LoadOrStore(id, &sync.Mutex{})
+	return l.(*sync.Mutex)
+}
+
+func (s *UploadService) partPath(id string) string {
+	return filepath.Join(s.dir, filepath.Base(id)+".part")
+}
+
+func newUploadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("cannot generate upload id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// claimUpload toma un upload completado del usuario para adjuntarlo a un ingreso o gasto.
+// El registro se borra en el acto (Consume) para que nadie más pueda usarlo;
+// si después falla la transacción hay que devolverlo con releaseUpload.
+func claimUpload(ctx context.Context, repo domain.UploadRepo, userID uint, id string) (*domain.Upload, error) {
+	if repo == nil {
+		return nil, domain.ErrNotFound
+	}
+	upload, err := repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if upload.UserID != userID || time.Now().After(upload.ExpiresAt) {
+		return nil, domain.ErrNotFound
+	}
+	if upload.CompletedAt == nil {
+		return nil, domain.ErrUploadIncomplete
+	}
+
+	ok, err := repo.Consume(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return upload, nil
+}
+
+// releaseUpload vuelve a dejar disponible un upload reclamado cuando la transacción falló.
+func releaseUpload(ctx context.Context, repo domain.UploadRepo, upload *domain.Upload) {
+	if err := repo.Create(ctx, upload); err != nil {
+		log.Printf("failed to release upload %s: %v", upload.ID, err)
+	}
+}
+
+// saveReceiptFile obtiene el archivo de un comprobante: lo guarda si vino en el multipart
+// o reclama el upload reanudable indicado. Devuelve el upload reclamado (o nil) para poder
+// deshacer con rollbackReceiptFile si falla la base de datos.
+func saveReceiptFile(
+	ctx context.Context,
+	fS domain.FileStorage,
+	uploads domain.UploadRepo,
+	file multipart.File,
+	fileHeader *multipart.FileHeader,
+	uploadID string,
+	userID uint,
+) (*domain.StoredFile, *domain.Upload, error) {
+	if file != nil && fileHeader != nil {
+		stored, err := fS.SavePDF(ctx, file, fileHeader.Size)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to save pdf: %w", err)
+		}
+		return stored, nil, nil
+	}
+
+	upload, err := claimUpload(ctx, uploads, userID, uploadID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid upload_id: %w", err)
+	}
+	return upload.Stored(), upload, nil
+}
+
+// rollbackReceiptFile deshace saveReceiptFile: borra el archivo subido por multipart
+// o devuelve el upload reclamado para que el cliente pueda reintentar.
+func rollbackReceiptFile(
+	ctx context.Context,
+	fS domain.FileStorage,
+	uploads domain.UploadRepo,
+	stored *domain.StoredFile,
+	upload *domain.Upload,
+) {
+	if upload != nil {
+		releaseUpload(ctx, uploads, upload)
+		return
+	}
+	if rmErr := deleteStoredFile(ctx, fS, stored.RelPath, stored.Quarantined); rmErr != nil {
+		log.Printf("failed to remove file %s after tx error: %v", stored.RelPath, rmErr)
+	}
+}
+
+// deleteStoredFile borra el archivo de un comprobante. El escáner mueve los infectados a la
+// cuarentena sin cambiar su rel_path, así que hay que saber dónde buscarlo.
+func deleteStoredFile(ctx context.Context, fS domain.FileStorage, relPath string, quarantined bool) error {
+	if quarantined {
+		return fS.DeleteQuarantinedPDF(ctx, relPath)
+	}
+	return fS.DeletePDF(ctx, relPath)
+}
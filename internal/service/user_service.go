@@ -5,6 +5,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
+	"strings"
 
 	"github.com/SaidMg10/gestor-one/internal/domain"
 	"github.com/SaidMg10/gestor-one/internal/validator"
@@ -12,11 +14,15 @@ import (
 
 type UserService struct {
 	userRepo domain.UserRepo
+	accounts *AccountService
+	authSvc  *AuthService
 }
 
-func NewUserService(u domain.UserRepo) *UserService {
+func NewUserService(u domain.UserRepo, accounts *AccountService, authSvc *AuthService) *UserService {
 	return &UserService{
 		userRepo: u,
+		accounts: accounts,
+		authSvc:  authSvc,
 	}
 }
 
@@ -52,7 +58,11 @@ func (s *UserService) Create(ctx context.Context, user *domain.User, pwd string)
 	if err := user.Password.Set(pwd); err != nil {
 		return fmt.Errorf("error al hashear la contraseña: %w", err)
 	}
-	return s.userRepo.Create(ctx, user)
+	if err := s.userRepo.Create(ctx, user); err != nil {
+		return err
+	}
+	s.sendVerification(ctx, user)
+	return nil
 }
 
 func (s *UserService) GetByID(ctx context.Context, id uint) (*domain.User, error) {
@@ -76,6 +86,7 @@ func (s *UserService) Update(ctx context.Context, id uint, updates *domain.User,
 		return domain.ErrNotFound
 	}
 
+	emailChanged := false
 	if updates.Email != "" && updates.Email != existing.Email {
 		if !validator.IsValidEmail(updates.Email) {
 			return domain.ErrInvalidEmail
@@ -90,8 +101,11 @@ func (s *UserService) Update(ctx context.Context, id uint, updates *domain.User,
 		}
 
 		existing.Email = updates.Email
+		existing.EmailVerifiedAt = nil
+		emailChanged = true
 	}
 
+	passwordSet := false
 	if pwd != nil && *pwd != "" {
 		if err := validator.ValidatePassword(*pwd); err != nil {
 			return err
@@ -99,6 +113,7 @@ func (s *UserService) Update(ctx context.Context, id uint, updates *domain.User,
 		if err := existing.Password.Set(*pwd); err != nil {
 			return fmt.Errorf("error al hashear la contraseña: %w", err)
 		}
+		passwordSet = true
 	}
 
 	if updates.Name != "" {
@@ -116,12 +131,84 @@ func (s *UserService) Update(ctx context.Context, id uint, updates *domain.User,
 		}
 		existing.Role = updates.Role
 	}
+	deactivated := false
 	if updates.Active != nil {
+		deactivated = !*updates.Active && (existing.Active == nil || *existing.Active)
 		existing.Active = updates.Active
 	}
 
 	// 6️⃣ Guardar
-	return s.userRepo.Update(ctx, existing)
+	if err := s.userRepo.Update(ctx, existing); err != nil {
+		return err
+	}
+	// Un usuario desactivado pierde todas sus sesiones en el momento
+	if deactivated && s.authSvc != nil {
+		if err := s.authSvc.LogoutAll(ctx, existing.ID); err != nil {
+			return fmt.Errorf("failed to revoke sessions of deactivated user: %w", err)
+		}
+		log.Printf("user %d deactivated, sessions revoked", existing.ID)
+	}
+	// Un enlace de recuperación enviado antes no puede pisar el email o la contraseña que puso el admin
+	if (emailChanged || passwordSet) && s.accounts != nil {
+		if err := s.accounts.RevokePasswordResets(ctx, existing.ID); err != nil {
+			return fmt.Errorf("failed to revoke password reset links: %w", err)
+		}
+	}
+	// El email nuevo hay que verificarlo de nuevo
+	if emailChanged {
+		if err := s.userRepo.SetEmailVerifiedAt(ctx, existing.ID, nil); err != nil {
+			return err
+		}
+		s.sendVerification(ctx, existing)
+	}
+	return nil
+}
+
+// UpdateProfile actualiza los datos personales del usuario: nombre, apellido y teléfono.
+// Es lo que un usuario puede cambiar de sí mismo; el email, el rol y el estado solo los
+// cambia un admin con Update. Los campos nil no se tocan; el teléfono se borra con "",
+// el nombre y el apellido no pueden quedar vacíos.
+func (s *UserService) UpdateProfile(ctx context.Context, id uint, update domain.ProfileUpdate) (*domain.User, error) {
+	profile := domain.ProfileUpdate{
+		Name:     trimmed(update.Name),
+		LastName: trimmed(update.LastName),
+		Phone:    trimmed(update.Phone),
+	}
+	for _, name := range []*string{profile.Name, profile.LastName} {
+		if name == nil {
+			continue
+		}
+		if *name == "" || len(*name) > 100 {
+			return nil, fmt.Errorf("%w: name and last name are required and must be at most 100 characters", domain.ErrInvalidInput)
+		}
+	}
+	if profile.Phone != nil && len(*profile.Phone) > 20 {
+		return nil, fmt.Errorf("%w: phone must be at most 20 characters", domain.ErrInvalidInput)
+	}
+
+	if err := s.userRepo.UpdateProfile(ctx, id, profile); err != nil {
+		return nil, err
+	}
+	return s.userRepo.GetByID(ctx, id)
+}
+
+func trimmed(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	t := strings.TrimSpace(*s)
+	return &t
+}
+
+// sendVerification manda el email de verificación; si falla el usuario ya quedó guardado
+// y puede pedirlo de nuevo, así que solo se registra.
+func (s *UserService) sendVerification(ctx context.Context, user *domain.User) {
+	if s.accounts == nil {
+		return
+	}
+	if err := s.accounts.SendVerification(ctx, user); err != nil {
+		log.Printf("failed to send verification email to user %d: %v", user.ID, err)
+	}
 }
 
 func (s *UserService) Delete(ctx context.Context, id uint) error {
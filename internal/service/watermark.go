@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/pdfdoc"
+)
+
+// ReceiptWatermarker marca los comprobantes que descargan ciertos roles con el usuario,
+// la fecha y "COPY" para poder rastrear filtraciones. La marca se aplica sobre una copia
+// al momento de la descarga; el archivo guardado no se modifica.
+type ReceiptWatermarker struct {
+	roles map[string]bool
+}
+
+// NewReceiptWatermarker crea un watermarker para los roles indicados.
+// Sin roles no se marca ninguna descarga.
+func NewReceiptWatermarker(roles []string) (*ReceiptWatermarker, error) {
+	wm := &ReceiptWatermarker{roles: map[string]bool{}}
+	for _, role := range roles {
+		role = strings.TrimSpace(role)
+		if role == "" {
+			continue
+		}
+		if !domain.IsValidRole(role) {
+			return nil, fmt.Errorf("%w: unknown role %q", domain.ErrInvalidInput, role)
+		}
+		wm.roles[role] = true
+	}
+	return wm, nil
+}
+
+// Applies indica si las descargas de user llevan marca de agua.
+func (wm *ReceiptWatermarker) Applies(user *domain.User) bool {
+	return wm != nil && user != nil && wm.roles[user.Role]
+}
+
+// Wrap devuelve file con la marca de agua de user si corresponde, o file tal cual si no.
+// Siempre se hace cargo de cerrar file. Si el PDF no se puede marcar devuelve error en vez
+// de entregar una copia sin marca.
+func (wm *ReceiptWatermarker) Wrap(file io.ReadCloser, user *domain.User) (io.ReadCloser, error) {
+	if !wm.Applies(user) {
+		return file, nil
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	// pdfcpu necesita un io.ReadSeeker y el storage (S3, cifrado) solo da un stream
+	raw, err := os.CreateTemp("", "gestor-download-*.pdf")
+	if err != nil {
+		return nil, fmt.Errorf("cannot create temp file: %w", err)
+	}
+	defer func() {
+		_ = raw.Close()
+		_ = os.Remove(raw.Name())
+	}()
+	if _, err := io.Copy(raw, file); err != nil {
+		return nil, fmt.Errorf("cannot read receipt: %w", err)
+	}
+	if _, err := raw.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	marked, err := os.CreateTemp("", "gestor-download-*.pdf")
+	if err != nil {
+		return nil, fmt.Errorf("cannot create temp file: %w", err)
+	}
+	out := &tempFile{File: marked}
+	if err := pdfdoc.Watermark(raw, marked, watermarkText(user, time.Now())); err != nil {
+		_ = out.Close()
+		return nil, fmt.Errorf("cannot watermark receipt: %w", err)
+	}
+	if _, err := marked.Seek(0, io.SeekStart); err != nil {
+		_ = out.Close()
+		return nil, err
+	}
+	return out, nil
+}
+
+func watermarkText(user *domain.User, now time.Time) string {
+	return fmt.Sprintf("COPY\n%s (#%d)\n%s", user.Email, user.ID, now.UTC().Format("2006-01-02 15:04:05 MST"))
+}
+
+// tempFile es un archivo temporal que se borra al cerrarlo.
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	_ = os.Remove(f.Name())
+	return err
+}
@@ -0,0 +1,375 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/SaidMg10/gestor-one/internal/config"
+	"github.com/SaidMg10/gestor-one/internal/domain"
+)
+
+// Formato de un archivo cifrado:
+//
+//	magic (6) | len(keyID) (1) | keyID | wrapped DEK (12 nonce + 32 + 16 tag) | nonce prefix (8) | segmentos
+//
+// Cada archivo tiene su propia data key (DEK) AES-256, envuelta con la master key indicada por keyID.
+// El contenido se cifra con AES-GCM en segmentos de 64 KiB para poder hacerlo en streaming;
+// el nonce de cada segmento es prefix||contador y el AAD marca el último segmento,
+// así se detecta si alguien trunca o reordena el archivo.
+const (
+	segmentSize  = 64 << 10
+	gcmTagSize   = 16
+	dekSize      = 32
+	noncePrefix  = 8
+	wrappedSize  = 12 + dekSize + gcmTagSize
+	encMagic     = "G1ENC\x01"
+	maxHeaderLen = len(encMagic) + 1 + 255 + wrappedSize + noncePrefix
+)
+
+var ErrUnknownKey = errors.New("unknown encryption key")
+
+// Keyring contiene las master keys disponibles y cuál se usa para cifrar archivos nuevos.
+// Las keys viejas se mantienen para poder descifrar hasta que se re-cifren los archivos.
+type Keyring struct {
+	activeID string
+	keys     map[string][]byte
+}
+
+func NewKeyring(cfg config.EncryptionConfig) (*Keyring, error) {
+	keys := make(map[string][]byte, len(cfg.MasterKeys))
+	for id, encoded := range cfg.MasterKeys {
+		if len(id) > 255 {
+			return nil, fmt.Errorf("encryption key id %q is too long", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q is not valid base64: %w", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("encryption key %q must be 32 bytes", id)
+		}
+		keys[id] = key
+	}
+	if _, ok := keys[cfg.ActiveKeyID]; !ok {
+		return nil, fmt.Errorf("active encryption key %q not configured", cfg.ActiveKeyID)
+	}
+	return &Keyring{activeID: cfg.ActiveKeyID, keys: keys}, nil
+}
+
+// ActiveKeyID es el id de la master key con la que se cifran los archivos nuevos.
+func (k *Keyring) ActiveKeyID() string {
+	return k.activeID
+}
+
+// EncryptedStorage envuelve otro FileStorage y cifra/descifra los archivos de forma transparente.
+// Los archivos sin cabecera de cifrado (subidos antes de activarlo) se leen tal cual.
+type EncryptedStorage struct {
+	inner   domain.FileStorage
+	keyring *Keyring
+	maxSize int64
+}
+
+func NewEncryptedStorage(inner domain.FileStorage, keyring *Keyring, maxSize int64) domain.FileStorage {
+	return &EncryptedStorage{inner: inner, keyring: keyring, maxSize: maxSize}
+}
+
+// maxCiphertextSize es el tamaño máximo que puede ocupar cifrado un archivo de plain bytes.
+// Se usa como límite del backend para que el límite real se aplique sobre el texto plano.
+func maxCiphertextSize(plain int64) int64 {
+	if plain <= 0 {
+		return plain
+	}
+	return plain + int64(maxHeaderLen) + (plain/segmentSize+1)*gcmTagSize
+}
+
+// SavePDF cifra el archivo mientras se sube. El checksum y el tamaño devueltos
+// son los del texto plano, para que sigan siendo comparables con el PDF original.
+func (es *EncryptedStorage) SavePDF(ctx context.Context, r io.Reader, size int64) (*domain.StoredFile, error) {
+	if r == nil {
+		return nil, fmt.Errorf("file is required")
+	}
+	if es.maxSize > 0 && size > es.maxSize {
+		return nil, domain.ErrFileTooLarge
+	}
+
+	hash := sha256.New()
+	counter := &countingReader{r: io.TeeReader(newLimitedReader(r, es.maxSize), hash)}
+
+	enc, err := newEncryptReader(counter, es.keyring.activeID, es.keyring.keys[es.keyring.activeID])
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := es.inner.SavePDF(ctx, enc, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	stored.Checksum = fmt.Sprintf("%x", hash.Sum(nil))
+	stored.Size = counter.n
+	stored.KeyID = es.keyring.activeID
+	return stored, nil
+}
+
+// OpenPDF devuelve un lector que descifra al vuelo.
+func (es *EncryptedStorage) OpenPDF(ctx context.Context, relPath string) (io.ReadCloser, error) {
+	rc, err := es.inner.OpenPDF(ctx, relPath)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReaderSize(rc, segmentSize+gcmTagSize)
+	magic, err := br.Peek(len(encMagic))
+	if err != nil || string(magic) != encMagic {
+		// Archivo en claro (anterior al cifrado)
+		return &readCloser{Reader: br, Closer: rc}, nil
+	}
+
+	dec, err := newDecryptReader(br, es.keyring)
+	if err != nil {
+		_ = rc.Close()
+		return nil, err
+	}
+	return &readCloser{Reader: dec, Closer: rc}, nil
+}
+
+func (es *EncryptedStorage) DeletePDF(ctx context.Context, relPath string) error {
+	return es.inner.DeletePDF(ctx, relPath)
+}
+
+func (es *EncryptedStorage) DeleteQuarantinedPDF(ctx context.Context, relPath string) error {
+	return es.inner.DeleteQuarantinedPDF(ctx, relPath)
+}
+
+func (es *EncryptedStorage) ListPDFs(ctx context.Context) ([]domain.StoredObject, error) {
+	return es.inner.ListPDFs(ctx)
+}
+
+func (es *EncryptedStorage) QuarantinePDF(ctx context.Context, relPath string) error {
+	return es.inner.QuarantinePDF(ctx, relPath)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptReader produce cabecera + segmentos cifrados a partir del texto plano.
+type encryptReader struct {
+	src     *bufio.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	plain   []byte
+	out     bytes.Buffer
+	done    bool
+}
+
+func newEncryptReader(src io.Reader, keyID string, masterKey []byte) (*encryptReader, error) {
+	dek := make([]byte, dekSize)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, fmt.Errorf("cannot generate data key: %w", err)
+	}
+
+	// Envolvemos la DEK con la master key; el keyID va como AAD
+	master, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	wrapNonce := make([]byte, master.NonceSize())
+	if _, err := rand.Read(wrapNonce); err != nil {
+		return nil, fmt.Errorf("cannot generate nonce: %w", err)
+	}
+	wrapped := master.Seal(wrapNonce, wrapNonce, dek, []byte(keyID))
+
+	aead, err := newGCM(dek)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, noncePrefix)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, fmt.Errorf("cannot generate nonce: %w", err)
+	}
+
+	er := &encryptReader{
+		src:    bufio.NewReaderSize(src, segmentSize),
+		aead:   aead,
+		prefix: prefix,
+		plain:  make([]byte, segmentSize),
+	}
+	er.out.WriteString(encMagic)
+	er.out.WriteByte(byte(len(keyID)))
+	er.out.WriteString(keyID)
+	er.out.Write(wrapped)
+	er.out.Write(prefix)
+	return er, nil
+}
+
+func (er *encryptReader) Read(p []byte) (int, error) {
+	for er.out.Len() == 0 {
+		if er.done {
+			return 0, io.EOF
+		}
+		if err := er.nextSegment(); err != nil {
+			return 0, err
+		}
+	}
+	return er.out.Read(p)
+}
+
+func (er *encryptReader) nextSegment() error {
+	n, err := io.ReadFull(er.src, er.plain)
+	final := false
+	switch {
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		final = true
+	case err != nil:
+		return err
+	default:
+		if _, peekErr := er.src.Peek(1); errors.Is(peekErr, io.EOF) {
+			final = true
+		} else if peekErr != nil {
+			return peekErr
+		}
+	}
+
+	er.out.Write(er.aead.Seal(nil, segmentNonce(er.prefix, er.counter), er.plain[:n], segmentAAD(final)))
+	er.counter++
+	er.done = final
+	return nil
+}
+
+// decryptReader valida y descifra los segmentos producidos por encryptReader.
+type decryptReader struct {
+	src     *bufio.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	sealed  []byte
+	out     bytes.Reader
+	done    bool
+}
+
+func newDecryptReader(src *bufio.Reader, keyring *Keyring) (*decryptReader, error) {
+	if _, err := src.Discard(len(encMagic)); err != nil {
+		return nil, err
+	}
+	idLen, err := src.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("corrupt encrypted file: %w", err)
+	}
+	header := make([]byte, int(idLen)+wrappedSize+noncePrefix)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return nil, fmt.Errorf("corrupt encrypted file: %w", err)
+	}
+	keyID := string(header[:idLen])
+	wrapped := header[idLen : int(idLen)+wrappedSize]
+	prefix := header[int(idLen)+wrappedSize:]
+
+	masterKey, ok := keyring.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	master, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	dek, err := master.Open(nil, wrapped[:master.NonceSize()], wrapped[master.NonceSize():], []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("cannot unwrap data key: %w", err)
+	}
+	aead, err := newGCM(dek)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{
+		src:    src,
+		aead:   aead,
+		prefix: prefix,
+		sealed: make([]byte, segmentSize+gcmTagSize),
+	}, nil
+}
+
+func (dr *decryptReader) Read(p []byte) (int, error) {
+	for dr.out.Len() == 0 {
+		if dr.done {
+			return 0, io.EOF
+		}
+		if err := dr.nextSegment(); err != nil {
+			return 0, err
+		}
+	}
+	return dr.out.Read(p)
+}
+
+func (dr *decryptReader) nextSegment() error {
+	n, err := io.ReadFull(dr.src, dr.sealed)
+	final := false
+	switch {
+	case errors.Is(err, io.EOF):
+		return fmt.Errorf("corrupt encrypted file: missing final segment")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		final = true
+	case err != nil:
+		return err
+	default:
+		if _, peekErr := dr.src.Peek(1); errors.Is(peekErr, io.EOF) {
+			final = true
+		} else if peekErr != nil {
+			return peekErr
+		}
+	}
+
+	plain, err := dr.aead.Open(nil, segmentNonce(dr.prefix, dr.counter), dr.sealed[:n], segmentAAD(final))
+	if err != nil {
+		return fmt.Errorf("corrupt encrypted file: %w", err)
+	}
+	dr.counter++
+	dr.done = final
+	dr.out.Reset(plain)
+	return nil
+}
+
+func segmentNonce(prefix []byte, counter uint32) []byte {
+	nonce := make([]byte, noncePrefix+4)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefix:], counter)
+	return nonce
+}
+
+func segmentAAD(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
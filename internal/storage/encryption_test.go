@@ -0,0 +1,255 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/SaidMg10/gestor-one/internal/config"
+	"github.com/SaidMg10/gestor-one/internal/domain"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{b}, 32))
+}
+
+func testKeyring(t *testing.T, active string, ids ...string) *Keyring {
+	t.Helper()
+	keys := map[string]string{}
+	for i, id := range ids {
+		keys[id] = testKey(byte(i + 1))
+	}
+	k, err := NewKeyring(config.EncryptionConfig{Enabled: true, ActiveKeyID: active, MasterKeys: keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func encrypt(t *testing.T, k *Keyring, plain []byte) []byte {
+	t.Helper()
+	enc, err := newEncryptReader(bytes.NewReader(plain), k.activeID, k.keys[k.activeID])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := io.ReadAll(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sealed
+}
+
+func decrypt(k *Keyring, sealed []byte) ([]byte, error) {
+	dec, err := newDecryptReader(bufio.NewReaderSize(bytes.NewReader(sealed), segmentSize+gcmTagSize), k)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(dec)
+}
+
+// headerLen es el largo de la cabecera para un keyID dado.
+func headerLen(keyID string) int {
+	return len(encMagic) + 1 + len(keyID) + wrappedSize + noncePrefix
+}
+
+func TestNewKeyring(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.EncryptionConfig
+		ok   bool
+	}{
+		{"válido", config.EncryptionConfig{ActiveKeyID: "k1", MasterKeys: map[string]string{"k1": testKey(1)}}, true},
+		{"sin key activa", config.EncryptionConfig{ActiveKeyID: "k2", MasterKeys: map[string]string{"k1": testKey(1)}}, false},
+		{"base64 inválido", config.EncryptionConfig{ActiveKeyID: "k1", MasterKeys: map[string]string{"k1": "not base64!"}}, false},
+		{"largo incorrecto", config.EncryptionConfig{ActiveKeyID: "k1", MasterKeys: map[string]string{"k1": base64.StdEncoding.EncodeToString(make([]byte, 16))}}, false},
+	}
+	for _, tt := range tests {
+		if _, err := NewKeyring(tt.cfg); (err == nil) != tt.ok {
+			t.Errorf("%s: NewKeyring error = %v; want ok=%v", tt.name, err, tt.ok)
+		}
+	}
+}
+
+func TestEncryptionRoundTrip(t *testing.T) {
+	k := testKeyring(t, "k1", "k1")
+	sizes := []int{0, 1, 1000, segmentSize - 1, segmentSize, segmentSize + 1, 3*segmentSize + 17}
+	for _, size := range sizes {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			plain := randomBytes(t, size)
+			sealed := encrypt(t, k, plain)
+
+			segments := size/segmentSize + 1
+			if size > 0 && size%segmentSize == 0 {
+				segments--
+			}
+			if want := headerLen("k1") + size + segments*gcmTagSize; len(sealed) != want {
+				t.Errorf("sealed size = %d; want %d (%d segments)", len(sealed), want, segments)
+			}
+			if int64(len(sealed)) > maxCiphertextSize(int64(size)) && size > 0 {
+				t.Errorf("sealed size %d exceeds maxCiphertextSize(%d) = %d", len(sealed), size, maxCiphertextSize(int64(size)))
+			}
+
+			got, err := decrypt(k, sealed)
+			if err != nil {
+				t.Fatalf("decrypt: %v", err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Error("decrypted content differs from the original")
+			}
+		})
+	}
+}
+
+func TestEncryptionDetectsTampering(t *testing.T) {
+	k := testKeyring(t, "k1", "k1")
+	plain := randomBytes(t, 2*segmentSize+100)
+	sealed := encrypt(t, k, plain)
+	body := headerLen("k1")
+	seg := segmentSize + gcmTagSize
+
+	tests := []struct {
+		name   string
+		mutate func([]byte) []byte
+	}{
+		{"byte modificado", func(b []byte) []byte { b[body+10] ^= 1; return b }},
+		{"tag modificado", func(b []byte) []byte { b[len(b)-1] ^= 1; return b }},
+		{"data key envuelta modificada", func(b []byte) []byte { b[len(encMagic)+1+len("k1")+20] ^= 1; return b }},
+		{"prefijo de nonce modificado", func(b []byte) []byte { b[body-1] ^= 1; return b }},
+		// Cortar justo en el límite de un segmento deja un archivo que parece completo:
+		// lo detecta el AAD, porque ese segmento no se cifró como el último
+		{"sin el último segmento", func(b []byte) []byte { return b[:body+2*seg] }},
+		{"sin los dos últimos segmentos", func(b []byte) []byte { return b[:body+seg] }},
+		{"último segmento truncado", func(b []byte) []byte { return b[:len(b)-5] }},
+		{"sin segmentos", func(b []byte) []byte { return b[:body] }},
+		{"segmentos reordenados", func(b []byte) []byte {
+			out := append([]byte{}, b[:body]...)
+			out = append(out, b[body+seg:body+2*seg]...)
+			out = append(out, b[body:body+seg]...)
+			return append(out, b[body+2*seg:]...)
+		}},
+		{"segmento agregado al final", func(b []byte) []byte { return append(b, b[body:body+seg]...) }},
+		{"cabecera truncada", func(b []byte) []byte { return b[:body-3] }},
+	}
+	for _, tt := range tests {
+		mutated := tt.mutate(append([]byte{}, sealed...))
+		if _, err := decrypt(k, mutated); err == nil {
+			t.Errorf("%s: decrypt succeeded; want error", tt.name)
+		}
+	}
+}
+
+func TestEncryptionKeyRotation(t *testing.T) {
+	old := testKeyring(t, "old", "old")
+	plain := randomBytes(t, 5000)
+	sealed := encrypt(t, old, plain)
+
+	// Las keys viejas se siguen aceptando para descifrar
+	rotated := testKeyring(t, "new", "old", "new")
+	rotated.keys["old"] = old.keys["old"]
+	got, err := decrypt(rotated, sealed)
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("decrypt with rotated keyring: %v", err)
+	}
+
+	if _, err := decrypt(testKeyring(t, "new", "new"), sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("decrypt without the key = %v; want ErrUnknownKey", err)
+	}
+
+	// El keyID va como AAD de la data key: otra master key con el mismo id no sirve
+	other := testKeyring(t, "old", "x", "old")
+	if _, err := decrypt(other, sealed); err == nil {
+		t.Error("decrypt with a different master key under the same id succeeded")
+	}
+}
+
+// memStorage es un FileStorage en memoria.
+type memStorage struct {
+	domain.FileStorage
+	files map[string][]byte
+}
+
+func (m *memStorage) SavePDF(ctx context.Context, r io.Reader, size int64) (*domain.StoredFile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	path := fmt.Sprintf("file-%d.pdf", len(m.files))
+	m.files[path] = data
+	return &domain.StoredFile{FileName: path, RelPath: path, Size: int64(len(data))}, nil
+}
+
+func (m *memStorage) OpenPDF(ctx context.Context, relPath string) (io.ReadCloser, error) {
+	data, ok := m.files[relPath]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func TestEncryptedStorage(t *testing.T) {
+	ctx := context.Background()
+	inner := &memStorage{files: map[string][]byte{}}
+	k := testKeyring(t, "k1", "k1")
+	es := NewEncryptedStorage(inner, k, 200<<10)
+
+	plain := append([]byte("%PDF-1.7\n"), randomBytes(t, 100<<10)...)
+	stored, err := es.SavePDF(ctx, bytes.NewReader(plain), int64(len(plain)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Checksum y tamaño son los del texto plano
+	if stored.Size != int64(len(plain)) || stored.Checksum != fmt.Sprintf("%x", sha256.Sum256(plain)) || stored.KeyID != "k1" {
+		t.Errorf("stored = %+v", stored)
+	}
+	raw := inner.files[stored.RelPath]
+	if !bytes.HasPrefix(raw, []byte(encMagic)) || bytes.Contains(raw, plain[:64]) {
+		t.Error("inner storage holds plain content")
+	}
+
+	rc, err := es.OpenPDF(ctx, stored.RelPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(rc)
+	_ = rc.Close()
+	if err != nil || !bytes.Equal(got, plain) {
+		t.Errorf("OpenPDF round trip: %v", err)
+	}
+
+	// Los archivos subidos antes de activar el cifrado se leen tal cual
+	inner.files["legacy.pdf"] = []byte("%PDF-1.4 legacy")
+	rc, err = es.OpenPDF(ctx, "legacy.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ = io.ReadAll(rc)
+	_ = rc.Close()
+	if string(got) != "%PDF-1.4 legacy" {
+		t.Errorf("legacy file = %q", got)
+	}
+
+	// El límite se aplica sobre el texto plano, también cuando el tamaño declarado miente
+	if _, err := es.SavePDF(ctx, bytes.NewReader(make([]byte, 300<<10)), 300<<10); !errors.Is(err, domain.ErrFileTooLarge) {
+		t.Errorf("SavePDF over the limit = %v; want ErrFileTooLarge", err)
+	}
+	if _, err := es.SavePDF(ctx, bytes.NewReader(make([]byte, 300<<10)), -1); !errors.Is(err, domain.ErrFileTooLarge) {
+		t.Errorf("SavePDF over the limit with unknown size = %v; want ErrFileTooLarge", err)
+	}
+}
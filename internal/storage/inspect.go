@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/pdfdoc"
+)
+
+// MaxExtractedText limita el texto que se guarda por comprobante para la búsqueda.
+const MaxExtractedText = 64 << 10
+
+// Acciones posibles ante un archivo infectado o que no se pudo escanear.
+const (
+	ScanActionReject     = "reject"     // no se guarda y la subida falla
+	ScanActionQuarantine = "quarantine" // se guarda en cuarentena y el receipt queda bloqueado
+	ScanActionAllow      = "allow"      // se guarda normalmente (solo para errores de escaneo)
+)
+
+// ScanPolicy define qué hacer según el veredicto del antivirus.
+type ScanPolicy struct {
+	OnInfected string
+	OnError    string
+}
+
+func (p ScanPolicy) actionFor(status domain.ScanStatus) string {
+	switch status {
+	case domain.ScanStatusInfected:
+		if p.OnInfected == ScanActionQuarantine {
+			return ScanActionQuarantine
+		}
+		return ScanActionReject
+	case domain.ScanStatusError:
+		switch p.OnError {
+		case ScanActionQuarantine, ScanActionAllow:
+			return p.OnError
+		}
+		return ScanActionReject
+	}
+	return ScanActionAllow
+}
+
+// InspectingStorage recibe la subida en un temporal, la inspecciona y recién entonces
+// la entrega al FileStorage interno. Así nada que no haya pasado el antivirus
+// y la validación de PDF llega al backend.
+type InspectingStorage struct {
+	inner     domain.FileStorage
+	scanner   domain.MalwareScanner
+	policy    ScanPolicy
+	pdfPolicy PDFPolicy
+	maxSize   int64
+}
+
+func NewInspectingStorage(
+	inner domain.FileStorage,
+	scanner domain.MalwareScanner,
+	policy ScanPolicy,
+	pdfPolicy PDFPolicy,
+	maxSize int64,
+) domain.FileStorage {
+	return &InspectingStorage{
+		inner:     inner,
+		scanner:   scanner,
+		policy:    policy,
+		pdfPolicy: pdfPolicy,
+		maxSize:   maxSize,
+	}
+}
+
+func (is *InspectingStorage) SavePDF(ctx context.Context, r io.Reader, size int64) (*domain.StoredFile, error) {
+	staged, stagedSize, err := stageUpload(r, size, is.maxSize)
+	if err != nil {
+		return nil, err
+	}
+	defer discardStaged(staged)
+
+	result, err := is.scanner.Scan(ctx, staged)
+	if err != nil {
+		log.Printf("malware scan failed: %v", err)
+		result = &domain.ScanResult{Status: domain.ScanStatusError, Signature: truncate(err.Error(), 255)}
+	}
+	scannedAt := time.Now()
+
+	action := is.policy.actionFor(result.Status)
+	if action == ScanActionReject {
+		if result.Status == domain.ScanStatusInfected {
+			log.Printf("upload rejected, malware found: %s", result.Signature)
+			return nil, domain.ErrInfectedFile
+		}
+		return nil, domain.ErrScanFailed
+	}
+
+	if _, err := staged.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("cannot rewind staged file: %w", err)
+	}
+
+	// Los archivos en cuarentena se guardan tal cual, para poder analizarlos después
+	var pdf *PDFReport
+	var text string
+	toSave, toSaveSize := staged, stagedSize
+	if action != ScanActionQuarantine {
+		sanitized, err := os.CreateTemp("", "gestor-sanitized-*.pdf")
+		if err != nil {
+			return nil, fmt.Errorf("cannot create temp file: %w", err)
+		}
+		defer discardStaged(sanitized)
+
+		pdf, err = InspectPDF(staged, sanitized, is.pdfPolicy)
+		if err != nil {
+			log.Printf("upload rejected by PDF policy: %v", err)
+			return nil, err
+		}
+		if pdf.Sanitized {
+			info, err := sanitized.Stat()
+			if err != nil {
+				return nil, fmt.Errorf("cannot stat sanitized file: %w", err)
+			}
+			toSave, toSaveSize = sanitized, info.Size()
+		}
+		if _, err := toSave.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("cannot rewind staged file: %w", err)
+		}
+
+		// El texto es solo para la búsqueda: si no se puede extraer el archivo igual se guarda
+		text, err = pdfdoc.ExtractText(toSave, toSaveSize, MaxExtractedText)
+		if err != nil {
+			log.Printf("cannot extract text from upload: %v", err)
+		}
+	}
+
+	stored, err := is.inner.SavePDF(ctx, toSave, toSaveSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if pdf != nil {
+		stored.PageCount = pdf.PageCount
+		stored.Sanitized = pdf.Sanitized
+		stored.Text = text
+	}
+	stored.ScanStatus = result.Status
+	stored.ScanResult = result.Signature
+	stored.ScannedAt = &scannedAt
+
+	if action == ScanActionQuarantine {
+		log.Printf("upload quarantined (%s): %s %s", stored.RelPath, result.Status, result.Signature)
+		if err := is.inner.QuarantinePDF(ctx, stored.RelPath); err != nil {
+			if rmErr := is.inner.DeletePDF(ctx, stored.RelPath); rmErr != nil {
+				log.Printf("failed to remove file after quarantine error: %v", rmErr)
+			}
+			return nil, fmt.Errorf("cannot quarantine file: %w", err)
+		}
+		stored.Quarantined = true
+	}
+
+	return stored, nil
+}
+
+func (is *InspectingStorage) OpenPDF(ctx context.Context, relPath string) (io.ReadCloser, error) {
+	return is.inner.OpenPDF(ctx, relPath)
+}
+
+func (is *InspectingStorage) DeletePDF(ctx context.Context, relPath string) error {
+	return is.inner.DeletePDF(ctx, relPath)
+}
+
+func (is *InspectingStorage) DeleteQuarantinedPDF(ctx context.Context, relPath string) error {
+	return is.inner.DeleteQuarantinedPDF(ctx, relPath)
+}
+
+func (is *InspectingStorage) ListPDFs(ctx context.Context) ([]domain.StoredObject, error) {
+	return is.inner.ListPDFs(ctx)
+}
+
+func (is *InspectingStorage) QuarantinePDF(ctx context.Context, relPath string) error {
+	return is.inner.QuarantinePDF(ctx, relPath)
+}
+
+// stageUpload copia r a un temporal respetando maxSize y lo deja posicionado al inicio.
+func stageUpload(r io.Reader, size, maxSize int64) (*os.File, int64, error) {
+	if r == nil {
+		return nil, 0, fmt.Errorf("file is required")
+	}
+	if maxSize > 0 && size > maxSize {
+		return nil, 0, domain.ErrFileTooLarge
+	}
+
+	tmp, err := os.CreateTemp("", "gestor-upload-*.pdf")
+	if err != nil {
+		return nil, 0, fmt.Errorf("cannot create temp file: %w", err)
+	}
+	written, err := io.Copy(tmp, newLimitedReader(r, maxSize))
+	if err != nil {
+		discardStaged(tmp)
+		return nil, 0, fmt.Errorf("cannot stage upload: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		discardStaged(tmp)
+		return nil, 0, fmt.Errorf("cannot rewind staged file: %w", err)
+	}
+	return tmp, written, nil
+}
+
+func discardStaged(f *os.File) {
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
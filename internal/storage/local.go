@@ -2,93 +2,168 @@
 package storage
 
 import (
+	"context"
 	"crypto/sha256"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"os"
 	"path/filepath"
-	"time"
+	"strings"
 
 	"github.com/SaidMg10/gestor-one/internal/domain"
 )
 
-type FileStorageLocal struct{}
+// FileStorageLocal guarda los comprobantes en un directorio del disco local.
+type FileStorageLocal struct {
+	dir     string
+	maxSize int64
+}
 
-func NewFileStorageLocal() domain.FileStorage {
-	return &FileStorageLocal{}
+func NewFileStorageLocal(dir string, maxSize int64) domain.FileStorage {
+	if dir == "" {
+		dir = "./uploads"
+	}
+	return &FileStorageLocal{dir: dir, maxSize: maxSize}
 }
 
-func (fsl *FileStorageLocal) SavePDF(fileHeader *multipart.FileHeader) (string, string, string, error) {
-	if fileHeader == nil {
-		return "", "", "", fmt.Errorf("file is required")
+// SavePDF lee el archivo una sola vez: mientras se copia a un temporal se calcula el checksum
+// y se controla el tamaño máximo. Al terminar se renombra el temporal, así nunca queda
+// un PDF a medio escribir con el nombre definitivo.
+func (fsl *FileStorageLocal) SavePDF(ctx context.Context, r io.Reader, size int64) (*domain.StoredFile, error) {
+	if r == nil {
+		return nil, fmt.Errorf("file is required")
+	}
+	if fsl.maxSize > 0 && size > fsl.maxSize {
+		return nil, domain.ErrFileTooLarge
 	}
 
-	if filepath.Ext(fileHeader.Filename) != ".pdf" {
-		return "", "", "", fmt.Errorf("only PDF files are allowed")
+	if err := os.MkdirAll(fsl.dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("cannot create uploads dir: %w", err)
 	}
 
-	file, err := fileHeader.Open()
+	// El temporal vive en el mismo directorio para que el rename sea atómico
+	tmp, err := os.CreateTemp(fsl.dir, ".upload-*.tmp")
 	if err != nil {
-		return "", "", "", fmt.Errorf("cannot open file: %w", err)
+		return nil, fmt.Errorf("cannot create temp file: %w", err)
 	}
+	tmpPath := tmp.Name()
+	committed := false
 	defer func() {
-		_ = file.Close()
+		if !committed {
+			_ = tmp.Close()
+			_ = os.Remove(tmpPath)
+		}
 	}()
-	fileBytes, err := io.ReadAll(file)
+
+	hash := sha256.New()
+	written, err := io.Copy(tmp, io.TeeReader(newLimitedReader(r, fsl.maxSize), hash))
 	if err != nil {
-		return "", "", "", fmt.Errorf("cannot read uploaded file: %w", err)
+		return nil, fmt.Errorf("cannot save file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return nil, fmt.Errorf("cannot flush file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("cannot close file: %w", err)
 	}
 
-	hash := sha256.Sum256(fileBytes)
-	checksum := fmt.Sprintf("%x", hash[:])
-
-	filename := fmt.Sprintf("%d_receipt.pdf", time.Now().UnixNano())
-
-	uploadDir := "./uploads"
-	fullDiskPath := filepath.Join(uploadDir, filename)
-
-	if err := os.MkdirAll(uploadDir, os.ModePerm); err != nil {
-		return "", "", "", fmt.Errorf("cannot create uploads dir: %w", err)
+	filename := newReceiptName()
+	if err := os.Rename(tmpPath, filepath.Join(fsl.dir, filename)); err != nil {
+		return nil, fmt.Errorf("cannot move file into place: %w", err)
 	}
+	committed = true
+
+	return &domain.StoredFile{
+		FileName: filename,
+		RelPath:  relPathFor(filename), // PATH RELATIVA para BD
+		Checksum: fmt.Sprintf("%x", hash.Sum(nil)),
+		Size:     written,
+	}, nil
+}
 
-	out, err := os.Create(fullDiskPath)
+func (fsl *FileStorageLocal) OpenPDF(ctx context.Context, relPath string) (io.ReadCloser, error) {
+	file, err := os.Open(fsl.diskPath(relPath))
 	if err != nil {
-		return "", "", "", fmt.Errorf("cannot create file: %w", err)
-	}
-	defer func() {
-		_ = out.Close()
-	}()
-	if _, err := file.Seek(0, 0); err != nil {
-		return "", "", "", fmt.Errorf("cannot reset file pointer: %w", err)
+		if os.IsNotExist(err) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, fmt.Errorf("cannot open file: %w", err)
 	}
+	return file, nil
+}
 
-	if _, err := io.Copy(out, file); err != nil {
-		return "", "", "", fmt.Errorf("cannot save file: %w", err)
+func (fsl *FileStorageLocal) DeletePDF(ctx context.Context, filePath string) error {
+	if filePath == "" {
+		return nil
 	}
-
-	// PATH RELATIVA para BD
-	relPath := "/uploads/" + filename
-
-	return filename, checksum, relPath, nil
+	return removeFile(fsl.diskPath(filePath))
 }
 
-func (fsl *FileStorageLocal) DeletePDF(filePath string) error {
-	if filePath == "" {
+// DeleteQuarantinedPDF borra el archivo que QuarantinePDF movió a <dir>/quarantine.
+func (fsl *FileStorageLocal) DeleteQuarantinedPDF(ctx context.Context, relPath string) error {
+	if relPath == "" {
 		return nil
 	}
+	return removeFile(filepath.Join(fsl.dir, quarantineDir, filepath.Base(relPath)))
+}
 
-	// recibes "/uploads/xxx.pdf"
-	filename := filepath.Base(filePath)
+func removeFile(diskPath string) error {
+	if err := os.Remove(diskPath); err != nil {
+		if os.IsNotExist(err) {
+			return domain.ErrNotFound
+		}
+		return fmt.Errorf("cannot remove file: %w", err)
+	}
+	return nil
+}
 
-	fullDiskPath := filepath.Join("./uploads", filename)
+// ListPDFs recorre el directorio de subidas. Se ignoran los temporales (".upload-*")
+// y el subdirectorio de cuarentena.
+func (fsl *FileStorageLocal) ListPDFs(ctx context.Context) ([]domain.StoredObject, error) {
+	entries, err := os.ReadDir(fsl.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot list uploads dir: %w", err)
+	}
 
-	if _, err := os.Stat(fullDiskPath); err != nil {
+	objects := make([]domain.StoredObject, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			// El archivo pudo borrarse mientras listábamos
+			continue
+		}
+		objects = append(objects, domain.StoredObject{
+			RelPath: relPathFor(entry.Name()),
+			Size:    info.Size(),
+			ModTime: info.ModTime(),
+		})
+	}
+	return objects, nil
+}
+
+// QuarantinePDF mueve el archivo a <dir>/quarantine para poder recuperarlo si hiciera falta.
+func (fsl *FileStorageLocal) QuarantinePDF(ctx context.Context, relPath string) error {
+	target := filepath.Join(fsl.dir, quarantineDir)
+	if err := os.MkdirAll(target, os.ModePerm); err != nil {
+		return fmt.Errorf("cannot create quarantine dir: %w", err)
+	}
+	if err := os.Rename(fsl.diskPath(relPath), filepath.Join(target, filepath.Base(relPath))); err != nil {
 		if os.IsNotExist(err) {
-			return nil
+			return domain.ErrNotFound
 		}
-		return err
+		return fmt.Errorf("cannot quarantine file: %w", err)
 	}
+	return nil
+}
 
-	return os.Remove(fullDiskPath)
+// diskPath traduce "/uploads/xxx.pdf" a la ruta real dentro de fsl.dir.
+// Solo se usa el nombre base para que un rel_path manipulado no salga del directorio.
+func (fsl *FileStorageLocal) diskPath(relPath string) string {
+	return filepath.Join(fsl.dir, filepath.Base(relPath))
 }
@@ -0,0 +1,308 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/types"
+)
+
+// Qué hacer con el contenido activo o los adjuntos de un PDF.
+const (
+	PDFActionStrip  = "strip"  // se elimina y el archivo se guarda reescrito
+	PDFActionReject = "reject" // la subida falla
+)
+
+// pdfHeaderWindow es cuánto se busca la cabecera %PDF- al inicio del archivo,
+// los lectores la aceptan aunque venga precedida de basura.
+const pdfHeaderWindow = 1024
+
+func init() {
+	// pdfcpu por defecto crea un directorio de configuración en el home del usuario
+	api.DisableConfigDir()
+}
+
+// PDFPolicy define cómo se validan y sanean los PDFs subidos.
+type PDFPolicy struct {
+	ActiveContent  string // strip, reject: JavaScript y acciones Launch
+	Attachments    string // strip, reject: archivos embebidos y anotaciones de adjunto
+	AllowEncrypted bool   // aceptar PDFs cifrados (solo si se pueden abrir sin contraseña)
+	MaxPages       int    // 0 = sin límite
+	Strict         bool   // validación estricta según PDF 32000-1:2008
+}
+
+// PDFReport resume lo encontrado al inspeccionar un PDF.
+type PDFReport struct {
+	PageCount   int
+	Encrypted   bool
+	JavaScript  int
+	Launch      int
+	Attachments int
+	Sanitized   bool // el archivo se reescribió para quitar contenido
+}
+
+// InspectPDF valida la estructura de r y aplica la política. Si hay que quitar contenido
+// escribe la versión saneada en w y devuelve Sanitized = true; si no, w no se toca.
+// Los rechazos devuelven un error que envuelve domain.ErrInvalidPDF.
+func InspectPDF(r io.ReadSeeker, w io.Writer, policy PDFPolicy) (report *PDFReport, err error) {
+	// pdfcpu puede entrar en pánico con archivos malformados; eso no puede tirar el servidor
+	defer func() {
+		if rec := recover(); rec != nil {
+			report, err = nil, fmt.Errorf("%w: malformed document (%v)", domain.ErrInvalidPDF, rec)
+		}
+	}()
+
+	head := make([]byte, pdfHeaderWindow)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("cannot read file: %w", err)
+	}
+	if !bytes.Contains(head[:n], []byte("%PDF-")) {
+		return nil, fmt.Errorf("%w: missing PDF header", domain.ErrInvalidPDF)
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("cannot rewind file: %w", err)
+	}
+
+	conf := model.NewDefaultConfiguration()
+	conf.ValidationMode = model.ValidationRelaxed
+	if policy.Strict {
+		conf.ValidationMode = model.ValidationStrict
+	}
+
+	ctx, err := api.ReadContext(r, conf)
+	if err != nil {
+		if errors.Is(err, pdfcpu.ErrWrongPassword) {
+			return nil, fmt.Errorf("%w: password protected", domain.ErrInvalidPDF)
+		}
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidPDF, err)
+	}
+
+	report = &PDFReport{Encrypted: ctx.XRefTable.Encrypt != nil}
+	if report.Encrypted && !policy.AllowEncrypted {
+		return nil, fmt.Errorf("%w: encrypted PDFs are not allowed", domain.ErrInvalidPDF)
+	}
+
+	if err := api.ValidateContext(ctx); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidPDF, err)
+	}
+	if err := ctx.XRefTable.EnsurePageCount(); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidPDF, err)
+	}
+	report.PageCount = ctx.PageCount
+	if report.PageCount == 0 {
+		return nil, fmt.Errorf("%w: document has no pages", domain.ErrInvalidPDF)
+	}
+	if policy.MaxPages > 0 && report.PageCount > policy.MaxPages {
+		return nil, fmt.Errorf("%w: %d pages exceeds the limit of %d", domain.ErrInvalidPDF, report.PageCount, policy.MaxPages)
+	}
+
+	// Primera pasada solo para contar, así podemos rechazar sin tocar nada
+	s := &pdfSanitizer{xRefTable: ctx.XRefTable}
+	s.walkTable(ctx.XRefTable)
+	if err := s.walkNames(ctx.XRefTable); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidPDF, err)
+	}
+	report.JavaScript, report.Launch, report.Attachments = s.javaScript, s.launch, s.attachments
+
+	hasActive := s.javaScript+s.launch > 0
+	if hasActive && policy.ActiveContent == PDFActionReject {
+		return nil, fmt.Errorf("%w: active content (JavaScript or launch actions) is not allowed", domain.ErrInvalidPDF)
+	}
+	if s.attachments > 0 && policy.Attachments == PDFActionReject {
+		return nil, fmt.Errorf("%w: attachments are not allowed", domain.ErrInvalidPDF)
+	}
+	if !hasActive && s.attachments == 0 {
+		return report, nil
+	}
+
+	s = &pdfSanitizer{xRefTable: ctx.XRefTable, strip: true}
+	s.walkTable(ctx.XRefTable)
+	if err := s.walkNames(ctx.XRefTable); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidPDF, err)
+	}
+	if err := api.WriteContext(ctx, w); err != nil {
+		return nil, fmt.Errorf("cannot write sanitized PDF: %w", err)
+	}
+	report.Sanitized = true
+	return report, nil
+}
+
+// pdfSanitizer recorre los objetos del documento contando (y con strip, neutralizando)
+// acciones JavaScript/Launch y adjuntos. Se recorren también los objetos directos anidados
+// porque las acciones suelen venir inline dentro de anotaciones o del catálogo.
+// Al neutralizar se quitan las referencias a las acciones, así el writer ni siquiera las escribe.
+type pdfSanitizer struct {
+	xRefTable   *model.XRefTable
+	strip       bool
+	javaScript  int
+	launch      int
+	attachments int
+}
+
+func (s *pdfSanitizer) walkTable(xRefTable *model.XRefTable) {
+	for _, entry := range xRefTable.Table {
+		if entry == nil || entry.Free || entry.Object == nil {
+			continue
+		}
+		s.walk(entry.Object)
+	}
+}
+
+func (s *pdfSanitizer) walk(o types.Object) {
+	switch obj := o.(type) {
+	case types.Dict:
+		s.inspectDict(obj)
+		for _, v := range obj {
+			s.walk(v)
+		}
+	case types.StreamDict:
+		s.inspectDict(obj.Dict)
+		for _, v := range obj.Dict {
+			s.walk(v)
+		}
+	case *types.StreamDict:
+		s.inspectDict(obj.Dict)
+		for _, v := range obj.Dict {
+			s.walk(v)
+		}
+	case types.Array:
+		for _, v := range obj {
+			s.walk(v)
+		}
+	}
+}
+
+// actionKeys son las entradas de un diccionario que pueden disparar una acción.
+var actionKeys = []string{"OpenAction", "A", "Next"}
+
+func (s *pdfSanitizer) inspectDict(d types.Dict) {
+	if s.strip {
+		s.dropActions(d)
+	}
+
+	if action := d.NameEntry("S"); action != nil {
+		switch *action {
+		case "JavaScript":
+			s.javaScript++
+			if s.strip {
+				d.Delete("JS")
+			}
+		case "Launch":
+			s.launch++
+			if s.strip {
+				for _, key := range []string{"F", "Win", "Mac", "Unix"} {
+					d.Delete(key)
+				}
+			}
+		}
+	} else if _, ok := d.Find("JS"); ok {
+		s.javaScript++
+		if s.strip {
+			d.Delete("JS")
+		}
+	}
+
+	if t := d.Type(); t != nil && *t == "Filespec" {
+		if _, ok := d.Find("EF"); ok {
+			s.attachments++
+			if s.strip {
+				d.Delete("EF")
+				d.Delete("RF")
+			}
+		}
+	}
+	if st := d.Subtype(); st != nil && *st == "FileAttachment" {
+		s.attachments++
+		if s.strip {
+			d.Delete("FS")
+		}
+	}
+}
+
+// dropActions quita de d las acciones peligrosas, incluidas las de /AA y las cadenas /Next.
+func (s *pdfSanitizer) dropActions(d types.Dict) {
+	for _, key := range actionKeys {
+		v, ok := d.Find(key)
+		if !ok {
+			continue
+		}
+		if arr, isArray := v.(types.Array); isArray {
+			kept := types.Array{}
+			for _, item := range arr {
+				if !s.isDangerous(item) {
+					kept = append(kept, item)
+				}
+			}
+			d[key] = kept
+			continue
+		}
+		if s.isDangerous(v) {
+			d.Delete(key)
+		}
+	}
+
+	aa, ok := d.Find("AA")
+	if !ok {
+		return
+	}
+	triggers, err := s.xRefTable.DereferenceDict(aa)
+	if err != nil || triggers == nil {
+		return
+	}
+	for trigger, v := range triggers {
+		if s.isDangerous(v) {
+			triggers.Delete(trigger)
+		}
+	}
+	if len(triggers) == 0 {
+		d.Delete("AA")
+	}
+}
+
+func (s *pdfSanitizer) isDangerous(o types.Object) bool {
+	action, err := s.xRefTable.DereferenceDict(o)
+	if err != nil || action == nil {
+		return false
+	}
+	if _, ok := action.Find("JS"); ok {
+		return true
+	}
+	name := action.NameEntry("S")
+	return name != nil && (*name == "JavaScript" || *name == "Launch")
+}
+
+// walkNames cuenta y elimina los árboles de nombres de scripts y adjuntos del catálogo.
+func (s *pdfSanitizer) walkNames(xRefTable *model.XRefTable) error {
+	catalog, err := xRefTable.Catalog()
+	if err != nil {
+		return err
+	}
+	obj, ok := catalog.Find("Names")
+	if !ok {
+		return nil
+	}
+	names, err := xRefTable.DereferenceDict(obj)
+	if err != nil || names == nil {
+		return err
+	}
+	if _, ok := names.Find("JavaScript"); ok {
+		s.javaScript++
+		if s.strip {
+			names.Delete("JavaScript")
+		}
+	}
+	if _, ok := names.Find("EmbeddedFiles"); ok {
+		s.attachments++
+		if s.strip {
+			names.Delete("EmbeddedFiles")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/config"
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// FileStorageS3 guarda los comprobantes en un bucket compatible con S3 (AWS S3, MinIO, ...).
+// Permite correr varias instancias de la API compartiendo los mismos archivos.
+type FileStorageS3 struct {
+	client  *minio.Client
+	bucket  string
+	maxSize int64
+}
+
+// s3MinPartSize es el tamaño mínimo de parte que acepta S3 en subidas multipart.
+const s3MinPartSize = 5 << 20
+
+func NewFileStorageS3(cfg config.S3StorageConfig, maxSize int64) (domain.FileStorage, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("s3 storage requires endpoint and bucket")
+	}
+
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot create s3 client: %w", err)
+	}
+
+	// Con MinIO local el bucket suele no existir todavía: lo creamos al arrancar
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	exists, err := client.BucketExists(ctx, cfg.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("cannot reach s3 bucket: %w", err)
+	}
+	if !exists {
+		if err := client.MakeBucket(ctx, cfg.Bucket, minio.MakeBucketOptions{Region: cfg.Region}); err != nil {
+			return nil, fmt.Errorf("cannot create s3 bucket: %w", err)
+		}
+	}
+
+	return &FileStorageS3{
+		client:  client,
+		bucket:  cfg.Bucket,
+		maxSize: maxSize,
+	}, nil
+}
+
+// SavePDF sube el archivo en streaming calculando el checksum al vuelo.
+// Con tamaño desconocido se usa multipart con partes chicas para no reservar buffers enormes.
+func (fss *FileStorageS3) SavePDF(ctx context.Context, r io.Reader, size int64) (*domain.StoredFile, error) {
+	if r == nil {
+		return nil, fmt.Errorf("file is required")
+	}
+	if fss.maxSize > 0 && size > fss.maxSize {
+		return nil, domain.ErrFileTooLarge
+	}
+
+	hash := sha256.New()
+	reader := io.TeeReader(newLimitedReader(r, fss.maxSize), hash)
+
+	filename := newReceiptName()
+	relPath := relPathFor(filename)
+
+	opts := minio.PutObjectOptions{ContentType: "application/pdf"}
+	if size < 0 {
+		opts.PartSize = s3MinPartSize
+	}
+
+	info, err := fss.client.PutObject(ctx, fss.bucket, objectKey(relPath), reader, size, opts)
+	if err != nil {
+		if errors.Is(err, domain.ErrFileTooLarge) {
+			return nil, domain.ErrFileTooLarge
+		}
+		return nil, fmt.Errorf("cannot upload file: %w", err)
+	}
+
+	return &domain.StoredFile{
+		FileName: filename,
+		RelPath:  relPath,
+		Checksum: fmt.Sprintf("%x", hash.Sum(nil)),
+		Size:     info.Size,
+	}, nil
+}
+
+func (fss *FileStorageS3) OpenPDF(ctx context.Context, relPath string) (io.ReadCloser, error) {
+	// GetObject es perezoso: hacemos Stat primero para devolver ErrNotFound a tiempo
+	if _, err := fss.client.StatObject(ctx, fss.bucket, objectKey(relPath), minio.StatObjectOptions{}); err != nil {
+		if isS3NotFound(err) {
+			return nil, domain.ErrNotFound
+		}
+		return nil, fmt.Errorf("cannot stat file: %w", err)
+	}
+
+	obj, err := fss.client.GetObject(ctx, fss.bucket, objectKey(relPath), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("cannot open file: %w", err)
+	}
+	return obj, nil
+}
+
+func (fss *FileStorageS3) DeletePDF(ctx context.Context, filePath string) error {
+	if filePath == "" {
+		return nil
+	}
+	return fss.removeObject(ctx, objectKey(filePath))
+}
+
+// DeleteQuarantinedPDF borra el objeto que QuarantinePDF copió a quarantine/.
+func (fss *FileStorageS3) DeleteQuarantinedPDF(ctx context.Context, relPath string) error {
+	if relPath == "" {
+		return nil
+	}
+	return fss.removeObject(ctx, path.Join(quarantineDir, path.Base(relPath)))
+}
+
+// removeObject borra key. RemoveObject no falla si el objeto ya no existe,
+// así que antes se comprueba que esté para poder devolver ErrNotFound.
+func (fss *FileStorageS3) removeObject(ctx context.Context, key string) error {
+	if _, err := fss.client.StatObject(ctx, fss.bucket, key, minio.StatObjectOptions{}); err != nil {
+		if isS3NotFound(err) {
+			return domain.ErrNotFound
+		}
+		return fmt.Errorf("cannot stat object: %w", err)
+	}
+	return fss.client.RemoveObject(ctx, fss.bucket, key, minio.RemoveObjectOptions{})
+}
+
+// ListPDFs lista los objetos bajo el prefijo de subidas.
+func (fss *FileStorageS3) ListPDFs(ctx context.Context) ([]domain.StoredObject, error) {
+	var objects []domain.StoredObject
+	for obj := range fss.client.ListObjects(ctx, fss.bucket, minio.ListObjectsOptions{
+		Prefix:    objectKey(relPrefix),
+		Recursive: true,
+	}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("cannot list bucket: %w", obj.Err)
+		}
+		objects = append(objects, domain.StoredObject{
+			RelPath: "/" + obj.Key,
+			Size:    obj.Size,
+			ModTime: obj.LastModified,
+		})
+	}
+	return objects, nil
+}
+
+// QuarantinePDF copia el objeto a quarantine/ y después borra el original.
+func (fss *FileStorageS3) QuarantinePDF(ctx context.Context, relPath string) error {
+	src := minio.CopySrcOptions{Bucket: fss.bucket, Object: objectKey(relPath)}
+	dst := minio.CopyDestOptions{Bucket: fss.bucket, Object: path.Join(quarantineDir, path.Base(relPath))}
+	if _, err := fss.client.CopyObject(ctx, dst, src); err != nil {
+		if isS3NotFound(err) {
+			return domain.ErrNotFound
+		}
+		return fmt.Errorf("cannot quarantine file: %w", err)
+	}
+	return fss.client.RemoveObject(ctx, fss.bucket, objectKey(relPath), minio.RemoveObjectOptions{})
+}
+
+func isS3NotFound(err error) bool {
+	resp := minio.ToErrorResponse(err)
+	return resp.Code == "NoSuchKey" || resp.Code == "NotFound"
+}
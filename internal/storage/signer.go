@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrURLExpired       = errors.New("signed url expired")
+)
+
+// URLSigner firma y valida URLs de descarga de comprobantes con HMAC-SHA256.
+// La firma cubre el receipt, el usuario al que se emitió y la expiración,
+// así un enlace filtrado deja de servir al vencer y no puede reutilizarse para otro archivo.
+type URLSigner struct {
+	key []byte
+	ttl time.Duration
+}
+
+// NewURLSigner crea el firmador. La clave es obligatoria: con una vacía cualquiera podría
+// firmar enlaces a cualquier comprobante.
+func NewURLSigner(key string, ttl time.Duration) (*URLSigner, error) {
+	if key == "" {
+		return nil, errors.New("url sign key is required")
+	}
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	return &URLSigner{key: []byte(key), ttl: ttl}, nil
+}
+
+// Sign devuelve la firma y la expiración para que userID descargue receiptID.
+func (s *URLSigner) Sign(receiptID, userID uint) (string, time.Time) {
+	expiresAt := time.Now().Add(s.ttl).Truncate(time.Second)
+	return s.signature(receiptID, userID, expiresAt.Unix()), expiresAt
+}
+
+// Verify comprueba la firma (en tiempo constante) y que no haya expirado.
+func (s *URLSigner) Verify(receiptID, userID uint, expires int64, signature string) error {
+	expected := s.signature(receiptID, userID, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	if time.Now().Unix() > expires {
+		return ErrURLExpired
+	}
+	return nil
+}
+
+func (s *URLSigner) signature(receiptID, userID uint, expires int64) string {
+	mac := hmac.New(sha256.New, s.key)
+	_, _ = fmt.Fprintf(mac, "%d:%d:%d", receiptID, userID, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/config"
+	"github.com/SaidMg10/gestor-one/internal/domain"
+)
+
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+// relPrefix es el prefijo lógico que se guarda en receipts.rel_path.
+// Es el mismo para todos los backends, así los registros no dependen de dónde vive el archivo.
+const relPrefix = "/uploads/"
+
+// quarantineDir es donde se mueven los archivos huérfanos en lugar de borrarlos.
+const quarantineDir = "quarantine"
+
+// New construye el FileStorage indicado en la configuración.
+// Si el cifrado está activo, el backend queda envuelto en un EncryptedStorage.
+func New(cfg config.StorageConfig) (domain.FileStorage, error) {
+	backendMax := cfg.MaxFileSize
+	if cfg.Encryption.Enabled {
+		backendMax = maxCiphertextSize(cfg.MaxFileSize)
+	}
+
+	var backend domain.FileStorage
+	switch cfg.Driver {
+	case "", DriverLocal:
+		backend = NewFileStorageLocal(cfg.LocalDir, backendMax)
+	case DriverS3:
+		s3, err := NewFileStorageS3(cfg.S3, backendMax)
+		if err != nil {
+			return nil, err
+		}
+		backend = s3
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+	}
+
+	if !cfg.Encryption.Enabled {
+		return backend, nil
+	}
+
+	keyring, err := NewKeyring(cfg.Encryption)
+	if err != nil {
+		return nil, err
+	}
+	return NewEncryptedStorage(backend, keyring, cfg.MaxFileSize), nil
+}
+
+func newReceiptName() string {
+	return fmt.Sprintf("%d_receipt.pdf", time.Now().UnixNano())
+}
+
+func relPathFor(filename string) string {
+	return relPrefix + filename
+}
+
+// objectKey convierte un rel_path ("/uploads/xxx.pdf") en la key del objeto ("uploads/xxx.pdf").
+func objectKey(relPath string) string {
+	return strings.TrimPrefix(relPath, "/")
+}
+
+// limitedReader corta la lectura con domain.ErrFileTooLarge en cuanto se supera max.
+// A diferencia de io.LimitReader no trunca en silencio: el archivo se rechaza.
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func newLimitedReader(r io.Reader, max int64) io.Reader {
+	if max <= 0 {
+		return r
+	}
+	return &limitedReader{r: r, remaining: max}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	// Leemos hasta un byte más del límite para detectar el exceso
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n, domain.ErrFileTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,115 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret es la clave "12345678901234567890" de los vectores SHA1 del RFC 6238 en base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238(t *testing.T) {
+	// El RFC publica códigos de 8 dígitos; los de 6 son sus últimos 6
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(t=%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(t=%d) = %s; want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestCodeAcceptsLowercaseAndPadding(t *testing.T) {
+	step := Step(time.Unix(59, 0))
+	for _, secret := range []string{"gezdgnbvgy3tqojqgezdgnbvgy3tqojq", rfcSecret + "===="} {
+		got, err := Code(secret, step)
+		if err != nil || got != "287082" {
+			t.Errorf("Code(%q) = %q, %v; want 287082", secret, got, err)
+		}
+	}
+	if _, err := Code("not base32!", step); err == nil {
+		t.Error("Code with an invalid secret: want error")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Step(now)
+	code := func(s int64) string {
+		c, err := Code(rfcSecret, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"intervalo actual", code(step), step, true},
+		{"intervalo anterior", code(step - 1), step - 1, true},
+		{"intervalo siguiente", code(step + 1), step + 1, true},
+		{"con espacios", " " + code(step)[:3] + " " + code(step)[3:] + " ", step, true},
+		{"fuera de la ventana", code(step - 2), 0, false},
+		{"largo incorrecto", code(step)[:5], 0, false},
+		{"vacío", "", 0, false},
+	}
+	for _, tt := range tests {
+		gotStep, ok := Validate(rfcSecret, tt.code, now)
+		if ok != tt.wantOK || gotStep != tt.wantStep {
+			t.Errorf("%s: Validate(%q) = %d, %v; want %d, %v", tt.name, tt.code, gotStep, ok, tt.wantStep, tt.wantOK)
+		}
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	a, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := GenerateSecret()
+	if len(a) != 32 || a == b {
+		t.Errorf("GenerateSecret() = %q, %q; want two distinct 32-char secrets", a, b)
+	}
+	if _, err := Code(a, 1); err != nil {
+		t.Errorf("Code with generated secret: %v", err)
+	}
+}
+
+func TestURI(t *testing.T) {
+	u, err := url.Parse(URI("Gestor One", "ana@example.com", rfcSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Gestor One:ana@example.com" {
+		t.Errorf("URI = %s", u)
+	}
+	q := u.Query()
+	want := map[string]string{"secret": rfcSecret, "issuer": "Gestor One", "algorithm": "SHA1", "digits": "6", "period": "30"}
+	for k, v := range want {
+		if q.Get(k) != v {
+			t.Errorf("URI %s = %q; want %q", k, q.Get(k), v)
+		}
+	}
+	if strings.Contains(u.RawQuery, "+") {
+		t.Errorf("URI query uses '+' for spaces: %s", u.RawQuery)
+	}
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"path/filepath"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type AnalysisHandler struct {
+	svc *service.AnalysisService
+}
+
+func NewAnalysisHandler(svc *service.AnalysisService) *AnalysisHandler {
+	return &AnalysisHandler{
+		svc: svc,
+	}
+}
+
+// Analyze sugiere monto, fecha, identificación fiscal y proveedor de un comprobante.
+// El PDF puede venir en el multipart ("file") o como upload_id de una subida reanudable.
+func (h *AnalysisHandler) Analyze(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var (
+		analysis *domain.ReceiptAnalysis
+		err      error
+	)
+	file, fileHeader, fileErr := c.Request.FormFile("file")
+	switch {
+	case fileErr == nil:
+		defer func() {
+			if err := file.Close(); err != nil {
+				log.Printf("failed to close file: %v", err)
+			}
+		}()
+		if filepath.Ext(fileHeader.Filename) != ".pdf" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "only PDF files are allowed"})
+			return
+		}
+		analysis, err = h.svc.AnalyzeFile(c.Request.Context(), file, fileHeader.Size)
+	case isTooLarge(fileErr):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "receipt file is too large"})
+		return
+	case c.PostForm("upload_id") != "":
+		analysis, err = h.svc.AnalyzeUpload(c.Request.Context(), user.ID, c.PostForm("upload_id"))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file or upload_id is required"})
+		return
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "upload not found"})
+		case errors.Is(err, domain.ErrUploadIncomplete):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrQuarantined):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(uploadErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, analysis)
+}
@@ -0,0 +1,128 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	svc *service.APIKeyService
+}
+
+func NewAPIKeyHandler(svc *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{svc: svc}
+}
+
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays int      `json:"expires_in_days"` // 0 = vencimiento por defecto
+}
+
+// CreateAPIKeyResponse trae el secreto de la key; es la única vez que se muestra.
+type CreateAPIKeyResponse struct {
+	domain.APIKey
+	Token string `json:"token"`
+}
+
+// Scopes lista los scopes que se pueden pedir al crear una key.
+func (h *APIKeyHandler) Scopes(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"scopes": domain.APIKeyScopes})
+}
+
+// Create emite una API key para el usuario autenticado.
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ExpiresInDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_in_days must be positive"})
+		return
+	}
+
+	ttl := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	key, token, err := h.svc.Create(c.Request.Context(), user, req.Name, req.Scopes, ttl)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidInput) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, CreateAPIKeyResponse{APIKey: *key, Token: token})
+}
+
+// List lista las API keys del usuario autenticado.
+func (h *APIKeyHandler) List(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	h.list(c, user.ID)
+}
+
+// Revoke revoca una API key del usuario autenticado.
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	h.revoke(c, user.ID)
+}
+
+// ListByUser lista las API keys del usuario :id (admin).
+func (h *APIKeyHandler) ListByUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	h.list(c, uint(id))
+}
+
+// RevokeAny revoca cualquier API key (admin).
+func (h *APIKeyHandler) RevokeAny(c *gin.Context) {
+	h.revoke(c, 0)
+}
+
+func (h *APIKeyHandler) list(c *gin.Context, userID uint) {
+	keys, err := h.svc.List(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if keys == nil {
+		keys = []domain.APIKey{}
+	}
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+func (h *APIKeyHandler) revoke(c *gin.Context, ownerID uint) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.svc.Revoke(c.Request.Context(), ownerID, uint(id)); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -1,8 +1,12 @@
 package http
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
+	"github.com/SaidMg10/gestor-one/internal/domain"
 	"github.com/SaidMg10/gestor-one/internal/service"
 	"github.com/gin-gonic/gin"
 )
@@ -24,13 +28,25 @@ type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+// LoginResponse trae los tokens o, si falta el segundo factor, el mfa_token para
+// completar el login en /auth/2fa/verify (o darlo de alta en /auth/2fa/setup y /confirm).
 type LoginResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken           string   `json:"access_token,omitempty"`
+	RefreshToken          string   `json:"refresh_token,omitempty"`
+	MFARequired           bool     `json:"mfa_required,omitempty"`
+	MFAToken              string   `json:"mfa_token,omitempty"`
+	MFAEnrollmentRequired bool     `json:"mfa_enrollment_required,omitempty"`
+	RecoveryCodes         []string `json:"recovery_codes,omitempty"`
+}
+
+type MFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code"`
 }
 
 type RefreshTokenResponse struct {
-	AccessToken string `json:"access_token"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 // Login is the handler for user login.
@@ -41,19 +57,102 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	accessToken, refreshToken, err := h.svc.Login(c.Request.Context(), req.Email, req.Password)
+	result, err := h.svc.Login(c.Request.Context(), req.Email, req.Password, clientInfo(c))
 	if err != nil {
+		if writeThrottled(c, err) {
+			return
+		}
+		if errors.Is(err, domain.ErrSSORequired) || errors.Is(err, domain.ErrAccountDisabled) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		// Manejar errores de login (usuario no encontrado, contraseña incorrecta)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 		return
 	}
 
-	resp := LoginResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+	c.JSON(http.StatusOK, loginResponse(result))
+}
+
+// VerifyMFA completa el login de un usuario con 2FA con un código TOTP o de recuperación.
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req MFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.svc.CompleteLogin(c.Request.Context(), req.MFAToken, req.Code, clientInfo(c))
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, loginResponse(result))
+}
+
+// SetupMFA da de alta el 2FA durante el login cuando el rol lo exige y el usuario no lo
+// tiene. Devuelve el secreto y la URI otpauth:// para mostrar como QR.
+func (h *AuthHandler) SetupMFA(c *gin.Context) {
+	var req MFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	secret, uri, err := h.svc.StartEnrollment(c.Request.Context(), req.MFAToken)
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, TwoFactorSetupResponse{Secret: secret, OTPAuthURI: uri})
+}
+
+// ConfirmMFA confirma el alta con el primer código y completa el login. La respuesta
+// incluye los códigos de recuperación, que no se vuelven a mostrar.
+func (h *AuthHandler) ConfirmMFA(c *gin.Context) {
+	var req MFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.svc.CompleteEnrollment(c.Request.Context(), req.MFAToken, req.Code, clientInfo(c))
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
 	}
+	c.JSON(http.StatusOK, loginResponse(result))
+}
 
-	c.JSON(http.StatusOK, resp)
+// JWKS publica las claves públicas con las que se verifican nuestros tokens, para que
+// otros servicios los validen sin el secreto.
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.svc.Authenticator.JWKS())
+}
+
+// writeThrottled responde 429 con Retry-After si el login está demorado o bloqueado por
+// intentos fallidos, y devuelve si lo hizo.
+func writeThrottled(c *gin.Context, err error) bool {
+	var throttled *domain.ThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	seconds := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": throttled.Error(), "retry_after": seconds})
+	return true
+}
+
+func loginResponse(r *service.LoginResult) LoginResponse {
+	return LoginResponse{
+		AccessToken:           r.AccessToken,
+		RefreshToken:          r.RefreshToken,
+		MFARequired:           r.MFAToken != "",
+		MFAToken:              r.MFAToken,
+		MFAEnrollmentRequired: r.MFAEnrollment,
+		RecoveryCodes:         r.RecoveryCodes,
+	}
 }
 
 // RefreshToken is the handler for refreshing access tokens.
@@ -69,16 +168,59 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// Llamamos al service para generar un nuevo access token
-	accessToken, err := h.svc.Refresh(c.Request.Context(), req.RefreshToken)
+	// El refresh token se rota: el cliente debe guardar el nuevo y descartar el anterior
+	accessToken, refreshToken, err := h.svc.Refresh(c.Request.Context(), req.RefreshToken, clientInfo(c))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired refresh token"})
+		if errors.Is(err, domain.ErrInvalidToken) || errors.Is(err, domain.ErrTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired refresh token"})
+			return
+		}
+		if errors.Is(err, domain.ErrAccountDisabled) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
 	resp := RefreshTokenResponse{
-		AccessToken: accessToken,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
 	}
 
 	c.JSON(200, resp)
 }
+
+// Logout revoca el refresh token recibido y los de su misma familia (esta sesión).
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "refresh token is required"})
+		return
+	}
+
+	if err := h.svc.Logout(c.Request.Context(), req.RefreshToken); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
+}
+
+// LogoutAll revoca todas las sesiones del usuario autenticado, con sus refresh tokens.
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.LogoutAll(c.Request.Context(), user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "logged out from all sessions"})
+}
@@ -3,6 +3,7 @@ package http
 import (
 	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"path/filepath"
 	"strconv"
@@ -37,35 +38,49 @@ type UpdateExpenseRequest struct {
 }
 
 type ExpenseResponse struct {
-	ID          uint    `json:"id"`
-	Amount      float64 `json:"amount"`
-	Description string  `json:"description"`
-	Type        string  `json:"type"`
-	ReceiptFile string  `json:"receipt_file"`
+	ID             uint    `json:"id"`
+	Amount         float64 `json:"amount"`
+	Description    string  `json:"description"`
+	Type           string  `json:"type"`
+	ReceiptFile    string  `json:"receipt_file"`
+	ReceiptPending bool    `json:"receipt_pending"`
+}
+
+// DeletedExpenseResponse es un gasto en la papelera.
+type DeletedExpenseResponse struct {
+	ExpenseResponse
+	Date      time.Time `json:"date"`
+	CreatedBy uint      `json:"created_by"`
+	DeletedAt time.Time `json:"deleted_at"`
 }
 
 func (h *ExpenseHandler) Create(c *gin.Context) {
 	var req CreateExpenseRequest
 	if err := c.ShouldBind(&req); err != nil {
+		if isTooLarge(err) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "receipt file is too large"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	file, fileHeader, err := c.Request.FormFile("receipt")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "receipt file is required"})
-		return
-	}
+	// El comprobante puede venir en el multipart o como upload_id de una subida reanudable;
+	// sin ninguno de los dos el gasto queda con el comprobante pendiente
+	uploadID := c.PostForm("upload_id")
+	file, fileHeader, _ := c.Request.FormFile("receipt")
 
-	defer func() {
-		if err := file.Close(); err != nil {
-			fmt.Printf("failed to close file: %v\n", err)
-		}
-	}()
+	if file != nil {
+		defer func() {
+			if err := file.Close(); err != nil {
+				log.Printf("failed to close file: %v", err)
+			}
+		}()
 
-	if filepath.Ext(fileHeader.Filename) != ".pdf" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "only PDF files are allowed"})
-		return
+		if filepath.Ext(fileHeader.Filename) != ".pdf" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "only PDF files are allowed"})
+			return
+		}
 	}
 
 	userCtx, exists := c.Get("user")
@@ -87,17 +102,18 @@ func (h *ExpenseHandler) Create(c *gin.Context) {
 		CreatedBy:   user.ID,
 	}
 
-	if err := h.svc.Create(c.Request.Context(), expense, file, fileHeader); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := h.svc.Create(c.Request.Context(), expense, file, fileHeader, uploadID); err != nil {
+		c.JSON(uploadErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
 		return
 	}
 
 	resp := ExpenseResponse{
-		ID:          expense.ID,
-		Amount:      expense.Amount,
-		Description: expense.Description,
-		Type:        string(expense.Type),
-		ReceiptFile: expense.Receipt.RelPath,
+		ID:             expense.ID,
+		Amount:         expense.Amount,
+		Description:    expense.Description,
+		Type:           string(expense.Type),
+		ReceiptFile:    expense.Receipt.RelPath,
+		ReceiptPending: expense.ReceiptPending,
 	}
 
 	c.JSON(http.StatusCreated, resp)
@@ -112,11 +128,12 @@ func (h *ExpenseHandler) List(c *gin.Context) {
 	expenseResponses := make([]ExpenseResponse, len(expenses))
 	for i, expense := range expenses {
 		expenseResponses[i] = ExpenseResponse{
-			ID:          expense.ID,
-			Amount:      expense.Amount,
-			Description: expense.Description,
-			Type:        string(expense.Type),
-			ReceiptFile: expense.Receipt.RelPath,
+			ID:             expense.ID,
+			Amount:         expense.Amount,
+			Description:    expense.Description,
+			Type:           string(expense.Type),
+			ReceiptFile:    expense.Receipt.RelPath,
+			ReceiptPending: expense.ReceiptPending,
 		}
 	}
 	c.JSON(http.StatusOK, expenseResponses)
@@ -139,11 +156,12 @@ func (h *ExpenseHandler) GetByID(c *gin.Context) {
 		return
 	}
 	response := ExpenseResponse{
-		ID:          expense.ID,
-		Amount:      expense.Amount,
-		Description: expense.Description,
-		Type:        string(expense.Type),
-		ReceiptFile: expense.Receipt.RelPath,
+		ID:             expense.ID,
+		Amount:         expense.Amount,
+		Description:    expense.Description,
+		Type:           string(expense.Type),
+		ReceiptFile:    expense.Receipt.RelPath,
+		ReceiptPending: expense.ReceiptPending,
 	}
 	c.JSON(http.StatusOK, response)
 }
@@ -156,27 +174,41 @@ func (h *ExpenseHandler) DownloadReceipt(c *gin.Context) {
 		return
 	}
 
-	expense, err := h.svc.GetByID(c.Request.Context(), uint(id))
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	expense, file, err := h.svc.OpenReceipt(c.Request.Context(), uint(id), user)
 	if err != nil {
 		if errors.Is(err, domain.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else if errors.Is(err, domain.ErrQuarantined) || errors.Is(err, domain.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		}
 		return
 	}
+	defer func() {
+		_ = file.Close()
+	}()
 
-	// RelPath: /uploads/archivo.pdf -> archivo real en ./uploads
+	// El archivo se transmite directamente desde el backend de storage (disco o S3)
 	filename := filepath.Base(expense.Receipt.RelPath)
-	fullPath := filepath.Join("./uploads", filename)
-
-	c.FileAttachment(fullPath, filename)
+	c.DataFromReader(http.StatusOK, -1, expense.Receipt.MimeType, file, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, filename),
+	})
 }
 
 func (h *ExpenseHandler) Update(c *gin.Context) {
 	var req UpdateExpenseRequest
 
 	if err := c.ShouldBind(&req); err != nil {
+		if isTooLarge(err) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "receipt file is too large"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -210,16 +242,90 @@ func (h *ExpenseHandler) Update(c *gin.Context) {
 	}
 
 	file, fileHeader, _ := c.Request.FormFile("receipt")
+	if file != nil {
+		defer func() {
+			if err := file.Close(); err != nil {
+				log.Printf("failed to close file: %v", err)
+			}
+		}()
+
+		if filepath.Ext(fileHeader.Filename) != ".pdf" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "only PDF files are allowed"})
+			return
+		}
+	}
 
-	err := h.svc.Update(c, uint(id), expense, file, fileHeader, user.ID)
+	err := h.svc.Update(c, uint(id), expense, file, fileHeader, c.PostForm("upload_id"), user.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(uploadErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "expense updated"})
 }
 
+// AttachReceipt adjunta el comprobante (multipart "receipt" o upload_id) a un gasto
+// creado sin él.
+func (h *ExpenseHandler) AttachReceipt(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid expense ID"})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	uploadID := c.PostForm("upload_id")
+	file, fileHeader, err := c.Request.FormFile("receipt")
+	if isTooLarge(err) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "receipt file is too large"})
+		return
+	}
+	if err != nil && uploadID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "receipt file is required"})
+		return
+	}
+	if file != nil {
+		defer func() {
+			if err := file.Close(); err != nil {
+				log.Printf("failed to close file: %v", err)
+			}
+		}()
+
+		if filepath.Ext(fileHeader.Filename) != ".pdf" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "only PDF files are allowed"})
+			return
+		}
+	}
+
+	expense, err := h.svc.AttachReceipt(c.Request.Context(), uint(id), file, fileHeader, uploadID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "expense not found"})
+		case errors.Is(err, domain.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "only the creator can attach the receipt"})
+		case errors.Is(err, domain.ErrReceiptAttached):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(uploadErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, ExpenseResponse{
+		ID:             expense.ID,
+		Amount:         expense.Amount,
+		Description:    expense.Description,
+		Type:           string(expense.Type),
+		ReceiptFile:    expense.Receipt.FileName,
+		ReceiptPending: expense.ReceiptPending,
+	})
+}
+
 func (h *ExpenseHandler) SoftDelete(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 64)
@@ -273,6 +379,35 @@ func (h *ExpenseHandler) Delete(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "expense permanently deleted"})
 }
 
+// ListTrash lista los gastos borrados que todavía se pueden restaurar.
+func (h *ExpenseHandler) ListTrash(c *gin.Context) {
+	expenses, err := h.svc.ListDeleted(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	responses := make([]DeletedExpenseResponse, len(expenses))
+	for i, expense := range expenses {
+		responses[i] = DeletedExpenseResponse{
+			ExpenseResponse: ExpenseResponse{
+				ID:             expense.ID,
+				Amount:         expense.Amount,
+				Description:    expense.Description,
+				Type:           string(expense.Type),
+				ReceiptFile:    expense.Receipt.FileName,
+				ReceiptPending: expense.ReceiptPending,
+			},
+			Date:      expense.Date,
+			CreatedBy: expense.CreatedBy,
+		}
+		if expense.DeletedAt != nil {
+			responses[i].DeletedAt = *expense.DeletedAt
+		}
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
 func (h *ExpenseHandler) Restore(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 64)
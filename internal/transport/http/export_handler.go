@@ -0,0 +1,152 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ExportHandler struct {
+	svc *service.ExportService
+}
+
+func NewExportHandler(svc *service.ExportService) *ExportHandler {
+	return &ExportHandler{
+		svc: svc,
+	}
+}
+
+// ReceiptsZIP descarga en un ZIP los comprobantes que cumplen los filtros
+// (?from=2024-05-01&to=2024-05-31&type=invoice&user_id=3&kind=income|expense).
+func (h *ExportHandler) ReceiptsZIP(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	filter, err := parseExportFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Una vez que empieza el stream ya no se puede cambiar el status: si algo falla
+	// a mitad de camino el cliente recibe un ZIP truncado y el error queda en el log
+	filename := fmt.Sprintf("receipts_%s.zip", time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Cache-Control", "private, no-store")
+
+	w := &lazyStatusWriter{c: c}
+	if err := h.svc.WriteReceiptsZIP(c.Request.Context(), w, user, filter); err != nil {
+		if !w.started {
+			c.Header("Content-Type", "application/json")
+			c.Header("Content-Disposition", "")
+			if errors.Is(err, domain.ErrInvalidInput) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("receipts zip export aborted: %v", err)
+	}
+}
+
+// ReceiptsPDF descarga un único PDF con un índice y todos los comprobantes que cumplen
+// los filtros, con los mismos parámetros que ReceiptsZIP.
+func (h *ExportHandler) ReceiptsPDF(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	filter, err := parseExportFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// El PDF se arma completo antes de escribirse, así que los errores casi siempre
+	// llegan antes del primer byte y se pueden responder como JSON
+	w := &lazyStatusWriter{c: c}
+	filename := fmt.Sprintf("receipts_%s.pdf", time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "application/pdf")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Header("Cache-Control", "private, no-store")
+
+	if err := h.svc.WriteReceiptsBinder(c.Request.Context(), w, user, filter); err != nil {
+		if !w.started {
+			c.Header("Content-Type", "application/json")
+			c.Header("Content-Disposition", "")
+			if errors.Is(err, domain.ErrInvalidInput) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		log.Printf("receipts pdf export aborted: %v", err)
+	}
+}
+
+// lazyStatusWriter escribe el 200 recién con el primer byte, así los errores
+// previos (filtros inválidos, fallas al listar) todavía pueden responder JSON.
+type lazyStatusWriter struct {
+	c       *gin.Context
+	started bool
+}
+
+func (w *lazyStatusWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.c.Status(http.StatusOK)
+	}
+	return w.c.Writer.Write(p)
+}
+
+// parseExportFilter lee los filtros de listado desde la query.
+// Las fechas aceptan 2006-01-02 o RFC3339; un "to" sin hora incluye todo ese día.
+func parseExportFilter(c *gin.Context) (service.ExportFilter, error) {
+	filter := service.ExportFilter{Kind: c.Query("kind")}
+	filter.Type = c.Query("type")
+
+	if v := c.Query("from"); v != "" {
+		from, _, err := parseDateParam(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from date")
+		}
+		filter.From = &from
+	}
+	if v := c.Query("to"); v != "" {
+		to, dateOnly, err := parseDateParam(v)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to date")
+		}
+		if dateOnly {
+			to = to.Add(24*time.Hour - time.Nanosecond)
+		}
+		filter.To = &to
+	}
+	if v := c.Query("user_id"); v != "" {
+		userID, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid user_id")
+		}
+		filter.CreatedBy = uint(userID)
+	}
+	return filter, nil
+}
+
+func parseDateParam(v string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	return t, false, err
+}
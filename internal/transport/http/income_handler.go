@@ -2,6 +2,7 @@ package http
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"path/filepath"
@@ -37,35 +38,49 @@ type UpdateIncomeRequest struct {
 }
 
 type IncomeResponse struct {
-	ID          uint    `json:"id"`
-	Amount      float64 `json:"amount"`
-	Description string  `json:"description"`
-	Type        string  `json:"type"`
-	ReceiptFile string  `json:"receipt_file"`
+	ID             uint    `json:"id"`
+	Amount         float64 `json:"amount"`
+	Description    string  `json:"description"`
+	Type           string  `json:"type"`
+	ReceiptFile    string  `json:"receipt_file"`
+	ReceiptPending bool    `json:"receipt_pending"`
+}
+
+// DeletedIncomeResponse es un ingreso en la papelera.
+type DeletedIncomeResponse struct {
+	IncomeResponse
+	Date      time.Time `json:"date"`
+	CreatedBy uint      `json:"created_by"`
+	DeletedAt time.Time `json:"deleted_at"`
 }
 
 func (h *IncomeHandler) Create(c *gin.Context) {
 	var req CreateIncomeRequest
 	if err := c.ShouldBind(&req); err != nil {
+		if isTooLarge(err) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "receipt file is too large"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	file, fileHeader, err := c.Request.FormFile("receipt")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "receipt file is required"})
-		return
-	}
+	// El comprobante puede venir en el multipart o como upload_id de una subida reanudable;
+	// sin ninguno de los dos el ingreso queda con el comprobante pendiente
+	uploadID := c.PostForm("upload_id")
+	file, fileHeader, _ := c.Request.FormFile("receipt")
 
-	defer func() {
-		if err := file.Close(); err != nil {
-			log.Printf("failed to close file: %v", err)
-		}
-	}()
+	if file != nil {
+		defer func() {
+			if err := file.Close(); err != nil {
+				log.Printf("failed to close file: %v", err)
+			}
+		}()
 
-	if filepath.Ext(fileHeader.Filename) != ".pdf" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "only PDF files are allowed"})
-		return
+		if filepath.Ext(fileHeader.Filename) != ".pdf" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "only PDF files are allowed"})
+			return
+		}
 	}
 
 	userCtx, exists := c.Get("user")
@@ -87,17 +102,18 @@ func (h *IncomeHandler) Create(c *gin.Context) {
 		CreatedBy:   user.ID,
 	}
 
-	if err := h.svc.Create(c.Request.Context(), income, file, fileHeader); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := h.svc.Create(c.Request.Context(), income, file, fileHeader, uploadID); err != nil {
+		c.JSON(uploadErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
 		return
 	}
 
 	resp := IncomeResponse{
-		ID:          income.ID,
-		Amount:      income.Amount,
-		Description: income.Description,
-		Type:        string(income.Type),
-		ReceiptFile: income.Receipt.FileName,
+		ID:             income.ID,
+		Amount:         income.Amount,
+		Description:    income.Description,
+		Type:           string(income.Type),
+		ReceiptFile:    income.Receipt.FileName,
+		ReceiptPending: income.ReceiptPending,
 	}
 
 	c.JSON(http.StatusCreated, resp)
@@ -113,11 +129,12 @@ func (h *IncomeHandler) List(c *gin.Context) {
 	incomeResponses := make([]IncomeResponse, len(incomes))
 	for i, income := range incomes {
 		incomeResponses[i] = IncomeResponse{
-			ID:          income.ID,
-			Amount:      income.Amount,
-			Description: income.Description,
-			Type:        string(income.Type),
-			ReceiptFile: income.Receipt.FileName,
+			ID:             income.ID,
+			Amount:         income.Amount,
+			Description:    income.Description,
+			Type:           string(income.Type),
+			ReceiptFile:    income.Receipt.FileName,
+			ReceiptPending: income.ReceiptPending,
 		}
 	}
 	c.JSON(http.StatusOK, incomeResponses)
@@ -140,11 +157,12 @@ func (h *IncomeHandler) GetByID(c *gin.Context) {
 		return
 	}
 	response := IncomeResponse{
-		ID:          income.ID,
-		Amount:      income.Amount,
-		Description: income.Description,
-		Type:        string(income.Type),
-		ReceiptFile: income.Receipt.RelPath,
+		ID:             income.ID,
+		Amount:         income.Amount,
+		Description:    income.Description,
+		Type:           string(income.Type),
+		ReceiptFile:    income.Receipt.RelPath,
+		ReceiptPending: income.ReceiptPending,
 	}
 	c.JSON(http.StatusOK, response)
 }
@@ -157,27 +175,41 @@ func (h *IncomeHandler) DownloadReceipt(c *gin.Context) {
 		return
 	}
 
-	income, err := h.svc.GetByID(c.Request.Context(), uint(id))
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	income, file, err := h.svc.OpenReceipt(c.Request.Context(), uint(id), user)
 	if err != nil {
 		if errors.Is(err, domain.ErrNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		} else if errors.Is(err, domain.ErrQuarantined) || errors.Is(err, domain.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		} else {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		}
 		return
 	}
+	defer func() {
+		_ = file.Close()
+	}()
 
-	// RelPath: /uploads/archivo.pdf -> archivo real en ./uploads
+	// El archivo se transmite directamente desde el backend de storage (disco o S3)
 	filename := filepath.Base(income.Receipt.RelPath)
-	fullPath := filepath.Join("./uploads", filename)
-
-	c.FileAttachment(fullPath, filename)
+	c.DataFromReader(http.StatusOK, -1, income.Receipt.MimeType, file, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, filename),
+	})
 }
 
 func (h *IncomeHandler) Update(c *gin.Context) {
 	var req UpdateIncomeRequest
 
 	if err := c.ShouldBind(&req); err != nil {
+		if isTooLarge(err) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "receipt file is too large"})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -212,16 +244,90 @@ func (h *IncomeHandler) Update(c *gin.Context) {
 	}
 
 	file, fileHeader, _ := c.Request.FormFile("receipt")
+	if file != nil {
+		defer func() {
+			if err := file.Close(); err != nil {
+				log.Printf("failed to close file: %v", err)
+			}
+		}()
+
+		if filepath.Ext(fileHeader.Filename) != ".pdf" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "only PDF files are allowed"})
+			return
+		}
+	}
 
-	err := h.svc.Update(c, uint(id), income, file, fileHeader, user.ID)
+	err := h.svc.Update(c, uint(id), income, file, fileHeader, c.PostForm("upload_id"), user.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(uploadErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "income updated"})
 }
 
+// AttachReceipt adjunta el comprobante (multipart "receipt" o upload_id) a un ingreso
+// creado sin él.
+func (h *IncomeHandler) AttachReceipt(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid income ID"})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	uploadID := c.PostForm("upload_id")
+	file, fileHeader, err := c.Request.FormFile("receipt")
+	if isTooLarge(err) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "receipt file is too large"})
+		return
+	}
+	if err != nil && uploadID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "receipt file is required"})
+		return
+	}
+	if file != nil {
+		defer func() {
+			if err := file.Close(); err != nil {
+				log.Printf("failed to close file: %v", err)
+			}
+		}()
+
+		if filepath.Ext(fileHeader.Filename) != ".pdf" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "only PDF files are allowed"})
+			return
+		}
+	}
+
+	income, err := h.svc.AttachReceipt(c.Request.Context(), uint(id), file, fileHeader, uploadID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "income not found"})
+		case errors.Is(err, domain.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "only the creator can attach the receipt"})
+		case errors.Is(err, domain.ErrReceiptAttached):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(uploadErrorStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, IncomeResponse{
+		ID:             income.ID,
+		Amount:         income.Amount,
+		Description:    income.Description,
+		Type:           string(income.Type),
+		ReceiptFile:    income.Receipt.FileName,
+		ReceiptPending: income.ReceiptPending,
+	})
+}
+
 func (h *IncomeHandler) SoftDelete(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 64)
@@ -275,6 +381,35 @@ func (h *IncomeHandler) Delete(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "income permanently deleted"})
 }
 
+// ListTrash lista los ingresos borrados que todavía se pueden restaurar.
+func (h *IncomeHandler) ListTrash(c *gin.Context) {
+	incomes, err := h.svc.ListDeleted(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	responses := make([]DeletedIncomeResponse, len(incomes))
+	for i, income := range incomes {
+		responses[i] = DeletedIncomeResponse{
+			IncomeResponse: IncomeResponse{
+				ID:             income.ID,
+				Amount:         income.Amount,
+				Description:    income.Description,
+				Type:           string(income.Type),
+				ReceiptFile:    income.Receipt.FileName,
+				ReceiptPending: income.ReceiptPending,
+			},
+			Date:      income.Date,
+			CreatedBy: income.CreatedBy,
+		}
+		if income.DeletedAt != nil {
+			responses[i].DeletedAt = *income.DeletedAt
+		}
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
 func (h *IncomeHandler) Restore(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.ParseUint(idParam, 10, 64)
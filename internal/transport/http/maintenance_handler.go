@@ -0,0 +1,46 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/SaidMg10/gestor-one/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type MaintenanceHandler struct {
+	svc *service.MaintenanceService
+}
+
+func NewMaintenanceHandler(svc *service.MaintenanceService) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		svc: svc,
+	}
+}
+
+// CheckStorage reporta inconsistencias entre receipts y storage sin modificar nada.
+// Con ?checksums=true además verifica el checksum de cada archivo.
+func (h *MaintenanceHandler) CheckStorage(c *gin.Context) {
+	opts := service.StorageCheckOptions{
+		VerifyChecksums: c.Query("checksums") == "true",
+	}
+	h.runStorageCheck(c, opts)
+}
+
+// RepairStorage ejecuta el chequeo y repara lo que se pueda:
+// pone en cuarentena huérfanos viejos y recalcula checksums.
+func (h *MaintenanceHandler) RepairStorage(c *gin.Context) {
+	opts := service.StorageCheckOptions{
+		VerifyChecksums: c.Query("checksums") == "true",
+		Repair:          true,
+	}
+	h.runStorageCheck(c, opts)
+}
+
+func (h *MaintenanceHandler) runStorageCheck(c *gin.Context, opts service.StorageCheckOptions) {
+	report, err := h.svc.CheckStorage(c.Request.Context(), opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,102 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// MeHandler atiende la cuenta del usuario autenticado (/me), sin :id.
+type MeHandler struct {
+	users    *service.UserService
+	accounts *service.AccountService
+}
+
+func NewMeHandler(users *service.UserService, accounts *service.AccountService) *MeHandler {
+	return &MeHandler{users: users, accounts: accounts}
+}
+
+// UpdateMeRequest son los datos de perfil que el usuario puede cambiar de sí mismo. No
+// trae email, rol ni estado: esos solo los cambia un admin. Un campo ausente no se toca;
+// "phone": "" borra el teléfono.
+type UpdateMeRequest struct {
+	Name     *string `json:"name,omitempty"`
+	LastName *string `json:"last_name,omitempty"`
+	Phone    *string `json:"phone,omitempty"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// Get devuelve el perfil del usuario autenticado.
+func (h *MeHandler) Get(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// Update cambia el perfil del usuario autenticado y lo devuelve actualizado.
+func (h *MeHandler) Update(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var req UpdateMeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updated, err := h.users.UpdateProfile(c.Request.Context(), user.ID, domain.ProfileUpdate{
+		Name:     req.Name,
+		LastName: req.LastName,
+		Phone:    req.Phone,
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidInput) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, newUserResponse(updated))
+}
+
+// ChangePassword cambia la contraseña del usuario autenticado con la actual. Las demás
+// sesiones se cierran; la de la petición sigue abierta.
+func (h *MeHandler) ChangePassword(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.accounts.ChangePassword(c.Request.Context(), user, req.CurrentPassword, req.NewPassword, c.ClientIP(), c.GetUint("session_id"))
+	if err != nil {
+		if writeThrottled(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, domain.ErrInvalidPassword):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrSSORequired):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type OIDCHandler struct {
+	svc *service.OIDCService
+}
+
+func NewOIDCHandler(svc *service.OIDCService) *OIDCHandler {
+	return &OIDCHandler{svc: svc}
+}
+
+// Providers lista los proveedores de login configurados, para que el frontend muestre
+// sus botones.
+func (h *OIDCHandler) Providers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.svc.Providers()})
+}
+
+// Login redirige al usuario al proveedor :provider para iniciar sesión.
+func (h *OIDCHandler) Login(c *gin.Context) {
+	h.login(c, c.Param("provider"))
+}
+
+// Callback recibe la vuelta del proveedor :provider (?code=...&state=...).
+func (h *OIDCHandler) Callback(c *gin.Context) {
+	h.callback(c, c.Param("provider"))
+}
+
+// GoogleLogin redirige al usuario a Google para iniciar sesión.
+func (h *OIDCHandler) GoogleLogin(c *gin.Context) {
+	h.login(c, service.ProviderGoogle)
+}
+
+// GoogleCallback recibe la vuelta de Google (?code=...&state=...).
+func (h *OIDCHandler) GoogleCallback(c *gin.Context) {
+	h.callback(c, service.ProviderGoogle)
+}
+
+func (h *OIDCHandler) login(c *gin.Context, provider string) {
+	url, err := h.svc.AuthURL(c.Request.Context(), provider)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "login provider is not configured"})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.Redirect(http.StatusFound, url)
+}
+
+// callback responde con nuestros tokens o, si hace falta el segundo factor, con el token
+// intermedio, igual que el login con contraseña.
+func (h *OIDCHandler) callback(c *gin.Context, provider string) {
+	if e := c.Query("error"); e != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": provider + " login failed: " + e})
+		return
+	}
+
+	result, err := h.svc.Callback(c.Request.Context(), provider, c.Query("code"), c.Query("state"), clientInfo(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "login provider is not configured"})
+		case errors.Is(err, domain.ErrInvalidInput):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrInvalidToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired login"})
+		case errors.Is(err, domain.ErrSSORequired):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "no active account is linked to this identity"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, loginResponse(result))
+}
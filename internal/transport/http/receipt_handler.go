@@ -0,0 +1,117 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/service"
+	"github.com/SaidMg10/gestor-one/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+type ReceiptHandler struct {
+	svc *service.ReceiptService
+}
+
+func NewReceiptHandler(svc *service.ReceiptService) *ReceiptHandler {
+	return &ReceiptHandler{
+		svc: svc,
+	}
+}
+
+// IncomeURL emite una URL de descarga firmada para el comprobante de un ingreso.
+func (h *ReceiptHandler) IncomeURL(c *gin.Context) {
+	h.issueURL(c, h.svc.IncomeURL)
+}
+
+// ExpenseURL emite una URL de descarga firmada para el comprobante de un gasto.
+func (h *ReceiptHandler) ExpenseURL(c *gin.Context) {
+	h.issueURL(c, h.svc.ExpenseURL)
+}
+
+func (h *ReceiptHandler) issueURL(
+	c *gin.Context,
+	issue func(ctx context.Context, user *domain.User, id uint) (*service.SignedURL, error),
+) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	userCtx, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	user, ok := userCtx.(*domain.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type in context"})
+		return
+	}
+
+	signed, err := issue(c.Request.Context(), user, uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, signed)
+}
+
+// Serve entrega el archivo de una URL firmada. No requiere Authorization:
+// la firma identifica al usuario y se revalida su permiso sobre la transacción.
+func (h *ReceiptHandler) Serve(c *gin.Context) {
+	receiptID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid receipt ID"})
+		return
+	}
+	userID, err := strconv.ParseUint(c.Query("uid"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid signed url"})
+		return
+	}
+	expires, err := strconv.ParseInt(c.Query("exp"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid signed url"})
+		return
+	}
+
+	receipt, file, err := h.svc.OpenSigned(c.Request.Context(), uint(receiptID), uint(userID), expires, c.Query("sig"))
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrInvalidSignature), errors.Is(err, storage.ErrURLExpired):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+		case errors.Is(err, domain.ErrQuarantined):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		case errors.Is(err, domain.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	c.Header("Cache-Control", "private, no-store")
+	c.DataFromReader(http.StatusOK, -1, receipt.MimeType, file, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, receipt.FileName),
+	})
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type ReminderHandler struct {
+	svc *service.ReminderService
+}
+
+func NewReminderHandler(svc *service.ReminderService) *ReminderHandler {
+	return &ReminderHandler{
+		svc: svc,
+	}
+}
+
+// ListPending lista los ingresos y gastos con el comprobante pendiente del usuario
+// (?user_id=3 para ver los de otro, solo roles con vista global).
+func (h *ReminderHandler) ListPending(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var userID uint
+	if v := c.Query("user_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+			return
+		}
+		userID = uint(id)
+	}
+
+	pending, err := h.svc.ListPending(c.Request.Context(), user, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if pending == nil {
+		pending = []domain.PendingReceipt{}
+	}
+	c.JSON(http.StatusOK, gin.H{"pending": pending})
+}
+
+// List devuelve los recordatorios del usuario, los no leídos primero.
+func (h *ReminderHandler) List(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	reminders, err := h.svc.ListReminders(c.Request.Context(), user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if reminders == nil {
+		reminders = []domain.Reminder{}
+	}
+	c.JSON(http.StatusOK, gin.H{"reminders": reminders})
+}
+
+// MarkRead marca un recordatorio del usuario como leído.
+func (h *ReminderHandler) MarkRead(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid reminder ID"})
+		return
+	}
+
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.MarkRead(c.Request.Context(), user, uint(id)); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "reminder not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "reminder marked as read"})
+}
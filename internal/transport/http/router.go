@@ -2,6 +2,8 @@
 package http
 
 import (
+	"strings"
+
 	"github.com/SaidMg10/gestor-one/internal/domain"
 	"github.com/SaidMg10/gestor-one/internal/middleware"
 	"github.com/SaidMg10/gestor-one/internal/service"
@@ -14,25 +16,42 @@ func NewRouter(
 	authSvc *service.AuthService,
 	incomeSvc *service.IncomeService,
 	expenseSvc *service.ExpenseService,
+	receiptSvc *service.ReceiptService,
+	maintenanceSvc *service.MaintenanceService,
+	uploadSvc *service.UploadService,
+	exportSvc *service.ExportService,
+	searchSvc *service.SearchService,
+	analysisSvc *service.AnalysisService,
+	reminderSvc *service.ReminderService,
+	oidcSvc *service.OIDCService,
+	twoFactorSvc *service.TwoFactorService,
+	accountSvc *service.AccountService,
+	apiKeySvc *service.APIKeyService,
+	maxUploadSize int64,
 ) *gin.Engine {
 	r := gin.Default()
+	r.MaxMultipartMemory = multipartMemory
 
 	// Middleware de CORS básico
 	r.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		c.Header("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,HEAD,DELETE,OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, "+
+			"Tus-Resumable, Upload-Length, Upload-Offset, Upload-Metadata")
+		c.Header("Access-Control-Expose-Headers", tusExposedHeaders)
 
 		if c.Request.Method == "OPTIONS" {
+			// Descubrimiento de tus
+			if strings.HasPrefix(c.Request.URL.Path, "/api/v1/uploads") {
+				writeTusDiscovery(c, uploadSvc.MaxSize())
+			}
 			c.AbortWithStatus(204)
 			return
 		}
 		c.Next()
 	})
 
-	r.Static("/uploads", "./uploads")
-
-	middleware := middleware.NewMiddleware(authSvc, authSvc.Authenticator, authSvc.UserRepo)
+	middleware := middleware.NewMiddleware(authSvc, authSvc.Authenticator, authSvc.UserRepo, apiKeySvc)
 
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -41,9 +60,18 @@ func NewRouter(
 		})
 	})
 
+	// Claves públicas de los JWT (vacío con HS256)
+	authHandler := NewAuthHandler(authSvc)
+	r.GET("/.well-known/jwks.json", authHandler.JWKS)
+
+	receiptHandler := NewReceiptHandler(receiptSvc)
+
 	// API V1
 	v1 := r.Group("/api/v1")
 	{
+		// Descarga de comprobantes por URL firmada (la firma reemplaza al Bearer token)
+		v1.GET("/files/receipts/:id", receiptHandler.Serve)
+
 		//  routes
 		users := v1.Group("/users")
 		{
@@ -55,12 +83,43 @@ func NewRouter(
 			users.DELETE("/:id", userHandler.Delete)
 		}
 
+		// Cuenta del usuario autenticado: perfil y cambio de contraseña
+		meHandler := NewMeHandler(userSvc, accountSvc)
+		me := v1.Group("/me")
+		me.Use(middleware.AuthTokenMiddleware())
+		{
+			me.GET("", meHandler.Get)
+			me.PATCH("", meHandler.Update)
+			me.POST("/password", meHandler.ChangePassword)
+		}
+
 		// Auth routes
 		auths := v1.Group("/auth")
 		{
-			authHandler := NewAuthHandler(authSvc)
 			auths.POST("/login", authHandler.Login)
 			auths.POST("/refresh", authHandler.RefreshToken) // opcional si implementas refresh token
+			auths.POST("/logout", authHandler.Logout)
+			auths.POST("/logout-all", middleware.AuthTokenMiddleware(), authHandler.LogoutAll)
+
+			// Recuperación de contraseña y verificación de email (los tokens llegan por email)
+			accountHandler := NewAccountHandler(accountSvc)
+			auths.POST("/password/forgot", accountHandler.ForgotPassword)
+			auths.POST("/password/reset", accountHandler.ResetPassword)
+			auths.POST("/email/verify", accountHandler.VerifyEmail)
+			auths.POST("/email/verify/resend", middleware.AuthTokenMiddleware(), accountHandler.ResendVerification)
+
+			// Segundo paso del login con 2FA (con el mfa_token que devuelve /login)
+			auths.POST("/2fa/verify", authHandler.VerifyMFA)
+			auths.POST("/2fa/setup", authHandler.SetupMFA)
+			auths.POST("/2fa/confirm", authHandler.ConfirmMFA)
+
+			// Login con proveedores OpenID Connect; la redirect URL de cada uno debe apuntar a su callback
+			oidcHandler := NewOIDCHandler(oidcSvc)
+			auths.GET("/oidc/providers", oidcHandler.Providers)
+			auths.GET("/oidc/:provider/login", oidcHandler.Login)
+			auths.GET("/oidc/:provider/callback", oidcHandler.Callback)
+			auths.GET("/google/login", oidcHandler.GoogleLogin)
+			auths.GET("/google/callback", oidcHandler.GoogleCallback)
 		}
 
 		// Auth testing routes
@@ -118,12 +177,15 @@ func NewRouter(
 			incomes.GET("", incomeHandler.List)
 			incomes.GET("/:id", incomeHandler.GetByID)
 			incomes.GET("/:id/download", incomeHandler.DownloadReceipt)
+			incomes.GET("/:id/receipt-url", receiptHandler.IncomeURL)
 			incomes.Use(middleware.CheckRole(domain.RoleAdmin, domain.RoleSuperAdmin, domain.RoleEmployee))
-			incomes.POST("", incomeHandler.Create)
-			incomes.PATCH("/:id", incomeHandler.Update)
+			incomes.POST("", middleware.LimitUploadSize(maxUploadSize), incomeHandler.Create)
+			incomes.PATCH("/:id", middleware.LimitUploadSize(maxUploadSize), incomeHandler.Update)
+			incomes.POST("/:id/receipt", middleware.LimitUploadSize(maxUploadSize), incomeHandler.AttachReceipt)
 			incomes.DELETE("/:id/soft", incomeHandler.SoftDelete)
 			incomes.Use(middleware.CheckRole(domain.RoleAdmin, domain.RoleSuperAdmin))
 			incomes.DELETE("/:id", incomeHandler.Delete)
+			incomes.GET("/trash", incomeHandler.ListTrash)
 			incomes.PATCH(":id/restore", incomeHandler.Restore)
 		}
 
@@ -135,16 +197,121 @@ func NewRouter(
 			expenses.GET("", expenseHandler.List)
 			expenses.GET("/:id", expenseHandler.GetByID)
 			expenses.GET("/:id/download", expenseHandler.DownloadReceipt)
+			expenses.GET("/:id/receipt-url", receiptHandler.ExpenseURL)
 			expenses.Use(middleware.CheckRole(domain.RoleAdmin, domain.RoleSuperAdmin, domain.RoleEmployee))
-			expenses.POST("", expenseHandler.Create)
-			expenses.PATCH("/:id", expenseHandler.Update)
+			expenses.POST("", middleware.LimitUploadSize(maxUploadSize), expenseHandler.Create)
+			expenses.PATCH("/:id", middleware.LimitUploadSize(maxUploadSize), expenseHandler.Update)
+			expenses.POST("/:id/receipt", middleware.LimitUploadSize(maxUploadSize), expenseHandler.AttachReceipt)
 			expenses.DELETE("/:id/soft", expenseHandler.SoftDelete)
 			expenses.Use(middleware.CheckRole(domain.RoleAdmin, domain.RoleSuperAdmin))
 			expenses.DELETE("/:id", expenseHandler.Delete)
+			expenses.GET("/trash", expenseHandler.ListTrash)
 			expenses.PATCH(":id/restore", expenseHandler.Restore)
 
 		}
 
+		// Subidas reanudables (tus 1.0); el upload_id resultante se usa al crear/editar ingresos y gastos
+		uploads := v1.Group("/uploads")
+		uploads.Use(middleware.AuthTokenMiddleware())
+		uploads.Use(middleware.CheckRole(domain.RoleAdmin, domain.RoleSuperAdmin, domain.RoleEmployee))
+		{
+			uploadHandler := NewUploadHandler(uploadSvc)
+			uploads.POST("", uploadHandler.Create)
+			uploads.HEAD("/:id", uploadHandler.Head)
+			uploads.PATCH("/:id", middleware.LimitUploadSize(maxUploadSize), uploadHandler.Patch)
+			uploads.DELETE("/:id", uploadHandler.Delete)
+		}
+
+		// Exportaciones de comprobantes; cada usuario exporta lo que puede ver
+		exports := v1.Group("/exports")
+		exports.Use(middleware.AuthTokenMiddleware())
+		{
+			exportHandler := NewExportHandler(exportSvc)
+			exports.GET("/receipts.zip", exportHandler.ReceiptsZIP)
+			exports.GET("/receipts.pdf", exportHandler.ReceiptsPDF)
+		}
+
+		// Comprobantes: transacciones que todavía no lo tienen y sugerencias de datos
+		// a partir del PDF antes de crear la transacción
+		receipts := v1.Group("/receipts")
+		receipts.Use(middleware.AuthTokenMiddleware())
+		{
+			reminderHandler := NewReminderHandler(reminderSvc)
+			receipts.GET("/pending", reminderHandler.ListPending)
+			receipts.Use(middleware.CheckRole(domain.RoleAdmin, domain.RoleSuperAdmin, domain.RoleEmployee))
+			analysisHandler := NewAnalysisHandler(analysisSvc)
+			receipts.POST("/analyze", middleware.LimitUploadSize(maxUploadSize), analysisHandler.Analyze)
+		}
+
+		// Recordatorios del usuario (p. ej. comprobantes pendientes)
+		reminders := v1.Group("/reminders")
+		reminders.Use(middleware.AuthTokenMiddleware())
+		{
+			reminderHandler := NewReminderHandler(reminderSvc)
+			reminders.GET("", reminderHandler.List)
+			reminders.POST("/:id/read", reminderHandler.MarkRead)
+		}
+
+		// 2FA (TOTP) del usuario autenticado
+		twoFactorHandler := NewTwoFactorHandler(twoFactorSvc)
+		twoFactor := v1.Group("/2fa")
+		twoFactor.Use(middleware.AuthTokenMiddleware())
+		{
+			twoFactor.GET("", twoFactorHandler.Status)
+			twoFactor.POST("/setup", twoFactorHandler.Setup)
+			twoFactor.POST("/confirm", twoFactorHandler.Confirm)
+			twoFactor.POST("/recovery-codes", twoFactorHandler.RegenerateRecoveryCodes)
+			twoFactor.POST("/disable", twoFactorHandler.Disable)
+		}
+
+		// API keys del usuario autenticado para scripts e integraciones; el token solo se
+		// muestra al crearla
+		apiKeyHandler := NewAPIKeyHandler(apiKeySvc)
+		apiKeys := v1.Group("/api-keys")
+		apiKeys.Use(middleware.AuthTokenMiddleware())
+		{
+			apiKeys.GET("", apiKeyHandler.List)
+			apiKeys.GET("/scopes", apiKeyHandler.Scopes)
+			apiKeys.POST("", apiKeyHandler.Create)
+			apiKeys.DELETE("/:id", apiKeyHandler.Revoke)
+		}
+
+		// Sesiones iniciadas del usuario autenticado (dispositivos con la sesión abierta)
+		sessionHandler := NewSessionHandler(authSvc)
+		sessions := v1.Group("/sessions")
+		sessions.Use(middleware.AuthTokenMiddleware())
+		{
+			sessions.GET("", sessionHandler.List)
+			sessions.DELETE("/:id", sessionHandler.Revoke)
+		}
+
+		// Búsqueda de texto completo; cada usuario busca en lo que puede ver
+		searchHandler := NewSearchHandler(searchSvc)
+		v1.GET("/search", middleware.AuthTokenMiddleware(), searchHandler.Search)
+
+		// Admin routes
+		admin := v1.Group("/admin")
+		admin.Use(middleware.AuthTokenMiddleware())
+		admin.Use(middleware.CheckRole(domain.RoleAdmin, domain.RoleSuperAdmin))
+		{
+			maintenanceHandler := NewMaintenanceHandler(maintenanceSvc)
+			admin.GET("/storage/check", maintenanceHandler.CheckStorage)
+			admin.POST("/storage/repair", maintenanceHandler.RepairStorage)
+			admin.GET("/2fa/roles", twoFactorHandler.ListPolicies)
+			admin.PUT("/2fa/roles/:role", twoFactorHandler.SetPolicy)
+			admin.DELETE("/users/:id/2fa", twoFactorHandler.Reset)
+
+			// Protección contra fuerza bruta del login
+			securityHandler := NewSecurityHandler(authSvc.Guard)
+			admin.GET("/security/events", securityHandler.Events)
+			admin.DELETE("/users/:id/lock", securityHandler.UnlockUser)
+			admin.DELETE("/security/ip-locks/:ip", securityHandler.UnlockIP)
+			admin.GET("/users/:id/api-keys", apiKeyHandler.ListByUser)
+			admin.DELETE("/api-keys/:id", apiKeyHandler.RevokeAny)
+			admin.GET("/users/:id/sessions", sessionHandler.ListByUser)
+			admin.DELETE("/sessions/:id", sessionHandler.RevokeAny)
+		}
+
 		// Products routes
 		/*
 			products := v1.Group("/products")
@@ -0,0 +1,91 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type SecurityHandler struct {
+	svc *service.LoginGuardService
+}
+
+func NewSecurityHandler(svc *service.LoginGuardService) *SecurityHandler {
+	return &SecurityHandler{svc: svc}
+}
+
+// Events lista el registro de seguridad, lo más reciente primero
+// (?user_id=3&type=login_failed&ip=10.0.0.1&limit=50&offset=0).
+func (h *SecurityHandler) Events(c *gin.Context) {
+	filter := domain.SecurityEventFilter{Type: c.Query("type"), IP: c.Query("ip")}
+	var err error
+	if filter.Limit, err = queryInt(c, "limit"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"})
+		return
+	}
+	if filter.Offset, err = queryInt(c, "offset"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid offset"})
+		return
+	}
+	if v := c.Query("user_id"); v != "" {
+		userID, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+			return
+		}
+		filter.UserID = uint(userID)
+	}
+
+	events, err := h.svc.Events(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if events == nil {
+		events = []domain.SecurityEvent{}
+	}
+	c.JSON(http.StatusOK, gin.H{"events": events})
+}
+
+// UnlockUser quita el bloqueo por intentos fallidos de la cuenta del usuario :id.
+func (h *SecurityHandler) UnlockUser(c *gin.Context) {
+	admin, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.svc.UnlockUser(c.Request.Context(), uint(id), admin.ID); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// UnlockIP quita el bloqueo por intentos fallidos de la IP :ip.
+func (h *SecurityHandler) UnlockIP(c *gin.Context) {
+	admin, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	if err := h.svc.UnlockIP(c.Request.Context(), c.Param("ip"), admin.ID); err != nil {
+		if errors.Is(err, domain.ErrInvalidInput) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,94 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler struct {
+	svc *service.AuthService
+}
+
+func NewSessionHandler(svc *service.AuthService) *SessionHandler {
+	return &SessionHandler{svc: svc}
+}
+
+// SessionResponse es una sesión de la lista; Current marca la del token de la petición.
+type SessionResponse struct {
+	domain.Session
+	Current bool `json:"current"`
+}
+
+// List lista las sesiones activas del usuario autenticado.
+func (h *SessionHandler) List(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	h.list(c, user.ID)
+}
+
+// Revoke cierra una sesión del usuario autenticado; puede ser la actual.
+func (h *SessionHandler) Revoke(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	h.revoke(c, user.ID)
+}
+
+// ListByUser lista las sesiones activas del usuario :id (admin).
+func (h *SessionHandler) ListByUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	h.list(c, uint(id))
+}
+
+// RevokeAny cierra cualquier sesión (admin).
+func (h *SessionHandler) RevokeAny(c *gin.Context) {
+	h.revoke(c, 0)
+}
+
+func (h *SessionHandler) list(c *gin.Context, userID uint) {
+	sessions, err := h.svc.Sessions(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	currentID := c.GetUint("session_id")
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, SessionResponse{Session: session, Current: session.ID == currentID})
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": response})
+}
+
+func (h *SessionHandler) revoke(c *gin.Context, ownerID uint) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.svc.RevokeSession(c.Request.Context(), ownerID, uint(id)); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// clientInfo es el cliente de la petición, para registrar la sesión.
+func clientInfo(c *gin.Context) domain.ClientInfo {
+	return domain.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
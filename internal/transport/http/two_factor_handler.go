@@ -0,0 +1,180 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorHandler struct {
+	svc *service.TwoFactorService
+}
+
+func NewTwoFactorHandler(svc *service.TwoFactorService) *TwoFactorHandler {
+	return &TwoFactorHandler{svc: svc}
+}
+
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorPolicyRequest struct {
+	Required *bool `json:"required" binding:"required"`
+}
+
+// Status devuelve el estado del 2FA del usuario autenticado.
+func (h *TwoFactorHandler) Status(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	status, err := h.svc.Status(c.Request.Context(), user)
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// Setup empieza el alta del 2FA; queda activo recién con Confirm.
+func (h *TwoFactorHandler) Setup(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	secret, uri, err := h.svc.Setup(c.Request.Context(), user)
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, TwoFactorSetupResponse{Secret: secret, OTPAuthURI: uri})
+}
+
+// Confirm activa el 2FA con el primer código y devuelve los códigos de recuperación.
+func (h *TwoFactorHandler) Confirm(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	codes, err := h.svc.Confirm(c.Request.Context(), user.ID, req.Code)
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// RegenerateRecoveryCodes reemplaza los códigos de recuperación.
+func (h *TwoFactorHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	codes, err := h.svc.RegenerateRecoveryCodes(c.Request.Context(), user.ID, req.Code)
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// Disable quita el 2FA del usuario autenticado.
+func (h *TwoFactorHandler) Disable(c *gin.Context) {
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.svc.Disable(c.Request.Context(), user, req.Code); err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListPolicies devuelve qué roles exigen 2FA.
+func (h *TwoFactorHandler) ListPolicies(c *gin.Context) {
+	policies, err := h.svc.Policies(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"policies": policies})
+}
+
+// SetPolicy cambia si el rol :role exige 2FA.
+func (h *TwoFactorHandler) SetPolicy(c *gin.Context) {
+	var req TwoFactorPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	policy, err := h.svc.SetRequired(c.Request.Context(), c.Param("role"), *req.Required)
+	if err != nil {
+		writeTwoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// Reset quita el 2FA de otro usuario (perdió la app y los códigos de recuperación).
+func (h *TwoFactorHandler) Reset(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	if err := h.svc.Reset(c.Request.Context(), uint(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// writeTwoFactorError traduce los errores del 2FA, del login en dos pasos incluido.
+func writeTwoFactorError(c *gin.Context, err error) {
+	if writeThrottled(c, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, domain.ErrInvalidToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired login, sign in again"})
+	case errors.Is(err, domain.ErrInvalidCode):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrTwoFactorEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "two-factor setup was not started"})
+	case errors.Is(err, domain.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "two-factor authentication is required for your role"})
+	case errors.Is(err, domain.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrAccountDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
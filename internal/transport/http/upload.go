@@ -0,0 +1,31 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+)
+
+// multipartMemory es lo que se mantiene en memoria al parsear un multipart;
+// el resto del archivo se vuelca a un temporal en disco.
+const multipartMemory = 1 << 20
+
+// isTooLarge indica si el error viene de superar el tamaño máximo de subida,
+// ya sea del cuerpo HTTP completo o del archivo individual.
+func isTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr) || errors.Is(err, domain.ErrFileTooLarge)
+}
+
+// uploadErrorStatus traduce los errores de una subida al código HTTP correspondiente.
+func uploadErrorStatus(err error, fallback int) int {
+	switch {
+	case isTooLarge(err):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, domain.ErrInfectedFile), errors.Is(err, domain.ErrScanFailed),
+		errors.Is(err, domain.ErrInvalidPDF):
+		return http.StatusUnprocessableEntity
+	}
+	return fallback
+}
@@ -0,0 +1,218 @@
+package http
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SaidMg10/gestor-one/internal/domain"
+	"github.com/SaidMg10/gestor-one/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// Cabeceras y valores del protocolo tus 1.0 (https://tus.io/protocols/resumable-upload).
+const (
+	tusVersion           = "1.0.0"
+	tusExtensions        = "creation,termination,expiration"
+	tusOffsetType        = "application/offset+octet-stream"
+	headerTusResumable   = "Tus-Resumable"
+	headerUploadLength   = "Upload-Length"
+	headerUploadOffset   = "Upload-Offset"
+	headerUploadMetadata = "Upload-Metadata"
+	headerUploadExpires  = "Upload-Expires"
+)
+
+// tusExposedHeaders son las cabeceras que un cliente tus en el navegador necesita leer.
+const tusExposedHeaders = "Location, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, " +
+	"Upload-Offset, Upload-Length, Upload-Expires"
+
+type UploadHandler struct {
+	svc *service.UploadService
+}
+
+func NewUploadHandler(svc *service.UploadService) *UploadHandler {
+	return &UploadHandler{
+		svc: svc,
+	}
+}
+
+// writeTusDiscovery escribe las cabeceras con las que el servidor anuncia qué soporta.
+// Se usa en la respuesta al OPTIONS, que resuelve el middleware de CORS.
+func writeTusDiscovery(c *gin.Context, maxSize int64) {
+	c.Header(headerTusResumable, tusVersion)
+	c.Header("Tus-Version", tusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	if maxSize > 0 {
+		c.Header("Tus-Max-Size", strconv.FormatInt(maxSize, 10))
+	}
+}
+
+// Create inicia un upload (extensión creation). Devuelve en Location la URL a la que
+// el cliente manda los PATCH; el último segmento es el upload_id para ingresos y gastos.
+func (h *UploadHandler) Create(c *gin.Context) {
+	if !h.checkVersion(c) {
+		return
+	}
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	length, err := strconv.ParseInt(c.GetHeader(headerUploadLength), 10, 64)
+	if err != nil || length <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Upload-Length"})
+		return
+	}
+
+	fileName := parseTusMetadata(c.GetHeader(headerUploadMetadata))["filename"]
+	if fileName != "" && !strings.HasSuffix(strings.ToLower(fileName), ".pdf") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "only PDF files are allowed"})
+		return
+	}
+
+	upload, err := h.svc.Create(c.Request.Context(), user.ID, length, fileName)
+	if err != nil {
+		c.JSON(uploadErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header(headerTusResumable, tusVersion)
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+upload.ID)
+	c.Header(headerUploadExpires, upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.JSON(http.StatusCreated, gin.H{"upload_id": upload.ID, "expires_at": upload.ExpiresAt})
+}
+
+// Head informa cuántos bytes se recibieron para poder reanudar.
+func (h *UploadHandler) Head(c *gin.Context) {
+	if !h.checkVersion(c) {
+		return
+	}
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	upload, err := h.svc.Get(c.Request.Context(), user.ID, c.Param("id"))
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+
+	c.Header(headerTusResumable, tusVersion)
+	c.Header("Cache-Control", "no-store")
+	c.Header(headerUploadOffset, strconv.FormatInt(upload.Offset, 10))
+	c.Header(headerUploadLength, strconv.FormatInt(upload.Length, 10))
+	c.Header(headerUploadExpires, upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusOK)
+}
+
+// Patch agrega un chunk. Upload-Offset tiene que coincidir con lo ya recibido.
+func (h *UploadHandler) Patch(c *gin.Context) {
+	if !h.checkVersion(c) {
+		return
+	}
+	if c.ContentType() != tusOffsetType {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Content-Type must be " + tusOffsetType})
+		return
+	}
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader(headerUploadOffset), 10, 64)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Upload-Offset"})
+		return
+	}
+
+	upload, err := h.svc.WriteChunk(c.Request.Context(), user.ID, c.Param("id"), offset, c.Request.Body)
+	if upload != nil {
+		c.Header(headerUploadOffset, strconv.FormatInt(upload.Offset, 10))
+		c.Header(headerUploadExpires, upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	c.Header(headerTusResumable, tusVersion)
+	if err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Delete cancela un upload (extensión termination).
+func (h *UploadHandler) Delete(c *gin.Context) {
+	if !h.checkVersion(c) {
+		return
+	}
+	user, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.svc.Terminate(c.Request.Context(), user.ID, c.Param("id")); err != nil {
+		h.fail(c, err)
+		return
+	}
+	c.Header(headerTusResumable, tusVersion)
+	c.Status(http.StatusNoContent)
+}
+
+// checkVersion rechaza clientes que hablan otra versión del protocolo.
+func (h *UploadHandler) checkVersion(c *gin.Context) bool {
+	if c.GetHeader(headerTusResumable) != tusVersion {
+		c.Header("Tus-Version", tusVersion)
+		c.AbortWithStatusJSON(http.StatusPreconditionFailed, gin.H{"error": "unsupported tus version"})
+		return false
+	}
+	return true
+}
+
+func (h *UploadHandler) fail(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "upload not found"})
+	case errors.Is(err, domain.ErrOffsetMismatch):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(uploadErrorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+	}
+}
+
+// currentUser obtiene el usuario autenticado o responde el error correspondiente.
+func currentUser(c *gin.Context) (*domain.User, bool) {
+	userCtx, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return nil, false
+	}
+
+	user, ok := userCtx.(*domain.User)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user type in context"})
+		return nil, false
+	}
+	return user, true
+}
+
+// parseTusMetadata decodifica Upload-Metadata: pares "clave valor-base64" separados por coma.
+func parseTusMetadata(header string) map[string]string {
+	meta := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), " ", 2)
+		if parts[0] == "" {
+			continue
+		}
+		if len(parts) == 1 {
+			meta[parts[0]] = ""
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			continue
+		}
+		meta[parts[0]] = string(value)
+	}
+	return meta
+}
@@ -53,6 +53,23 @@ type UserResponse struct {
 	Role     string  `json:"role"`
 	GoogleID *string `json:"google_id,omitempty"`
 	Active   bool    `json:"active"`
+	// EmailVerified indica si el usuario confirmó su email con el enlace que se le mandó
+	EmailVerified bool `json:"email_verified"`
+}
+
+// newUserResponse arma la respuesta de un usuario.
+func newUserResponse(user *domain.User) UserResponse {
+	return UserResponse{
+		ID:       user.ID,
+		Name:     user.Name,
+		LastName: user.LastName,
+		Email:    user.Email,
+		Phone:    user.Phone,
+		Role:     user.Role,
+		Active:   user.Active == nil || *user.Active,
+
+		EmailVerified: user.EmailVerifiedAt != nil,
+	}
 }
 
 func (h *UserHandler) Create(c *gin.Context) {
@@ -101,16 +118,8 @@ func (h *UserHandler) List(c *gin.Context) {
 	}
 	// Entity mapping
 	userResponses := make([]UserResponse, len(users))
-	for i, user := range users {
-		userResponses[i] = UserResponse{
-			ID:       user.ID,
-			Name:     user.Name,
-			LastName: user.LastName,
-			Email:    user.Email,
-			Phone:    user.Phone,
-			Role:     user.Role,
-			Active:   *user.Active,
-		}
+	for i := range users {
+		userResponses[i] = newUserResponse(&users[i])
 	}
 	c.JSON(http.StatusOK, userResponses)
 }
@@ -131,16 +140,7 @@ func (h *UserHandler) GetByID(c *gin.Context) {
 		}
 		return
 	}
-	response := UserResponse{
-		ID:       user.ID,
-		Name:     user.Name,
-		LastName: user.LastName,
-		Email:    user.Email,
-		Phone:    user.Phone,
-		Role:     user.Role,
-		Active:   *user.Active,
-	}
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, newUserResponse(user))
 }
 
 func (h *UserHandler) Update(c *gin.Context) {
@@ -0,0 +1,2 @@
+ALTER TABLE receipts
+DROP COLUMN IF EXISTS key_id;
@@ -0,0 +1,2 @@
+ALTER TABLE receipts
+ADD COLUMN key_id VARCHAR(64) NULL;
@@ -0,0 +1,5 @@
+ALTER TABLE receipts
+DROP COLUMN IF EXISTS quarantined,
+DROP COLUMN IF EXISTS scanned_at,
+DROP COLUMN IF EXISTS scan_result,
+DROP COLUMN IF EXISTS scan_status;
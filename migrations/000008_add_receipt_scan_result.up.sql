@@ -0,0 +1,5 @@
+ALTER TABLE receipts
+ADD COLUMN scan_status VARCHAR(20) NULL,
+ADD COLUMN scan_result VARCHAR(255) NULL,
+ADD COLUMN scanned_at TIMESTAMP NULL,
+ADD COLUMN quarantined BOOLEAN NOT NULL DEFAULT FALSE;
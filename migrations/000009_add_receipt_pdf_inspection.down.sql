@@ -0,0 +1,3 @@
+ALTER TABLE receipts
+DROP COLUMN IF EXISTS sanitized,
+DROP COLUMN IF EXISTS page_count;
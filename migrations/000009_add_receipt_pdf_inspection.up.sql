@@ -0,0 +1,3 @@
+ALTER TABLE receipts
+ADD COLUMN page_count INTEGER NOT NULL DEFAULT 0,
+ADD COLUMN sanitized BOOLEAN NOT NULL DEFAULT FALSE;
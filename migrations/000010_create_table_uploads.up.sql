@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS uploads (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    file_name VARCHAR(255),
+    upload_length BIGINT NOT NULL,
+    upload_offset BIGINT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP NULL,
+    stored_name VARCHAR(255),
+    rel_path VARCHAR(255),
+    checksum VARCHAR(255),
+    key_id VARCHAR(64),
+    scan_status VARCHAR(20),
+    scan_result VARCHAR(255),
+    scanned_at TIMESTAMP NULL,
+    quarantined BOOLEAN NOT NULL DEFAULT FALSE,
+    page_count INTEGER NOT NULL DEFAULT 0,
+    sanitized BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_uploads_user_id ON uploads(user_id);
+CREATE INDEX IF NOT EXISTS idx_uploads_expires_at ON uploads(expires_at);
+
+ALTER TABLE uploads
+ADD CONSTRAINT fk_uploads_user
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE;
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_receipts_search_vector;
+DROP INDEX IF EXISTS idx_expenses_search_vector;
+DROP INDEX IF EXISTS idx_incomes_search_vector;
+
+ALTER TABLE receipts DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE expenses DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE incomes DROP COLUMN IF EXISTS search_vector;
+
+ALTER TABLE uploads DROP COLUMN IF EXISTS content;
+ALTER TABLE receipts DROP COLUMN IF EXISTS content;
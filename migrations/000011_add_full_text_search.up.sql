@@ -0,0 +1,32 @@
+-- Texto extraído de los PDFs al guardarlos
+ALTER TABLE receipts
+ADD COLUMN content TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE uploads
+ADD COLUMN content TEXT NOT NULL DEFAULT '';
+
+-- Vectores de búsqueda en español e inglés; el tipo va con la configuración simple
+-- porque son códigos (invoice, fuel, ...) que no conviene reducir a raíces
+ALTER TABLE incomes
+ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('spanish', coalesce(description, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(type, '')), 'B')
+) STORED;
+
+ALTER TABLE expenses
+ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('spanish', coalesce(description, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(type, '')), 'B')
+) STORED;
+
+ALTER TABLE receipts
+ADD COLUMN search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('spanish', content), 'C') ||
+    setweight(to_tsvector('english', content), 'C')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_incomes_search_vector ON incomes USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_expenses_search_vector ON expenses USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_receipts_search_vector ON receipts USING GIN (search_vector);
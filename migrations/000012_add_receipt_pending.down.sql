@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS reminders;
+
+DROP INDEX IF EXISTS idx_expenses_receipt_pending;
+DROP INDEX IF EXISTS idx_incomes_receipt_pending;
+
+ALTER TABLE expenses
+DROP COLUMN IF EXISTS receipt_reminded_at,
+DROP COLUMN IF EXISTS receipt_pending;
+
+ALTER TABLE incomes
+DROP COLUMN IF EXISTS receipt_reminded_at,
+DROP COLUMN IF EXISTS receipt_pending;
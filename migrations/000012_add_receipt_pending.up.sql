@@ -0,0 +1,31 @@
+ALTER TABLE incomes
+ADD COLUMN receipt_pending BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN receipt_reminded_at TIMESTAMP NULL;
+
+ALTER TABLE expenses
+ADD COLUMN receipt_pending BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN receipt_reminded_at TIMESTAMP NULL;
+
+-- Solo interesan las filas pendientes: índices parciales chicos
+CREATE INDEX IF NOT EXISTS idx_incomes_receipt_pending ON incomes(created_by, created_at)
+    WHERE receipt_pending AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_expenses_receipt_pending ON expenses(created_by, created_at)
+    WHERE receipt_pending AND deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS reminders (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    transaction_id BIGINT NOT NULL,
+    message VARCHAR(255) NOT NULL,
+    read_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reminders_user_id ON reminders(user_id);
+
+ALTER TABLE reminders
+ADD CONSTRAINT fk_reminders_user
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_google_id;
+
+DROP TABLE IF EXISTS oauth_states;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS oauth_states (
+    id VARCHAR(64) PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    verifier VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oauth_states_expires_at ON oauth_states(expires_at);
+
+-- Una cuenta de Google solo puede quedar vinculada a un usuario
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_google_id ON users(google_id)
+    WHERE google_id IS NOT NULL AND google_id <> '';
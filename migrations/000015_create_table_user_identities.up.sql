@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS user_identities (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(150),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+ALTER TABLE user_identities
+ADD CONSTRAINT fk_user_identities_user
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE CASCADE;
+
+-- Una cuenta externa pertenece a un solo usuario y un usuario tiene una cuenta por proveedor
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_provider_subject ON user_identities(provider, subject);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_identities_user_provider ON user_identities(user_id, provider);
+
+-- Las cuentas de Google ya vinculadas pasan a ser identidades del proveedor "google"
+INSERT INTO user_identities (user_id, provider, subject, email)
+SELECT id, 'google', google_id, email FROM users
+WHERE google_id IS NOT NULL AND google_id <> ''
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS two_factor_policies;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS two_factors;
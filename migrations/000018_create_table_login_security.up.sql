@@ -0,0 +1,29 @@
+-- Fallos de login recientes por cuenta ("account:<email>") y por IP ("ip:<addr>")
+CREATE TABLE IF NOT EXISTS login_throttles (
+    key VARCHAR(200) PRIMARY KEY,
+    failures INT NOT NULL DEFAULT 0,
+    last_failure_at TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_throttles_last_failure_at ON login_throttles(last_failure_at);
+
+CREATE TABLE IF NOT EXISTS security_events (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(30) NOT NULL,
+    user_id BIGINT NULL,
+    email VARCHAR(150) NULL,
+    ip VARCHAR(45) NULL,
+    detail VARCHAR(255) NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_security_events_user_id ON security_events(user_id);
+CREATE INDEX IF NOT EXISTS idx_security_events_created_at ON security_events(created_at);
+
+-- El registro sobrevive al usuario
+ALTER TABLE security_events
+ADD CONSTRAINT fk_security_events_user
+    FOREIGN KEY (user_id)
+    REFERENCES users(id)
+    ON DELETE SET NULL;